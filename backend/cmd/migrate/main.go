@@ -0,0 +1,93 @@
+// Команда migrate управляет схемой базы данных отдельно от сервера:
+//
+//	migrate status    — список миграций и время их применения
+//	migrate up        — применить все новые миграции
+//	migrate down N    — откатить N последних миграций (по умолчанию 1)
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"youtube-market/internal/db"
+
+	"github.com/joho/godotenv"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate status | up | down [N]")
+	os.Exit(2)
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: could not load .env file: %v", err)
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	conn, err := db.Open()
+	if err != nil {
+		log.Fatalf("database connection failed: %v", err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		log.Fatalf("failed to get underlying sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "status":
+		statuses, err := db.MigrationsStatus(ctx, sqlDB)
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+
+	case "up":
+		applied, err := db.MigrateUp(ctx, sqlDB)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				usage()
+			}
+		}
+		reverted, err := db.MigrateDown(ctx, sqlDB, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+
+	default:
+		usage()
+	}
+}
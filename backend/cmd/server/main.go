@@ -12,6 +12,7 @@ import (
 	"youtube-market/internal/models"
 	"youtube-market/internal/notifier"
 	"youtube-market/internal/security"
+	"youtube-market/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -55,6 +56,11 @@ func main() {
 		logger.Fatal("Failed to initialize database", err, nil)
 	}
 
+	// Initialize photo storage
+	if err := storage.Init(); err != nil {
+		logger.Fatal("Failed to initialize photo storage", err, nil)
+	}
+
 	// Initialize Redis for rate limiting
 	if err := middleware.InitRedis(); err != nil {
 		logger.Warning("Redis not available, rate limiting disabled", map[string]interface{}{
@@ -168,19 +174,45 @@ func setupRouter() *gin.Engine {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Telegram webhook (регистрируется только при заданном BOT_WEBHOOK_URL)
+	handlers.RegisterBotWebhook(r)
+
 	// API routes with TMA authentication
 	api := r.Group("/api")
 	api.Use(middleware.TMAuthMiddleware())
+	api.Use(handlers.BlacklistIdentityMiddleware())
+	api.Use(handlers.TelegramUserMiddleware())
 	{
 		api.GET("/ads", handlers.GetAds)
-		api.GET("/myads", handlers.GetMyAds)
-		api.GET("/profile/:username", handlers.GetProfileAds)
+		api.POST("/ads", middleware.UserRateLimit("ads", 10, time.Hour), handlers.CreateAd)
+		api.PUT("/ads/:id", middleware.UserRateLimit("ads", 10, time.Hour), handlers.UpdateAd)
+		api.POST("/ads/:id/invoice", middleware.UserRateLimit("invoices", 20, time.Hour), handlers.CreateInvoice)
+		api.POST("/ads/:id/favorite", handlers.AddFavorite)
+		api.DELETE("/ads/:id/favorite", handlers.RemoveFavorite)
+		api.GET("/favorites", handlers.GetFavorites)
+		api.GET("/myads", middleware.RequireOwner("user_id", handlers.CanManageAds), handlers.GetMyAds)
+		api.GET("/profile", handlers.GetProfile)
+		api.GET("/profile/:username", handlers.GetProfile)
 		api.GET("/scammer/:username", handlers.CheckScammer)
 		api.GET("/blacklist", handlers.GetBlacklist)
+		api.POST("/reports", middleware.UserRateLimit("reports", 5, time.Hour), handlers.CreateReport)
+		api.GET("/reviews", handlers.GetReviews)
+		api.POST("/reviews", middleware.UserRateLimit("reviews", 10, 24*time.Hour), handlers.CreateReview)
+		api.GET("/deals", handlers.GetDeals)
+		api.GET("/subscriptions", handlers.GetSubscriptions)
+		api.POST("/subscriptions", middleware.UserRateLimit("subscriptions", 30, time.Hour), handlers.CreateSubscription)
+		api.DELETE("/subscriptions/:id", handlers.DeleteSubscription)
+	}
+
+	// Маршруты только для менеджеров (любая роль из бота)
+	manager := api.Group("/manager", middleware.RequireManager(handlers.IsActiveManager))
+	{
+		manager.GET("/moderation", handlers.GetModerationQueue)
 	}
 
 	// Photo endpoint - публичный, не требует авторизации (изображения загружаются через <img>)
 	r.GET("/api/ads/:id/photo", handlers.GetAdPhoto)
+	r.GET("/api/ads/:id/photos/:n", handlers.GetAdPhoto)
 
 	return r
 }
@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
-	"youtube-market/internal/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,10 +14,44 @@ import (
 
 var DB *gorm.DB
 
+// Init подключается к базе и, если не отключено DB_MIGRATE_ON_START=false, применяет миграции
 func Init() error {
+	db, err := Open()
+	if err != nil {
+		return err
+	}
+
+	if migrateOnStart() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+		}
+		applied, err := MigrateUp(context.Background(), sqlDB)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied migration %04d_%s\n", m.Version, m.Name)
+		}
+	}
+
+	DB = db
+	fmt.Println("Database initialized successfully")
+	return nil
+}
+
+// migrateOnStart — применять ли миграции при старте сервера.
+// При раздельном деплое миграции запускаются командой cmd/migrate.
+func migrateOnStart() bool {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("DB_MIGRATE_ON_START")))
+	return value != "false" && value != "0" && value != "no"
+}
+
+// Open подключается к базе из DATABASE_URL с повторными попытками и настраивает пул соединений
+func Open() (*gorm.DB, error) {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is not set")
+		return nil, fmt.Errorf("DATABASE_URL environment variable is not set")
 	}
 
 	// Логируем DSN для отладки (без пароля)
@@ -50,13 +84,13 @@ func Init() error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
+		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
 	}
 
 	// Настройка connection pool для стабильности
 	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
 
 	// Настройки connection pool
@@ -69,19 +103,12 @@ func Init() error {
 	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := sqlDB.PingContext(pingCtx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 	
 	fmt.Println("Database ping successful")
 
-	// Auto migrate models
-	if err := db.AutoMigrate(&models.User{}, &models.Ad{}); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
-	}
-
-	DB = db
-	fmt.Println("Database initialized successfully")
-	return nil
+	return db, nil
 }
 
 // maskDSN скрывает пароль в DSN для безопасного логирования
@@ -0,0 +1,41 @@
+-- Исходная схема, которую раньше создавал AutoMigrate.
+-- IF NOT EXISTS позволяет применить миграцию к уже существующей базе.
+CREATE TABLE IF NOT EXISTS users (
+    id         bigserial PRIMARY KEY,
+    username   varchar(64),
+    is_scammer boolean,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS ads (
+    id                  bigserial PRIMARY KEY,
+    user_id             bigint,
+    client_id           varchar(64),
+    username            varchar(64),
+    title               varchar(128),
+    "desc"              varchar(2048),
+    photo_id            varchar(256),
+    photo_path          varchar(512),
+    category            varchar(32),
+    mode                varchar(16),
+    tag                 varchar(64),
+    is_premium          boolean,
+    status              varchar(16),
+    expires_at          timestamptz,
+    pre_expiry_notified boolean,
+    created_at          timestamptz,
+    updated_at          timestamptz,
+    deleted_at          timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_ads_client_id ON ads (client_id);
+CREATE INDEX IF NOT EXISTS idx_ads_username ON ads (username);
+CREATE INDEX IF NOT EXISTS idx_ads_category ON ads (category);
+CREATE INDEX IF NOT EXISTS idx_ads_mode ON ads (mode);
+CREATE INDEX IF NOT EXISTS idx_ads_tag ON ads (tag);
+CREATE INDEX IF NOT EXISTS idx_ads_status ON ads (status);
+CREATE INDEX IF NOT EXISTS idx_ads_expires_at ON ads (expires_at);
+CREATE INDEX IF NOT EXISTS idx_ads_deleted_at ON ads (deleted_at);
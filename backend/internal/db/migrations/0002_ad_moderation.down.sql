@@ -0,0 +1,2 @@
+ALTER TABLE ads DROP COLUMN IF EXISTS reject_reason;
+ALTER TABLE ads DROP COLUMN IF EXISTS requested_days;
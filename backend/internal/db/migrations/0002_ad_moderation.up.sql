@@ -0,0 +1,2 @@
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS requested_days bigint;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS reject_reason varchar(512);
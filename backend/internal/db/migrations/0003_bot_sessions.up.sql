@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS bot_sessions (
+    chat_id    bigint PRIMARY KEY,
+    data       bytea,
+    expires_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_bot_sessions_expires_at ON bot_sessions (expires_at);
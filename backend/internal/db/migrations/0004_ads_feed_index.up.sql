@@ -0,0 +1,2 @@
+-- Индекс под ленту объявлений: премиум первыми, затем по свежести
+CREATE INDEX IF NOT EXISTS idx_ads_feed ON ads (is_premium, updated_at, id);
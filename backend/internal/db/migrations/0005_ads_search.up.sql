@@ -0,0 +1,9 @@
+-- Полнотекстовый поиск по заголовку и описанию. Заголовок весит больше описания,
+-- текст разбирается русской и английской конфигурациями.
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce("desc", '')), 'B') ||
+    setweight(to_tsvector('english', coalesce("desc", '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_ads_search_vector ON ads USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS blacklist_evidences;
+DROP TABLE IF EXISTS blacklist_entries;
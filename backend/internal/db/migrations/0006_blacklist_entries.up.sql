@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS blacklist_entries (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    username   varchar(64),
+    reason     varchar(1024),
+    added_by   bigint,
+    removed_at timestamptz,
+    removed_by bigint,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_blacklist_entries_user_id ON blacklist_entries (user_id);
+CREATE INDEX IF NOT EXISTS idx_blacklist_entries_username ON blacklist_entries (username);
+CREATE INDEX IF NOT EXISTS idx_blacklist_entries_removed_at ON blacklist_entries (removed_at);
+
+CREATE TABLE IF NOT EXISTS blacklist_evidences (
+    id         bigserial PRIMARY KEY,
+    entry_id   bigint,
+    file_id    varchar(256),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_blacklist_evidences_entry_id ON blacklist_evidences (entry_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_blacklist_entries_evidence') THEN
+        ALTER TABLE blacklist_evidences ADD CONSTRAINT fk_blacklist_entries_evidence
+            FOREIGN KEY (entry_id) REFERENCES blacklist_entries (id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Пользователи, внесённые до появления истории, получают запись без причины
+INSERT INTO blacklist_entries (user_id, username, reason, added_by, created_at, updated_at)
+SELECT u.id, u.username, '', 0, u.updated_at, u.updated_at
+FROM users u
+WHERE u.is_scammer = true
+  AND u.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM blacklist_entries e WHERE e.user_id = u.id AND e.removed_at IS NULL);
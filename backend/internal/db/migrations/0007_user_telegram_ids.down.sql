@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_aliases;
+DROP INDEX IF EXISTS idx_blacklist_entries_telegram_id;
+ALTER TABLE blacklist_entries DROP COLUMN IF EXISTS telegram_id;
+DROP INDEX IF EXISTS idx_users_telegram_id;
+ALTER TABLE users DROP COLUMN IF EXISTS telegram_id;
@@ -0,0 +1,22 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS telegram_id bigint;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_telegram_id ON users (telegram_id);
+
+ALTER TABLE blacklist_entries ADD COLUMN IF NOT EXISTS telegram_id bigint;
+CREATE INDEX IF NOT EXISTS idx_blacklist_entries_telegram_id ON blacklist_entries (telegram_id);
+
+CREATE TABLE IF NOT EXISTS user_aliases (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    username   varchar(64),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_aliases_user_id ON user_aliases (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_aliases_username ON user_aliases (username);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_users_aliases') THEN
+        ALTER TABLE user_aliases ADD CONSTRAINT fk_users_aliases
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+    END IF;
+END $$;
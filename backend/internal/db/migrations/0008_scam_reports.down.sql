@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS report_screenshots;
+DROP TABLE IF EXISTS scam_reports;
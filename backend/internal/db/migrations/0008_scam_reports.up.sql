@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS scam_reports (
+    id                 bigserial PRIMARY KEY,
+    reporter_id        bigint,
+    reporter_username  varchar(64),
+    target_username    varchar(64),
+    target_telegram_id bigint,
+    ad_id              bigint,
+    category           varchar(32),
+    text               varchar(2048),
+    status             varchar(16),
+    reviewed_by        bigint,
+    reviewed_at        timestamptz,
+    blacklist_entry_id bigint,
+    created_at         timestamptz,
+    updated_at         timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_scam_reports_reporter_id ON scam_reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_scam_reports_target_username ON scam_reports (target_username);
+CREATE INDEX IF NOT EXISTS idx_scam_reports_ad_id ON scam_reports (ad_id);
+CREATE INDEX IF NOT EXISTS idx_scam_reports_status ON scam_reports (status);
+
+CREATE TABLE IF NOT EXISTS report_screenshots (
+    id           bigserial PRIMARY KEY,
+    report_id    bigint,
+    content_type varchar(64),
+    data         bytea,
+    file_id      varchar(256),
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_report_screenshots_report_id ON report_screenshots (report_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_scam_reports_screenshots') THEN
+        ALTER TABLE report_screenshots ADD CONSTRAINT fk_scam_reports_screenshots
+            FOREIGN KEY (report_id) REFERENCES scam_reports (id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- Ключ фото объявления в хранилище файлов (локальный диск или S3).
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS photo_key varchar(256);
@@ -0,0 +1,11 @@
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS photo_id varchar(256);
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS photo_path varchar(512);
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS photo_key varchar(256);
+
+-- Возвращаем в объявление обложку галереи, остальные фото теряются
+UPDATE ads SET photo_id = p.file_id, photo_key = p.key
+FROM ad_photos p
+WHERE p.ad_id = ads.id AND p.position = 0;
+
+ALTER TABLE ads DROP COLUMN IF EXISTS photo_count;
+DROP TABLE IF EXISTS ad_photos;
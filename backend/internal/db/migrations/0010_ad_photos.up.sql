@@ -0,0 +1,33 @@
+-- Галерея фото объявления вместо одного фото в строке ads
+CREATE TABLE IF NOT EXISTS ad_photos (
+    id         bigserial PRIMARY KEY,
+    ad_id      bigint,
+    position   bigint,
+    file_id    varchar(256),
+    key        varchar(256),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ad_photos_ad_position ON ad_photos (ad_id, position);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_ad_photos_ad') THEN
+        ALTER TABLE ad_photos ADD CONSTRAINT fk_ad_photos_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS photo_count bigint NOT NULL DEFAULT 0;
+
+-- Переносим единственное фото старых объявлений в галерею
+INSERT INTO ad_photos (ad_id, position, file_id, key, created_at)
+SELECT id, 0, photo_id, coalesce(photo_key, ''), coalesce(updated_at, now())
+FROM ads
+WHERE coalesce(photo_id, '') <> ''
+ON CONFLICT (ad_id, position) DO NOTHING;
+
+UPDATE ads SET photo_count = 1 WHERE coalesce(photo_id, '') <> '' AND photo_count = 0;
+
+ALTER TABLE ads DROP COLUMN IF EXISTS photo_id;
+ALTER TABLE ads DROP COLUMN IF EXISTS photo_path;
+ALTER TABLE ads DROP COLUMN IF EXISTS photo_key;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          bigserial PRIMARY KEY,
+    actor_id    bigint,
+    action      varchar(32),
+    entity_type varchar(16),
+    entity_id   varchar(64),
+    changes     jsonb,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS managers (
+    telegram_id bigint PRIMARY KEY,
+    username    varchar(64),
+    role        varchar(16),
+    added_by    bigint,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_managers_role ON managers (role);
@@ -0,0 +1,32 @@
+-- Снимки объявлений при каждом сохранении для истории и отката
+CREATE TABLE IF NOT EXISTS ad_revisions (
+    id            bigserial PRIMARY KEY,
+    ad_id         bigint,
+    editor_id     bigint,
+    username      varchar(64),
+    title         varchar(128),
+    "desc"        varchar(2048),
+    category      varchar(32),
+    mode          varchar(16),
+    tag           varchar(64),
+    is_premium    boolean,
+    status        varchar(16),
+    expires_at    timestamptz,
+    restored_from bigint,
+    created_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_ad_revisions_ad_id ON ad_revisions (ad_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_ad_revisions_ad') THEN
+        ALTER TABLE ad_revisions ADD CONSTRAINT fk_ad_revisions_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Текущее состояние существующих объявлений становится их первой ревизией
+INSERT INTO ad_revisions (ad_id, editor_id, username, title, "desc", category, mode, tag, is_premium, status, expires_at, created_at)
+SELECT id, 0, username, title, "desc", category, mode, tag, is_premium, status, expires_at, coalesce(updated_at, now())
+FROM ads
+WHERE NOT EXISTS (SELECT 1 FROM ad_revisions r WHERE r.ad_id = ads.id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id                 bigserial PRIMARY KEY,
+    ad_id              bigint,
+    user_id            bigint,
+    product            varchar(16),
+    days               bigint,
+    amount             bigint,
+    currency           varchar(8),
+    status             varchar(16),
+    telegram_charge_id varchar(128),
+    provider_charge_id varchar(128),
+    paid_at            timestamptz,
+    refunded_at        timestamptz,
+    refunded_by        bigint,
+    created_at         timestamptz,
+    updated_at         timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_payments_ad_id ON payments (ad_id);
+CREATE INDEX IF NOT EXISTS idx_payments_user_id ON payments (user_id);
+CREATE INDEX IF NOT EXISTS idx_payments_status ON payments (status);
+CREATE INDEX IF NOT EXISTS idx_payments_telegram_charge_id ON payments (telegram_charge_id);
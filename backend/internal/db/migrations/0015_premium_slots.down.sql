@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS premium_bookings;
+DROP TABLE IF EXISTS premium_slot_limits;
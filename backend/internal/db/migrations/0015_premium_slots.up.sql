@@ -0,0 +1,32 @@
+-- Лимиты премиум-мест по категориям; для категорий без записи действует лимит по умолчанию
+CREATE TABLE IF NOT EXISTS premium_slot_limits (
+    category   varchar(32) PRIMARY KEY,
+    max_active bigint,
+    updated_by bigint,
+    updated_at timestamptz
+);
+
+-- Очередь на премиум-места и окна, которые получили поднятые из неё объявления
+CREATE TABLE IF NOT EXISTS premium_bookings (
+    id           bigserial PRIMARY KEY,
+    ad_id        bigint,
+    requested_by bigint,
+    status       varchar(16),
+    starts_at    timestamptz,
+    ends_at      timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_premium_bookings_ad_id ON premium_bookings (ad_id);
+CREATE INDEX IF NOT EXISTS idx_premium_bookings_status ON premium_bookings (status);
+-- У объявления не больше одной живой заявки
+CREATE UNIQUE INDEX IF NOT EXISTS idx_premium_bookings_open_ad ON premium_bookings (ad_id)
+    WHERE status IN ('queued', 'active');
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_premium_bookings_ad') THEN
+        ALTER TABLE premium_bookings ADD CONSTRAINT fk_premium_bookings_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
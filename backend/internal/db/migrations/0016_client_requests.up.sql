@@ -0,0 +1,24 @@
+-- Заявки владельцев объявлений из бота (продление, снятие)
+CREATE TABLE IF NOT EXISTS client_requests (
+    id         bigserial PRIMARY KEY,
+    ad_id      bigint,
+    user_id    bigint,
+    kind       varchar(16),
+    days       bigint,
+    status     varchar(16),
+    handled_by bigint,
+    handled_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_client_requests_ad_id ON client_requests (ad_id);
+CREATE INDEX IF NOT EXISTS idx_client_requests_user_id ON client_requests (user_id);
+CREATE INDEX IF NOT EXISTS idx_client_requests_status ON client_requests (status);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_client_requests_ad') THEN
+        ALTER TABLE client_requests ADD CONSTRAINT fk_client_requests_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,38 @@
+-- Сохранённые поиски пользователей Mini App
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    category   varchar(32),
+    mode       varchar(16),
+    tag        varchar(64),
+    query      varchar(256),
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions (user_id);
+
+-- Оповещения по подпискам: одно объявление приходит пользователю не больше одного раза
+CREATE TABLE IF NOT EXISTS subscription_alerts (
+    id              bigserial PRIMARY KEY,
+    subscription_id bigint,
+    user_id         bigint,
+    ad_id           bigint,
+    digest          boolean DEFAULT false,
+    sent_at         timestamptz,
+    created_at      timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_subscription_alerts_subscription_id ON subscription_alerts (subscription_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_alerts_user_ad ON subscription_alerts (user_id, ad_id);
+CREATE INDEX IF NOT EXISTS idx_subscription_alerts_sent_at ON subscription_alerts (sent_at);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscription_alerts_subscription') THEN
+        ALTER TABLE subscription_alerts ADD CONSTRAINT fk_subscription_alerts_subscription
+            FOREIGN KEY (subscription_id) REFERENCES subscriptions (id) ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscription_alerts_ad') THEN
+        ALTER TABLE subscription_alerts ADD CONSTRAINT fk_subscription_alerts_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_favorites_ad_soft_delete ON ads;
+DROP FUNCTION IF EXISTS favorites_delete_soft_deleted_ad();
+DROP TABLE IF EXISTS favorites;
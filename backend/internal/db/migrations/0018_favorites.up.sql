@@ -0,0 +1,34 @@
+-- Избранные объявления пользователей Mini App
+CREATE TABLE IF NOT EXISTS favorites (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    ad_id      bigint,
+    notify     boolean DEFAULT true,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_user_ad ON favorites (user_id, ad_id);
+CREATE INDEX IF NOT EXISTS idx_favorites_ad_id ON favorites (ad_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_favorites_ad') THEN
+        ALTER TABLE favorites ADD CONSTRAINT fk_favorites_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Объявления удаляются мягко (deleted_at), поэтому внешний ключ не срабатывает:
+-- избранное чистится триггером при проставлении deleted_at
+CREATE OR REPLACE FUNCTION favorites_delete_soft_deleted_ad() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM favorites WHERE ad_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_favorites_ad_soft_delete ON ads;
+CREATE TRIGGER trg_favorites_ad_soft_delete
+    AFTER UPDATE OF deleted_at ON ads
+    FOR EACH ROW
+    WHEN (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL)
+    EXECUTE FUNCTION favorites_delete_soft_deleted_ad();
@@ -0,0 +1,40 @@
+-- Пользователи Mini App: дата первого и последнего визита
+CREATE TABLE IF NOT EXISTS telegram_users (
+    telegram_id   bigint PRIMARY KEY,
+    username      varchar(64),
+    first_seen_at timestamptz,
+    last_seen_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_telegram_users_username ON telegram_users (username);
+
+-- Отзывы об авторах объявлений
+CREATE TABLE IF NOT EXISTS reviews (
+    id                bigserial PRIMARY KEY,
+    ad_id             bigint,
+    reviewer_id       bigint,
+    reviewer_username varchar(64),
+    target_id         bigint,
+    rating            bigint,
+    comment           varchar(1024),
+    status            varchar(16),
+    verified          boolean DEFAULT false,
+    moderated_by      bigint,
+    moderated_at      timestamptz,
+    created_at        timestamptz,
+    updated_at        timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_pair_ad ON reviews (reviewer_id, target_id, ad_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_ad_id ON reviews (ad_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_target_id ON reviews (target_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_reviews_rating') THEN
+        ALTER TABLE reviews ADD CONSTRAINT chk_reviews_rating CHECK (rating BETWEEN 1 AND 5);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_reviews_ad') THEN
+        ALTER TABLE reviews ADD CONSTRAINT fk_reviews_ad
+            FOREIGN KEY (ad_id) REFERENCES ads (id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,22 @@
+-- Сделки через гаранта
+CREATE TABLE IF NOT EXISTS deals (
+    id              bigserial PRIMARY KEY,
+    ad_id           bigint,
+    seller_id       bigint,
+    seller_username varchar(64),
+    buyer_id        bigint,
+    buyer_username  varchar(64),
+    amount          bigint,
+    fee             bigint,
+    status          varchar(16),
+    manager_id      bigint,
+    funded_at       timestamptz,
+    delivered_at    timestamptz,
+    closed_at       timestamptz,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_deals_ad_id ON deals (ad_id);
+CREATE INDEX IF NOT EXISTS idx_deals_seller_id ON deals (seller_id);
+CREATE INDEX IF NOT EXISTS idx_deals_buyer_id ON deals (buyer_id);
+CREATE INDEX IF NOT EXISTS idx_deals_status ON deals (status);
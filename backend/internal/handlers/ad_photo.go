@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -13,11 +19,197 @@ import (
 	"youtube-market/internal/metrics"
 	"youtube-market/internal/middleware"
 	"youtube-market/internal/models"
+	"youtube-market/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// maxAdPhotoSize — предел размера скачиваемого из Telegram фото (Bot API отдаёт файлы до 20 МБ)
+const maxAdPhotoSize = 20 << 20
+
+// errTelegramFileNotFound — Telegram больше не отдаёт файл по этому file_id
+var errTelegramFileNotFound = errors.New("telegram file not found")
+
+var telegramFileClient = &http.Client{Timeout: 30 * time.Second}
+
+var adPhotoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// downloadTelegramFile получает путь к файлу через getFile и скачивает его.
+// Пути Telegram живут около часа, поэтому каждый раз запрашиваем свежий.
+func downloadTelegramFile(ctx context.Context, fileID string) ([]byte, error) {
+	token := getBotToken()
+	if token == "" {
+		return nil, fmt.Errorf("BOT_TOKEN is not set")
+	}
+
+	getFileURL := fmt.Sprintf("%s/bot%s/getFile?file_id=%s", telegramAPIURL(), token, url.QueryEscape(fileID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getFileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := telegramFileClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getFile request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			FilePath string `json:"file_path"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode getFile response: %w", err)
+	}
+	if !result.OK || result.Result.FilePath == "" {
+		// На неизвестный или устаревший file_id Telegram отвечает 400
+		return nil, errTelegramFileNotFound
+	}
+
+	fileURL := fmt.Sprintf("%s/file/bot%s/%s", telegramAPIURL(), token, strings.TrimPrefix(result.Result.FilePath, "/"))
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	fileResp, err := telegramFileClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("file download failed: %w", err)
+	}
+	defer fileResp.Body.Close()
+
+	if fileResp.StatusCode == http.StatusNotFound {
+		return nil, errTelegramFileNotFound
+	}
+	if fileResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram returned status %d", fileResp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(fileResp.Body, maxAdPhotoSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAdPhotoSize {
+		return nil, fmt.Errorf("file is larger than %d MB", maxAdPhotoSize>>20)
+	}
+	return data, nil
+}
+
+// storeAdPhoto сохраняет оригинал фото в хранилище под ключом из SHA-256 содержимого.
+// Ключ неизменен для одинаковых данных, поэтому его хэш служит основой ETag.
+func storeAdPhoto(ctx context.Context, data []byte) (string, string, error) {
+	if storage.Photos == nil {
+		return "", "", fmt.Errorf("photo storage is not initialized")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := adPhotoExtensions[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported photo type %s", contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	key := "ads/" + hex.EncodeToString(sum[:]) + ext
+	if err := storage.Photos.Put(ctx, key, data, contentType); err != nil {
+		return "", "", err
+	}
+	return key, contentType, nil
+}
+
+// fetchAdPhoto скачивает фото из Telegram по file_id и кладёт его в хранилище
+func fetchAdPhoto(ctx context.Context, fileID string) (string, error) {
+	data, err := downloadTelegramFile(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	key, _, err := storeAdPhoto(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	go warmAdPhotoVariants(key, data)
+	return key, nil
+}
+
+// loadAdPhotos возвращает галерею объявления в порядке показа
+func loadAdPhotos(adID uint) ([]models.AdPhoto, error) {
+	var photos []models.AdPhoto
+	err := db.DB.Where("ad_id = ?", adID).Order("position ASC").Find(&photos).Error
+	return photos, err
+}
+
+// replaceAdPhotos заменяет галерею объявления и обновляет счётчик фото в ads
+func replaceAdPhotos(tx *gorm.DB, adID uint, photos []models.AdPhoto) error {
+	if err := tx.Where("ad_id = ?", adID).Delete(&models.AdPhoto{}).Error; err != nil {
+		return err
+	}
+	if len(photos) > 0 {
+		rows := make([]models.AdPhoto, len(photos))
+		for i, photo := range photos {
+			key := photo.Key
+			if key == "" {
+				key = prefetchedAdPhotoKey(photo.FileID)
+			}
+			rows[i] = models.AdPhoto{AdID: adID, Position: i, FileID: photo.FileID, Key: key}
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Ad{}).Where("id = ?", adID).UpdateColumn("photo_count", len(photos)).Error
+}
+
+// loadAdPhoto читает фото из хранилища. Если объекта нет, фото заново скачивается
+// из Telegram по FileID и новый ключ сохраняется в ad_photos.
+func loadAdPhoto(ctx context.Context, photo *models.AdPhoto) (*storage.Object, error) {
+	if storage.Photos == nil {
+		return nil, fmt.Errorf("photo storage is not initialized")
+	}
+
+	if photo.Key != "" {
+		obj, err := storage.Photos.Get(ctx, photo.Key)
+		if err == nil {
+			return obj, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		log.Printf("GetAdPhoto: фото %s объявления ID=%d отсутствует в хранилище", photo.Key, photo.AdID)
+	}
+
+	if photo.FileID == "" {
+		return nil, storage.ErrNotFound
+	}
+
+	log.Printf("GetAdPhoto: скачиваем фото %d объявления ID=%d из Telegram по FileID", photo.Position, photo.AdID)
+	data, err := downloadTelegramFile(ctx, photo.FileID)
+	if err != nil {
+		return nil, err
+	}
+	key, contentType, err := storeAdPhoto(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != photo.Key {
+		queryStart := time.Now()
+		if err := db.DB.Model(&models.AdPhoto{}).Where("id = ?", photo.ID).Update("key", key).Error; err != nil {
+			log.Printf("GetAdPhoto: не удалось сохранить ключ фото %d объявления ID=%d: %v", photo.ID, photo.AdID, err)
+		}
+		metrics.DatabaseQueryDuration.WithLabelValues("update").Observe(time.Since(queryStart).Seconds())
+		photo.Key = key
+	}
+
+	return &storage.Object{Data: data, ContentType: contentType, ModTime: time.Now()}, nil
+}
+
+// GetAdPhoto отдаёт фото объявления номер :n из галереи (с нуля); без :n — обложку.
+// ?size=thumb|medium|full выбирает размер (по умолчанию full).
 func GetAdPhoto(c *gin.Context) {
 	start := time.Now()
 	id, err := strconv.Atoi(c.Param("id"))
@@ -28,173 +220,92 @@ func GetAdPhoto(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ad id"})
 		return
 	}
-	
-	log.Printf("GetAdPhoto: запрос фото для объявления ID=%d", id)
+	position := 0
+	if raw := c.Param("n"); raw != "" {
+		position, err = strconv.Atoi(raw)
+		if err != nil || position < 0 {
+			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "ad_photo").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo number"})
+			return
+		}
+	}
+	size := c.DefaultQuery("size", defaultAdPhotoVariant)
+	if _, ok := adPhotoVariants[size]; !ok {
+		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "ad_photo").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be thumb, medium or full"})
+		return
+	}
 
 	queryStart := time.Now()
-	var ad models.Ad
-	if err := db.DB.First(&ad, id).Error; err != nil {
-		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
+	var photo models.AdPhoto
+	if err := db.DB.
+		Joins("JOIN ads ON ads.id = ad_photos.ad_id AND ads.deleted_at IS NULL").
+		Where("ad_photos.ad_id = ? AND ad_photos.position = ?", id, position).
+		First(&photo).Error; err != nil {
 		metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
+			c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
 		} else {
 			middleware.CaptureError(c, err, map[string]string{
 				"handler": "GetAdPhoto",
 				"ad_id":   strconv.Itoa(id),
 			})
+			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "500").Inc()
 			metrics.ErrorsTotal.WithLabelValues("database", "ad_photo").Inc()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch ad"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch photo"})
 		}
 		return
 	}
 	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
 
-	if ad.PhotoPath == "" {
-		log.Printf("GetAdPhoto: объявление ID=%d не имеет фото (PhotoPath пустой)", id)
-		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
-		c.Status(http.StatusNotFound)
-		return
-	}
-
-	token := getBotToken()
-	if token == "" {
-		log.Printf("GetAdPhoto: BOT_TOKEN не установлен")
-		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "500").Inc()
-		metrics.ErrorsTotal.WithLabelValues("config", "ad_photo").Inc()
-		c.Status(http.StatusNotFound)
-		return
-	}
-
-	// Определяем путь к файлу
-	var photoPath string
-	var photoURL string
+	// Устанавливаем CORS заголовки для изображений
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+	// Под тем же номером может оказаться другое фото — браузер перепроверяет по ETag
+	c.Header("Cache-Control", "public, max-age=3600")
 
-	if ad.PhotoPath != "" {
-		// Используем PhotoPath (новый формат)
-		photoPath = ad.PhotoPath
-		// Убеждаемся, что путь не начинается с "/"
-		if strings.HasPrefix(photoPath, "/") {
-			photoPath = strings.TrimPrefix(photoPath, "/")
-		}
-		photoURL = fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", token, photoPath)
-		log.Printf("GetAdPhoto: используем PhotoPath для объявления ID=%d, PhotoPath=%s", id, ad.PhotoPath)
-	} else if ad.PhotoID != "" {
-		// Fallback: если PhotoPath пустой, но есть PhotoID, используем PhotoID напрямую
-		// Telegram API позволяет получить файл по FileID через специальный endpoint
-		// Но лучше использовать getFile для получения пути
-		log.Printf("GetAdPhoto: PhotoPath пустой, но есть PhotoID=%s для объявления ID=%d, пытаемся получить путь через getFile", ad.PhotoID, id)
-		
-		// Пытаемся получить путь через getFile API
-		getFileURL := fmt.Sprintf("https://api.telegram.org/bot%s/getFile?file_id=%s", token, ad.PhotoID)
-		resp, err := http.Get(getFileURL)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			defer resp.Body.Close()
-			var result struct {
-				OK     bool `json:"ok"`
-				Result struct {
-					FilePath string `json:"file_path"`
-				} `json:"result"`
-			}
-			if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.OK && result.Result.FilePath != "" {
-				photoPath = result.Result.FilePath
-				photoURL = fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", token, photoPath)
-				log.Printf("GetAdPhoto: успешно получен путь через getFile: PhotoPath=%s", photoPath)
-			} else {
-				log.Printf("GetAdPhoto: не удалось получить путь через getFile для PhotoID=%s", ad.PhotoID)
-				metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
-				c.Status(http.StatusNotFound)
-				return
-			}
-		} else {
-			log.Printf("GetAdPhoto: ошибка при запросе getFile для PhotoID=%s: %v", ad.PhotoID, err)
-			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
-			c.Status(http.StatusNotFound)
+	// ETag известен по ключу, поэтому на повторный запрос отвечаем 304, не читая файл
+	if photo.Key != "" {
+		etag := adPhotoETag(photo.Key, size)
+		if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+			c.Header("ETag", etag)
+			c.Status(http.StatusNotModified)
+			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "304").Inc()
+			metrics.APIReponseTime.WithLabelValues("ad_photo").Observe(time.Since(start).Seconds())
 			return
 		}
-	} else {
-		log.Printf("GetAdPhoto: объявление ID=%d не имеет ни PhotoPath, ни PhotoID", id)
-		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
-		c.Status(http.StatusNotFound)
-		return
 	}
 
-	log.Printf("GetAdPhoto: запрос фото из Telegram API для объявления ID=%d, URL=%s", id, photoURL)
-	
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Get(photoURL)
+	obj, err := loadAdPhotoVariant(c.Request.Context(), &photo, size)
 	if err != nil {
-		log.Printf("GetAdPhoto: ошибка при запросе к Telegram API: %v", err)
-		middleware.CaptureError(c, err, map[string]string{
-			"handler":    "GetAdPhoto",
-			"ad_id":      strconv.Itoa(id),
-			"error_type": "external_api",
-			"photo_path": ad.PhotoPath,
-		})
-		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "502").Inc()
-		metrics.ErrorsTotal.WithLabelValues("external_api", "ad_photo").Inc()
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch photo"})
-		return
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("GetAdPhoto: Telegram API вернул статус %d для объявления ID=%d, PhotoPath=%s", resp.StatusCode, id, ad.PhotoPath)
-		
-		// Если файл не найден (404), возвращаем 404, а не 502
-		if resp.StatusCode == http.StatusNotFound {
-			log.Printf("GetAdPhoto: файл не найден в Telegram, возможно был удален. Объявление ID=%d, PhotoPath=%s", id, ad.PhotoPath)
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, errTelegramFileNotFound) {
+			log.Printf("GetAdPhoto: фото %d объявления ID=%d недоступно: %v", position, id, err)
 			metrics.APIRequestsTotal.WithLabelValues("ad_photo", "404").Inc()
-			metrics.ErrorsTotal.WithLabelValues("external_api", "ad_photo").Inc()
 			c.Status(http.StatusNotFound)
 			return
 		}
-		
-		// Для других ошибок возвращаем 502
-		middleware.CaptureError(c, fmt.Errorf("telegram API returned status %d", resp.StatusCode), map[string]string{
-			"handler":     "GetAdPhoto",
-			"ad_id":       strconv.Itoa(id),
-			"error_type":  "external_api",
-			"status_code": strconv.Itoa(resp.StatusCode),
-			"photo_path":  ad.PhotoPath,
+		log.Printf("GetAdPhoto: ошибка получения фото %d объявления ID=%d: %v", position, id, err)
+		middleware.CaptureError(c, err, map[string]string{
+			"handler":    "GetAdPhoto",
+			"ad_id":      strconv.Itoa(id),
+			"error_type": "storage",
+			"photo_key":  photo.Key,
 		})
 		metrics.APIRequestsTotal.WithLabelValues("ad_photo", "502").Inc()
-		metrics.ErrorsTotal.WithLabelValues("external_api", "ad_photo").Inc()
+		metrics.ErrorsTotal.WithLabelValues("storage", "ad_photo").Inc()
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch photo"})
 		return
 	}
 
-	// Устанавливаем CORS заголовки для изображений
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
-	c.Header("Cache-Control", "public, max-age=3600") // Кэшируем на 1 час
+	c.Header("ETag", adPhotoETag(photo.Key, size))
+	c.Header("Content-Type", obj.ContentType)
 
-	// Устанавливаем заголовки из ответа Telegram API
-	for k, values := range resp.Header {
-		if len(values) == 0 {
-			continue
-		}
-		switch k {
-		case "Content-Type", "Content-Length":
-			c.Header(k, values[0])
-		}
-	}
+	// ServeContent выставляет Last-Modified и отвечает 304 на If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, "", obj.ModTime, bytes.NewReader(obj.Data))
 
-	// Устанавливаем статус и копируем тело ответа
-	c.Status(http.StatusOK)
-	
-	// Копируем тело ответа
-	bytesCopied, err := io.Copy(c.Writer, resp.Body)
-	if err != nil {
-		log.Printf("GetAdPhoto: ошибка при копировании тела ответа: %v", err)
-		// Не возвращаем ошибку клиенту, так как заголовки уже отправлены
-		return
-	}
-	
-	log.Printf("GetAdPhoto: успешно отправлено %d байт для объявления ID=%d", bytesCopied, id)
-	metrics.APIRequestsTotal.WithLabelValues("ad_photo", "200").Inc()
+	metrics.APIRequestsTotal.WithLabelValues("ad_photo", strconv.Itoa(c.Writer.Status())).Inc()
 	metrics.APIReponseTime.WithLabelValues("ad_photo").Observe(time.Since(start).Seconds())
 }
-
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"youtube-market/internal/imaging"
+	"youtube-market/internal/models"
+	"youtube-market/internal/storage"
+)
+
+// adPhotoVariant — размер, в котором Mini App запрашивает фото (?size=)
+type adPhotoVariant struct {
+	MaxWidth int
+	Quality  int
+}
+
+const defaultAdPhotoVariant = "full"
+
+var adPhotoVariants = map[string]adPhotoVariant{
+	"thumb":  {MaxWidth: 320, Quality: 75},
+	"medium": {MaxWidth: 800, Quality: 82},
+	"full":   {MaxWidth: 2048, Quality: 88},
+}
+
+// adPhotoHash — SHA-256 содержимого из ключа вида ads/<sha256>.jpg
+func adPhotoHash(key string) string {
+	return strings.TrimSuffix(path.Base(key), path.Ext(key))
+}
+
+func adPhotoVariantKey(key, size string) string {
+	return "variants/" + adPhotoHash(key) + "_" + size + ".jpg"
+}
+
+// adPhotoETag не зависит от даты файла: варианты одного фото всегда одинаковы
+func adPhotoETag(key, size string) string {
+	return `"` + adPhotoHash(key) + "-" + size + `"`
+}
+
+// etagMatches проверяет заголовок If-None-Match (список ETag через запятую или *)
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAdPhotoVariant отдаёт уменьшенную копию фото без метаданных. Копия строится
+// из оригинала при первом запросе и кэшируется в хранилище рядом с ним.
+func loadAdPhotoVariant(ctx context.Context, photo *models.AdPhoto, size string) (*storage.Object, error) {
+	if photo.Key != "" && storage.Photos != nil {
+		obj, err := storage.Photos.Get(ctx, adPhotoVariantKey(photo.Key, size))
+		if err == nil {
+			return obj, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	original, err := loadAdPhoto(ctx, photo)
+	if err != nil {
+		return nil, err
+	}
+	return buildAdPhotoVariant(ctx, photo.Key, original.Data, size)
+}
+
+func buildAdPhotoVariant(ctx context.Context, key string, original []byte, size string) (*storage.Object, error) {
+	variant := adPhotoVariants[size]
+	data, err := imaging.ResizeJPEG(original, variant.MaxWidth, variant.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.Photos.Put(ctx, adPhotoVariantKey(key, size), data, "image/jpeg"); err != nil {
+		// Отдаём копию даже без кэша — в следующий раз попробуем сохранить снова
+		log.Printf("Не удалось сохранить вариант %s фото %s: %v", size, key, err)
+	}
+	return &storage.Object{Data: data, ContentType: "image/jpeg", ModTime: time.Now()}, nil
+}
+
+// warmAdPhotoVariants заранее строит все варианты только что загруженного фото,
+// чтобы первый показ в Mini App не ждал обработки
+func warmAdPhotoVariants(key string, original []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for size := range adPhotoVariants {
+		if _, err := buildAdPhotoVariant(ctx, key, original, size); err != nil {
+			log.Printf("Не удалось подготовить вариант %s фото %s: %v", size, key, err)
+		}
+	}
+}
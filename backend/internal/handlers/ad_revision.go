@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"youtube-market/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// saveAdRevision сохраняет снимок объявления после изменения. editorID — Telegram ID
+// менеджера или автора из Mini App, restoredFrom — ревизия, из которой восстановлено объявление.
+// Вызывается в той же транзакции, что и сохранение объявления.
+func saveAdRevision(tx *gorm.DB, ad models.Ad, editorID int64, restoredFrom *uint) error {
+	return tx.Create(&models.AdRevision{
+		AdID:         ad.ID,
+		EditorID:     editorID,
+		Username:     ad.Username,
+		Title:        ad.Title,
+		Desc:         ad.Desc,
+		Category:     ad.Category,
+		Mode:         ad.Mode,
+		Tag:          ad.Tag,
+		IsPremium:    ad.IsPremium,
+		Status:       ad.Status,
+		ExpiresAt:    ad.ExpiresAt,
+		RestoredFrom: restoredFrom,
+	}).Error
+}
+
+// applyAdRevision возвращает содержимое объявления к ревизии. Срок, статус и премиум не трогаем:
+// откат меняет текст объявления, а не его размещение, оплаченное в Stars или полученное из очереди.
+func applyAdRevision(ad *models.Ad, rev models.AdRevision) {
+	ad.Username = rev.Username
+	ad.Title = rev.Title
+	ad.Desc = rev.Desc
+	ad.Category = rev.Category
+	ad.Mode = rev.Mode
+	ad.Tag = rev.Tag
+}
+
+// adRevisionField — поле, которое показывается в истории изменений
+type adRevisionField struct {
+	label string
+	value func(models.AdRevision) string
+}
+
+var adRevisionFields = []adRevisionField{
+	{"заголовок", func(r models.AdRevision) string { return r.Title }},
+	{"описание", func(r models.AdRevision) string { return r.Desc }},
+	{"категория", func(r models.AdRevision) string {
+		if label, ok := categoryLabels[r.Category]; ok {
+			return label
+		}
+		return r.Category
+	}},
+	{"тег", func(r models.AdRevision) string { return r.Tag }},
+	{"премиум", func(r models.AdRevision) string {
+		if r.IsPremium {
+			return "да"
+		}
+		return "нет"
+	}},
+}
+
+// adRevisionChange — изменение поля между соседними ревизиями
+type adRevisionChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+func diffAdRevisions(prev, next models.AdRevision) []adRevisionChange {
+	var changes []adRevisionChange
+	for _, field := range adRevisionFields {
+		from, to := field.value(prev), field.value(next)
+		if from != to {
+			changes = append(changes, adRevisionChange{Field: field.label, From: from, To: to})
+		}
+	}
+	return changes
+}
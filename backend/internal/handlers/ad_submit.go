@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adSubmission — тело запроса на создание или изменение объявления из Mini App
+type adSubmission struct {
+	Title        string `json:"title"`
+	Desc         string `json:"desc"`
+	Category     string `json:"category"`
+	Mode         string `json:"mode"`
+	Tag          string `json:"tag"`
+	DurationDays int    `json:"duration_days"`
+}
+
+// apply переносит поля заявки в объявление и проверяет их так же, как persistAd
+func (s adSubmission) apply(ad *models.Ad) error {
+	ad.Title = truncate(strings.TrimSpace(s.Title), 128)
+	ad.Desc = truncate(strings.TrimSpace(s.Desc), 2048)
+	ad.Category = strings.TrimSpace(s.Category)
+	ad.Mode = strings.TrimSpace(s.Mode)
+	ad.Tag = strings.TrimSpace(s.Tag)
+
+	if err := validateAdFields(ad); err != nil {
+		return err
+	}
+	if !isValidDuration(s.DurationDays) {
+		return errInvalidDuration
+	}
+	ad.RequestedDays = s.DurationDays
+	return nil
+}
+
+var errInvalidDuration = errors.New("срок размещения должен быть 1, 7, 14 или 30 дней")
+
+// CreateAd создаёт объявление от имени пользователя Mini App.
+// Объявление попадает в статус pending и ждёт модерации менеджером.
+func CreateAd(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("create_ad", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	var req adSubmission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("create_ad", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_ad").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	ad := models.Ad{
+		UserID:   userID,
+		ClientID: strconv.FormatInt(userID, 10),
+		Username: middleware.AuthUsername(c),
+		Status:   models.AdStatusPending,
+	}
+	if err := req.apply(&ad); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("create_ad", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_ad").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryStart := time.Now()
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ad).Error; err != nil {
+			return err
+		}
+		return saveAdRevision(tx, ad, userID, nil)
+	}); err != nil {
+		log.Printf("CreateAd: ошибка создания объявления: %v", err)
+		middleware.CaptureError(c, err, map[string]string{
+			"handler": "CreateAd",
+		})
+		metrics.APIRequestsTotal.WithLabelValues("create_ad", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_ad").Inc()
+		metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось сохранить объявление. Попробуйте позже."})
+		return
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+
+	log.Printf("CreateAd: создано объявление ID=%d от пользователя %d, ожидает модерации", ad.ID, userID)
+
+	metrics.APIRequestsTotal.WithLabelValues("create_ad", "201").Inc()
+	metrics.APIReponseTime.WithLabelValues("create_ad").Observe(time.Since(start).Seconds())
+
+	c.JSON(http.StatusCreated, buildAdView(ad))
+}
+
+// UpdateAd изменяет объявление владельца и отправляет его на повторную модерацию
+func UpdateAd(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "update_ad").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ad id"})
+		return
+	}
+
+	var req adSubmission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "update_ad").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	var ad models.Ad
+	if err := db.DB.First(&ad, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			metrics.APIRequestsTotal.WithLabelValues("update_ad", "404").Inc()
+			c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+			return
+		}
+		middleware.CaptureError(c, err, map[string]string{
+			"handler": "UpdateAd",
+			"ad_id":   strconv.FormatUint(id, 10),
+		})
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "update_ad").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch ad"})
+		return
+	}
+
+	if ad.UserID != userID && ad.ClientID != strconv.FormatInt(userID, 10) {
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "403").Inc()
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only edit your own ads"})
+		return
+	}
+
+	if err := req.apply(&ad); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "update_ad").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if username := middleware.AuthUsername(c); username != "" {
+		ad.Username = username
+	}
+	ad.Status = models.AdStatusPending
+	ad.PreExpiryNotified = false
+	ad.RejectReason = ""
+
+	queryStart := time.Now()
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&ad).Error; err != nil {
+			return err
+		}
+		return saveAdRevision(tx, ad, userID, nil)
+	}); err != nil {
+		log.Printf("UpdateAd: ошибка обновления объявления ID=%d: %v", ad.ID, err)
+		middleware.CaptureError(c, err, map[string]string{
+			"handler": "UpdateAd",
+			"ad_id":   strconv.FormatUint(id, 10),
+		})
+		metrics.APIRequestsTotal.WithLabelValues("update_ad", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "update_ad").Inc()
+		metrics.DatabaseQueryDuration.WithLabelValues("update").Observe(time.Since(queryStart).Seconds())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось сохранить объявление. Попробуйте позже."})
+		return
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("update").Observe(time.Since(queryStart).Seconds())
+
+	log.Printf("UpdateAd: объявление ID=%d изменено пользователем %d, ожидает модерации", ad.ID, userID)
+
+	metrics.APIRequestsTotal.WithLabelValues("update_ad", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("update_ad").Observe(time.Since(start).Seconds())
+
+	c.JSON(http.StatusOK, buildAdView(ad))
+}
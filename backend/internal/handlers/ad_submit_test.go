@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdSubmissionApply(t *testing.T) {
+	valid := adSubmission{Title: "Монтаж роликов", Desc: "Быстро и недорого", Category: "services", Mode: "offer", Tag: "designer", DurationDays: 7}
+
+	tests := []struct {
+		name    string
+		edit    func(s *adSubmission)
+		wantErr string
+		check   func(t *testing.T, ad models.Ad)
+	}{
+		{name: "valid", edit: func(*adSubmission) {}, check: func(t *testing.T, ad models.Ad) {
+			if ad.RequestedDays != 7 || ad.Category != "services" || ad.Mode != "offer" {
+				t.Fatalf("unexpected ad: %+v", ad)
+			}
+		}},
+		{name: "fields are trimmed", edit: func(s *adSubmission) { s.Title = "  Монтаж  "; s.Tag = " designer " }, check: func(t *testing.T, ad models.Ad) {
+			if ad.Title != "Монтаж" || ad.Tag != "designer" {
+				t.Fatalf("fields not trimmed: %q, %q", ad.Title, ad.Tag)
+			}
+		}},
+		{name: "title is truncated", edit: func(s *adSubmission) { s.Title = strings.Repeat("я", 300) }, check: func(t *testing.T, ad models.Ad) {
+			if n := len([]rune(ad.Title)); n > 128 {
+				t.Fatalf("title has %d runes, want at most 128", n)
+			}
+		}},
+		{name: "other gets the general mode", edit: func(s *adSubmission) { s.Category = "other"; s.Mode = ""; s.Tag = "courses" }, check: func(t *testing.T, ad models.Ad) {
+			if ad.Mode != "general" {
+				t.Fatalf("mode = %q, want general", ad.Mode)
+			}
+		}},
+		{name: "empty title", edit: func(s *adSubmission) { s.Title = "   " }, wantErr: "заголовок"},
+		{name: "empty description", edit: func(s *adSubmission) { s.Desc = "" }, wantErr: "описание"},
+		{name: "unknown category", edit: func(s *adSubmission) { s.Category = "crypto" }, wantErr: "неизвестная категория"},
+		{name: "mode of another category", edit: func(s *adSubmission) { s.Mode = "sell" }, wantErr: "режим sell"},
+		{name: "empty tag", edit: func(s *adSubmission) { s.Tag = "" }, wantErr: "тег не может"},
+		{name: "tag of another category", edit: func(s *adSubmission) { s.Tag = "channel" }, wantErr: "тег channel"},
+		{name: "zero duration", edit: func(s *adSubmission) { s.DurationDays = 0 }, wantErr: errInvalidDuration.Error()},
+		{name: "unsupported duration", edit: func(s *adSubmission) { s.DurationDays = 90 }, wantErr: errInvalidDuration.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.edit(&req)
+			var ad models.Ad
+			err := req.apply(&ad)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, ad)
+		})
+	}
+}
+
+// adsResponder отдаёт объявления на SELECT из ads и id на INSERT ... RETURNING
+func adsResponder(ads ...models.Ad) fakeResponder {
+	return func(query string, args []driver.Value) (fakeResult, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT") && strings.Contains(query, `FROM "ads"`):
+			return adRows(ads...), nil
+		case strings.HasPrefix(query, "INSERT") && strings.Contains(query, "RETURNING"):
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}}, nil
+		default:
+			return fakeResult{affected: 1}, nil
+		}
+	}
+}
+
+func adRows(ads ...models.Ad) fakeResult {
+	result := fakeResult{columns: []string{"id", "user_id", "client_id", "username", "title", "desc", "category", "mode", "tag", "status", "expires_at", "is_premium"}}
+	for _, ad := range ads {
+		result.rows = append(result.rows, []driver.Value{
+			int64(ad.ID), ad.UserID, ad.ClientID, ad.Username, ad.Title, ad.Desc, ad.Category, ad.Mode, ad.Tag, ad.Status, ad.ExpiresAt, ad.IsPremium,
+		})
+	}
+	return result
+}
+
+func putAd(t *testing.T, userID int64, body adSubmission) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	r.PUT("/api/ads/:id", UpdateAd)
+
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPut, "/api/ads/42", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateAdOwnership(t *testing.T) {
+	body := adSubmission{Title: "Новый заголовок", Desc: "Новое описание", Category: "services", Mode: "offer", Tag: "voice", DurationDays: 14}
+	stored := models.Ad{ID: 42, UserID: 2002, ClientID: "2002", Title: "Старый", Desc: "Старое", Category: "services", Mode: "offer", Tag: "designer",
+		Status: models.AdStatusActive, ExpiresAt: time.Now().Add(48 * time.Hour)}
+	byClientID := stored
+	byClientID.UserID = 0
+
+	tests := []struct {
+		name       string
+		caller     int64
+		ad         models.Ad
+		wantStatus int
+		wantWrites bool
+	}{
+		{"owner by user_id", 2002, stored, http.StatusOK, true},
+		{"owner by client_id", 2002, byClientID, http.StatusOK, true},
+		{"another user", 1001, stored, http.StatusForbidden, false},
+		{"another user, ad created by a manager", 1001, byClientID, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t, adsResponder(tt.ad))
+			w := putAd(t, tt.caller, body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			writes := 0
+			for _, query := range fake.Queries() {
+				if strings.HasPrefix(query, "UPDATE") || strings.HasPrefix(query, "INSERT") {
+					writes++
+				}
+			}
+			if tt.wantWrites != (writes > 0) {
+				t.Fatalf("writes = %d, want writes: %v (queries %v)", writes, tt.wantWrites, fake.Queries())
+			}
+			if !tt.wantWrites {
+				return
+			}
+			var view AdView
+			if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if view.Title != body.Title || view.Status != models.AdStatusPending {
+				t.Fatalf("updated ad = %+v, want new title and pending status", view)
+			}
+		})
+	}
+}
+
+func TestUpdateAdNotFound(t *testing.T) {
+	useFakeDB(t, adsResponder())
+	if w := putAd(t, 2002, adSubmission{}); w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
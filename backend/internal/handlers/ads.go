@@ -15,7 +15,18 @@ import (
 	"gorm.io/gorm"
 )
 
-const maxPremiumActiveAds = 3
+
+const (
+	defaultAdsPageSize = 20
+	maxAdsPageSize     = 100
+)
+
+// Варианты сортировки ленты объявлений
+const (
+	adsSortPremium  = "premium"  // премиум сверху, затем недавно обновлённые (по умолчанию)
+	adsSortNewest   = "newest"   // недавно обновлённые, премиум закреплены на первой странице
+	adsSortExpiring = "expiring" // скоро истекающие, премиум закреплены на первой странице
+)
 
 func GetAds(c *gin.Context) {
 	start := time.Now()
@@ -24,34 +35,75 @@ func GetAds(c *gin.Context) {
 	category := strings.TrimSpace(c.Query("cat"))
 	mode := strings.TrimSpace(c.Query("mode"))
 	tag := strings.TrimSpace(c.Query("tag"))
+	searchQuery := truncate(strings.TrimSpace(c.Query("q")), maxSearchQueryLen)
 
-	log.Printf("GetAds: запрос - category=%s, mode=%s, tag=%s", category, mode, tag)
+	sortBy := strings.ToLower(strings.TrimSpace(c.Query("sort")))
+	switch {
+	case sortBy == "" && searchQuery != "":
+		sortBy = adsSortRelevance
+	case sortBy == "":
+		sortBy = adsSortPremium
+	case sortBy == adsSortRelevance && searchQuery == "":
+		metrics.APIRequestsTotal.WithLabelValues("ads", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "ads").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort=relevance requires q"})
+		return
+	case sortBy == adsSortPremium, sortBy == adsSortNewest, sortBy == adsSortExpiring, sortBy == adsSortRelevance:
+	default:
+		metrics.APIRequestsTotal.WithLabelValues("ads", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "ads").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: premium, newest, expiring, relevance"})
+		return
+	}
 
-	filteredQuery := db.DB.Where("status = ? AND expires_at > ?", models.AdStatusActive, now)
+	limit := defaultAdsPageSize
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			metrics.APIRequestsTotal.WithLabelValues("ads", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "ads").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if parsed > maxAdsPageSize {
+			parsed = maxAdsPageSize
+		}
+		limit = parsed
+	}
 
-	if category != "" {
-		filteredQuery = filteredQuery.Where("category = ?", category)
+	cursor, err := decodeAdsCursor(c.Query("cursor"), sortBy)
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("ads", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "ads").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
 	}
-	// Для категории "other" не применяем фильтр по mode, так как режим всегда "general"
-	if mode != "" && category != "other" {
-		filteredQuery = filteredQuery.Where("mode = ?", mode)
+
+	log.Printf("GetAds: запрос - category=%s, mode=%s, tag=%s, q=%q, sort=%s, limit=%d, cursor=%t", category, mode, tag, searchQuery, sortBy, limit, cursor != nil)
+
+	baseQuery := func() *gorm.DB {
+		query := db.DB.Model(&models.Ad{}).Where("ads.status = ? AND ads.expires_at > ?", models.AdStatusActive, now)
+		query = applyAdFilters(query, category, mode, tag)
+		if searchQuery != "" {
+			query = applyAdSearch(query, searchQuery)
+		}
+		return query
 	}
-	if tag != "" {
-		if strings.EqualFold(tag, "all") {
-			// ignore tag filter when "all"
-		} else {
-			filteredQuery = filteredQuery.Where("tag = ?", tag)
+	// selectQuery — baseQuery с колонками ранга и подсветки, если задан поиск.
+	// Без поиска колонки задаём явно: иначе GORM возьмёт их из adSearchRow, а search_rank и др. в ads нет.
+	selectQuery := func() *gorm.DB {
+		if searchQuery != "" {
+			return baseQuery().Select(adSearchColumns)
 		}
+		return baseQuery().Select("ads.*")
 	}
 
-	filteredQuery = filteredQuery.Order("is_premium DESC, updated_at DESC")
-
-	var filtered []models.Ad
-	if err := filteredQuery.Find(&filtered).Error; err != nil {
-		log.Printf("GetAds: ошибка БД при получении объявлений: %v", err)
+	var total int64
+	if err := baseQuery().Count(&total).Error; err != nil {
+		log.Printf("GetAds: ошибка БД при подсчёте объявлений: %v", err)
 		middleware.CaptureError(c, err, map[string]string{
 			"handler": "GetAds",
-			"query":   "filtered",
+			"query":   "count",
 		})
 		metrics.APIRequestsTotal.WithLabelValues("ads", "500").Inc()
 		metrics.ErrorsTotal.WithLabelValues("database", "ads").Inc()
@@ -59,28 +111,40 @@ func GetAds(c *gin.Context) {
 		return
 	}
 
-	// Премиум объявления должны фильтроваться по тем же параметрам, что и обычные
-	var premium []models.Ad
-	premiumQuery := db.DB.Where("status = ? AND expires_at > ? AND is_premium = ?", models.AdStatusActive, now, true)
-
-	if category != "" {
-		premiumQuery = premiumQuery.Where("category = ?", category)
-	}
-	// Для категории "other" не применяем фильтр по mode, так как режим всегда "general"
-	if mode != "" && category != "other" {
-		premiumQuery = premiumQuery.Where("mode = ?", mode)
-	}
-	if tag != "" && !strings.EqualFold(tag, "all") {
-		premiumQuery = premiumQuery.Where("tag = ?", tag)
+	pageQuery := selectQuery()
+	switch sortBy {
+	case adsSortPremium:
+		if cursor != nil {
+			pageQuery = pageQuery.Where("(ads.is_premium, ads.updated_at, ads.id) < (?, ?, ?)", cursor.Premium, cursor.UpdatedAt, cursor.ID)
+		}
+		pageQuery = pageQuery.Order("ads.is_premium DESC, ads.updated_at DESC, ads.id DESC")
+	case adsSortNewest:
+		// Премиум объявления закрепляются отдельно и не дублируются в ленте
+		pageQuery = pageQuery.Where("ads.is_premium = ?", false)
+		if cursor != nil {
+			pageQuery = pageQuery.Where("(ads.updated_at, ads.id) < (?, ?)", cursor.UpdatedAt, cursor.ID)
+		}
+		pageQuery = pageQuery.Order("ads.updated_at DESC, ads.id DESC")
+	case adsSortExpiring:
+		pageQuery = pageQuery.Where("ads.is_premium = ?", false)
+		if cursor != nil {
+			pageQuery = pageQuery.Where("(ads.expires_at, ads.id) > (?, ?)", cursor.ExpiresAt, cursor.ID)
+		}
+		pageQuery = pageQuery.Order("ads.expires_at ASC, ads.id ASC")
+	case adsSortRelevance:
+		pageQuery = pageQuery.Where("ads.is_premium = ?", false)
+		if cursor != nil {
+			pageQuery = pageQuery.Where("("+adSearchRank+", ads.id) < (?, ?)", cursor.Rank, cursor.ID)
+		}
+		pageQuery = pageQuery.Order("search_rank DESC, ads.id DESC")
 	}
 
-	premiumQuery = premiumQuery.Order("updated_at DESC")
-
-	if err := premiumQuery.Find(&premium).Error; err != nil {
-		log.Printf("GetAds: ошибка БД при получении премиум объявлений: %v", err)
+	var pageRows []adSearchRow
+	if err := pageQuery.Limit(limit + 1).Find(&pageRows).Error; err != nil {
+		log.Printf("GetAds: ошибка БД при получении объявлений: %v", err)
 		middleware.CaptureError(c, err, map[string]string{
 			"handler": "GetAds",
-			"query":   "premium",
+			"query":   "filtered",
 		})
 		metrics.APIRequestsTotal.WithLabelValues("ads", "500").Inc()
 		metrics.ErrorsTotal.WithLabelValues("database", "ads").Inc()
@@ -88,15 +152,55 @@ func GetAds(c *gin.Context) {
 		return
 	}
 
-	combined := mergeAds(premium, filtered)
+	nextCursor := ""
+	if len(pageRows) > limit {
+		pageRows = pageRows[:limit]
+		nextCursor = encodeAdsCursor(sortBy, pageRows[len(pageRows)-1])
+	}
+	page, highlights := splitSearchRows(pageRows)
 
-	log.Printf("GetAds: category=%s, mode=%s, tag=%s, найдено filtered=%d, premium=%d, combined=%d",
-		category, mode, tag, len(filtered), len(premium), len(combined))
+	// Премиум объявления закрепляются только на первой странице и фильтруются по тем же параметрам, что и обычные
+	var premium []models.Ad
+	if cursor == nil && sortBy != adsSortPremium {
+		var premiumRows []adSearchRow
+		premiumQuery := selectQuery().Where("ads.is_premium = ?", true).Order("ads.updated_at DESC, ads.id DESC")
+		if err := premiumQuery.Find(&premiumRows).Error; err != nil {
+			log.Printf("GetAds: ошибка БД при получении премиум объявлений: %v", err)
+			middleware.CaptureError(c, err, map[string]string{
+				"handler": "GetAds",
+				"query":   "premium",
+			})
+			metrics.APIRequestsTotal.WithLabelValues("ads", "500").Inc()
+			metrics.ErrorsTotal.WithLabelValues("database", "ads").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось загрузить объявления. Попробуйте позже."})
+			return
+		}
+		var premiumHighlights map[uint]adSearchRow
+		premium, premiumHighlights = splitSearchRows(premiumRows)
+		for id, row := range premiumHighlights {
+			highlights[id] = row
+		}
+	}
+
+	combined := mergeAds(premium, page)
+
+	log.Printf("GetAds: category=%s, mode=%s, tag=%s, sort=%s, найдено page=%d, premium=%d, total=%d",
+		category, mode, tag, sortBy, len(page), len(premium), total)
 
-	response := make([]AdView, 0, len(combined))
+	response := AdsPage{
+		Items:      make([]AdView, 0, len(combined)),
+		NextCursor: nextCursor,
+		Total:      total,
+	}
 	for _, ad := range combined {
-		response = append(response, buildAdView(ad))
+		view := buildAdView(ad)
+		if searchQuery != "" {
+			view.TitleHighlight = highlights[ad.ID].TitleHighlight
+			view.Snippet = highlights[ad.ID].Snippet
+		}
+		response.Items = append(response.Items, view)
 	}
+	attachReputations(response.Items, combined)
 
 	// Собираем метрики
 	duration := time.Since(start)
@@ -106,30 +210,40 @@ func GetAds(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// applyAdFilters добавляет к запросу фильтры Mini App по категории, режиму и тегу
+func applyAdFilters(query *gorm.DB, category, mode, tag string) *gorm.DB {
+	if category != "" {
+		query = query.Where("ads.category = ?", category)
+	}
+	// Для категории "other" не применяем фильтр по mode, так как режим всегда "general"
+	if mode != "" && category != "other" {
+		query = query.Where("ads.mode = ?", mode)
+	}
+	// Тег "all" означает отсутствие фильтра
+	if tag != "" && !strings.EqualFold(tag, "all") {
+		query = query.Where("ads.tag = ?", tag)
+	}
+	return query
+}
+
 func GetMyAds(c *gin.Context) {
 	start := time.Now()
-	userIDStr := c.Query("user_id")
-	log.Printf("GetMyAds: получен запрос с user_id=%s", userIDStr)
-	if userIDStr == "" {
-		log.Printf("GetMyAds: ошибка - user_id не указан")
-		metrics.APIRequestsTotal.WithLabelValues("myads", "400").Inc()
-		metrics.ErrorsTotal.WithLabelValues("validation", "myads").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id parameter is required"})
+	// Владельца проверяет middleware.RequireOwner: сам пользователь или, для менеджера, user_id из запроса
+	ownerID, ok := middleware.OwnerID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("myads", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
 		return
 	}
+	userIDStr := strconv.FormatInt(ownerID, 10)
+	log.Printf("GetMyAds: получен запрос для user_id=%s", userIDStr)
 
 	// Ищем объявления по ClientID (который менеджер вводит во время создания объявления)
 	// ClientID совпадает с user_id из Telegram
 	// Также ищем по UserID на случай если client_id не совпадает
 	var ads []models.Ad
 	queryStart := time.Now()
-	query := db.DB.Where("client_id = ?", userIDStr)
-
-	// Также пробуем найти по user_id (на случай если client_id не установлен правильно)
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err == nil {
-		query = query.Or("user_id = ?", userIDInt)
-	}
+	query := db.DB.Where("client_id = ?", userIDStr).Or("user_id = ?", ownerID)
 
 	if err := query.
 		Order(gorm.Expr("CASE WHEN status = ? THEN 0 WHEN status = ? THEN 1 ELSE 2 END, updated_at DESC", models.AdStatusActive, models.AdStatusExpired)).
@@ -159,14 +273,3 @@ func GetMyAds(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
-
-func activePremiumCount(excludeID *uint) (int64, error) {
-	query := db.DB.Model(&models.Ad{}).Where("status = ? AND is_premium = ? AND expires_at > ?", models.AdStatusActive, true, time.Now())
-	if excludeID != nil {
-		query = query.Where("id <> ?", *excludeID)
-	}
-
-	var count int64
-	err := query.Count(&count).Error
-	return count, err
-}
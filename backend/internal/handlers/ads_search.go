@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"youtube-market/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	adsSortRelevance  = "relevance" // по релевантности полнотекстового поиска (по умолчанию при заданном q)
+	maxSearchQueryLen = 256
+)
+
+// Запрос строится по русской и английской конфигурациям одновременно,
+// чтобы находились и «озвучка», и «voice-over»
+const adSearchJoin = "CROSS JOIN (SELECT websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) AS tsq) AS search"
+
+const adSearchRank = "ts_rank(ads.search_vector, search.tsq)::float8"
+
+// Фрагменты подсвечиваются по HTML-экранированному тексту, поэтому их можно вставлять в разметку как есть
+const adSearchColumns = "ads.*, " + adSearchRank + " AS search_rank, " +
+	"ts_headline('russian', replace(replace(replace(ads.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), search.tsq, " +
+	"'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight, " +
+	"ts_headline('russian', replace(replace(replace(ads.\"desc\", '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), search.tsq, " +
+	"'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2') AS snippet"
+
+// adSearchRow — объявление вместе с результатами полнотекстового поиска
+type adSearchRow struct {
+	models.Ad
+	SearchRank     float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// applyAdSearch ограничивает выборку объявлениями, подходящими под поисковый запрос q
+func applyAdSearch(query *gorm.DB, q string) *gorm.DB {
+	return query.
+		Joins(adSearchJoin, q, q).
+		Where("ads.search_vector @@ search.tsq")
+}
+
+func splitSearchRows(rows []adSearchRow) ([]models.Ad, map[uint]adSearchRow) {
+	ads := make([]models.Ad, 0, len(rows))
+	byID := make(map[uint]adSearchRow, len(rows))
+	for _, row := range rows {
+		ads = append(ads, row.Ad)
+		byID[row.ID] = row
+	}
+	return ads, byID
+}
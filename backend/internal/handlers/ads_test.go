@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyAds не должен доверять user_id из запроса: без владельца от RequireOwner — 401
+func TestGetMyAdsIgnoresQueryUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1001))
+		c.Next()
+	})
+	r.GET("/api/myads", GetMyAds)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/myads?user_id=2002", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// managerRows отвечает на загрузку роли менеджера из loadManager
+func managerRows(managers map[int64]string, args []driver.Value) fakeResult {
+	result := fakeResult{columns: []string{"telegram_id", "role"}}
+	if len(args) > 0 {
+		if id, ok := args[0].(int64); ok && managers[id] != "" {
+			result.rows = append(result.rows, []driver.Value{id, managers[id]})
+		}
+	}
+	return result
+}
+
+// myAdsResponder знает менеджеров и отдаёт объявления, подходящие под client_id/user_id из запроса GetMyAds
+func myAdsResponder(managers map[int64]string, ads ...models.Ad) fakeResponder {
+	return func(query string, args []driver.Value) (fakeResult, error) {
+		switch {
+		case strings.Contains(query, `FROM "managers"`):
+			return managerRows(managers, args), nil
+		case strings.Contains(query, `FROM "ads"`) && len(args) >= 2:
+			var matched []models.Ad
+			for _, ad := range ads {
+				if ad.ClientID == args[0] || ad.UserID == args[1] {
+					matched = append(matched, ad)
+				}
+			}
+			return adRows(matched...), nil
+		default:
+			return fakeResult{}, nil
+		}
+	}
+}
+
+// /api/myads в сборке как в main.go: RequireOwner с CanManageAds перед GetMyAds
+func TestMyAdsRoute(t *testing.T) {
+	const (
+		userID    int64 = 1001
+		otherID   int64 = 2002
+		editorID  int64 = 3003
+		viewerID  int64 = 4004
+		expiresIn       = 72 * time.Hour
+	)
+	managers := map[int64]string{
+		editorID: models.ManagerRoleAdEditor,
+		viewerID: models.ManagerRoleViewer,
+	}
+	ads := []models.Ad{
+		{ID: 1, UserID: userID, ClientID: "1001", Title: "Своё", Status: models.AdStatusActive, ExpiresAt: time.Now().Add(expiresIn)},
+		{ID: 2, ClientID: "1001", Title: "Своё от менеджера", Status: models.AdStatusPending, ExpiresAt: time.Now().Add(expiresIn)},
+		{ID: 3, UserID: otherID, ClientID: "2002", Title: "Чужое", Status: models.AdStatusInactive, ExpiresAt: time.Now().Add(expiresIn)},
+		{ID: 4, UserID: editorID, ClientID: "3003", Title: "Редактора", Status: models.AdStatusActive, ExpiresAt: time.Now().Add(expiresIn)},
+	}
+
+	tests := []struct {
+		name       string
+		caller     int64
+		query      string
+		wantStatus int
+		wantIDs    []uint
+	}{
+		{"own ads without param", userID, "", http.StatusOK, []uint{1, 2}},
+		{"own user_id", userID, "?user_id=1001", http.StatusOK, []uint{1, 2}},
+		{"non-manager reads another user", userID, "?user_id=2002", http.StatusForbidden, nil},
+		{"viewer reads another user", viewerID, "?user_id=2002", http.StatusForbidden, nil},
+		{"ad editor reads another user", editorID, "?user_id=2002", http.StatusOK, []uint{3}},
+		{"ad editor without param", editorID, "", http.StatusOK, []uint{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeDB(t, myAdsResponder(managers, ads...))
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("user_id", tt.caller)
+				c.Next()
+			})
+			r.GET("/api/myads", middleware.RequireOwner("user_id", CanManageAds), GetMyAds)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/myads"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				for _, query := range fake.Queries() {
+					if strings.Contains(query, `FROM "ads"`) {
+						t.Fatalf("ads queried despite status %d: %s", w.Code, query)
+					}
+				}
+				return
+			}
+
+			var views []AdView
+			if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var ids []uint
+			for _, view := range views {
+				ids = append(ids, view.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Fatalf("ad ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+// feedResponder ведёт себя как Postgres для ленты: колонок поиска в ads нет
+func feedResponder(ads ...models.Ad) fakeResponder {
+	return func(query string, args []driver.Value) (fakeResult, error) {
+		if !strings.HasPrefix(query, "SELECT") {
+			return fakeResult{}, nil
+		}
+		for _, column := range []string{"search_rank", "title_highlight", "snippet"} {
+			if strings.Contains(query, column) {
+				return fakeResult{}, errors.New(`ERROR: column "` + column + `" does not exist (SQLSTATE 42703)`)
+			}
+		}
+		switch {
+		case strings.Contains(query, "count(*)") && strings.Contains(query, `FROM "ads"`):
+			return fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{int64(len(ads))}}}, nil
+		case strings.Contains(query, `FROM "ads"`):
+			return adRows(ads...), nil
+		default:
+			return fakeResult{}, nil
+		}
+	}
+}
+
+// Лента Mini App без q: раньше GORM запрашивал колонки поиска из adSearchRow и отвечал 500
+func TestGetAdsFeedWithoutQuery(t *testing.T) {
+	ads := []models.Ad{
+		{ID: 2, UserID: 1001, Title: "Канал о путешествиях", Category: "buysell", Mode: "sell", Tag: "channel",
+			Status: models.AdStatusActive, ExpiresAt: time.Now().Add(24 * time.Hour)},
+		{ID: 1, UserID: 1002, Title: "Озвучу ролик", Category: "services", Mode: "offer", Tag: "voice",
+			Status: models.AdStatusActive, ExpiresAt: time.Now().Add(48 * time.Hour)},
+	}
+
+	for _, query := range []string{"", "?sort=newest", "?sort=expiring", "?cat=buysell&tag=all&limit=5"} {
+		t.Run("GET /api/ads"+query, func(t *testing.T) {
+			fake := useFakeDB(t, feedResponder(ads...))
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/api/ads", GetAds)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ads"+query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d (body %s, queries %v)", w.Code, http.StatusOK, w.Body.String(), fake.Queries())
+			}
+			var page AdsPage
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if page.Total != int64(len(ads)) || len(page.Items) == 0 {
+				t.Fatalf("page = %+v, want %d ads", page, len(ads))
+			}
+			if page.Items[0].Title == "" || page.Items[0].TitleHighlight != "" {
+				t.Fatalf("unexpected first item: %+v", page.Items[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"youtube-market/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Действия, которые попадают в журнал аудита
+const (
+	auditAdCreate         = "ad_create"
+	auditAdUpdate         = "ad_update"
+	auditAdRenew          = "ad_renew"
+	auditAdPublish        = "ad_publish"
+	auditAdStatus         = "ad_status"
+	auditAdApprove        = "ad_approve"
+	auditAdReject         = "ad_reject"
+	auditAdRestore        = "ad_restore"
+	auditAdPayment        = "ad_payment"
+	auditAdRefund         = "ad_refund"
+	auditAdPremiumQueue   = "ad_premium_queue"
+	auditAdPremiumUnqueue = "ad_premium_unqueue"
+	auditAdPremiumPromote = "ad_premium_promote"
+	auditBlacklistAdd     = "blacklist_add"
+	auditBlacklistRemove  = "blacklist_remove"
+	auditManagerRole      = "manager_role"
+	auditManagerRemove    = "manager_remove"
+	auditReviewPublish    = "review_publish"
+	auditReviewHide       = "review_hide"
+	auditDealCreate       = "deal_create"
+	auditDealStatus       = "deal_status"
+)
+
+const (
+	auditEntityAd      = "ad"
+	auditEntityUser    = "user"
+	auditEntityManager = "manager"
+	auditEntityReview  = "review"
+	auditEntityDeal    = "deal"
+)
+
+var auditActionLabels = map[string]string{
+	auditAdCreate:         "создал объявление",
+	auditAdUpdate:         "изменил объявление",
+	auditAdRenew:          "продлил объявление",
+	auditAdPublish:        "выложил объявление",
+	auditAdStatus:         "сменил статус объявления",
+	auditAdApprove:        "одобрил объявление",
+	auditAdReject:         "отклонил объявление",
+	auditAdRestore:        "восстановил версию объявления",
+	auditAdPayment:        "оплатил в Stars",
+	auditAdRefund:         "вернул оплату по",
+	auditAdPremiumQueue:   "поставил в очередь на премиум",
+	auditAdPremiumUnqueue: "убрал из очереди на премиум",
+	auditAdPremiumPromote: "поднял из очереди в премиум",
+	auditBlacklistAdd:     "внёс в чёрный список",
+	auditBlacklistRemove:  "убрал из чёрного списка",
+	auditManagerRole:      "назначил роль",
+	auditManagerRemove:    "удалил менеджера",
+	auditReviewPublish:    "опубликовал отзыв",
+	auditReviewHide:       "скрыл отзыв",
+	auditDealCreate:       "открыл сделку",
+	auditDealStatus:       "сменил статус сделки",
+}
+
+// auditIgnoredFields меняются при любом сохранении и только засоряют журнал
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// auditChange — значение поля до и после действия
+type auditChange struct {
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// auditFields превращает структуру или карту в набор полей по их JSON-именам
+func auditFields(value interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// auditDiff сравнивает состояния до и после и оставляет только изменившиеся поля
+func auditDiff(before, after interface{}) (map[string]auditChange, error) {
+	from, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]auditChange)
+	for key, value := range to {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = auditChange{From: from[key], To: value}
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok && !auditIgnoredFields[key] {
+			changes[key] = auditChange{From: value}
+		}
+	}
+	return changes, nil
+}
+
+// recordAudit записывает действие менеджера actorID над сущностью в журнал.
+// Вызывается в той же транзакции, что и само изменение.
+func recordAudit(tx *gorm.DB, actorID int64, action, entityType string, entityID interface{}, before, after interface{}) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit diff: %w", err)
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("audit diff: %w", err)
+	}
+
+	return tx.Create(&models.AuditEvent{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Changes:    string(raw),
+	}).Error
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -14,8 +15,9 @@ import (
 
 func CheckScammer(c *gin.Context) {
 	start := time.Now()
-	username := strings.TrimSpace(strings.TrimPrefix(c.Param("username"), "@"))
-	if username == "" {
+	// Принимаем как @username, так и числовой Telegram ID
+	target := parseBlacklistTarget(strings.TrimSpace(c.Param("username")))
+	if target.empty() {
 		metrics.APIRequestsTotal.WithLabelValues("scammer", "400").Inc()
 		metrics.ErrorsTotal.WithLabelValues("validation", "scammer").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"error": "username parameter is required"})
@@ -23,8 +25,7 @@ func CheckScammer(c *gin.Context) {
 	}
 
 	queryStart := time.Now()
-	var user models.User
-	err := db.DB.Where("LOWER(username) = LOWER(?) AND is_scammer = true", username).First(&user).Error
+	user, err := findBlacklistUser(db.DB, target, true)
 	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
 
 	if err == gorm.ErrRecordNotFound {
@@ -44,11 +45,30 @@ func CheckScammer(c *gin.Context) {
 		return
 	}
 
+	queryStart = time.Now()
+	entries, err := activeBlacklistEntries([]int64{user.ID})
+	var aliases map[int64][]string
+	if err == nil {
+		aliases, err = userAliases([]int64{user.ID})
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("scammer", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "scammer").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check user"})
+		return
+	}
+	entry, ok := entries[user.ID]
+
 	metrics.APIRequestsTotal.WithLabelValues("scammer", "200").Inc()
 	metrics.APIReponseTime.WithLabelValues("scammer").Observe(time.Since(start).Seconds())
 	c.JSON(http.StatusOK, gin.H{
-		"safe": false,
-		"msg":  "Осторожно! Мошенник",
+		"safe":               false,
+		"msg":                "Осторожно! Мошенник",
+		"username":           user.Username,
+		"previous_usernames": aliases[user.ID],
+		"reason":             entry.Reason,
+		"listed_at":          blacklistListedAt(*user, entry, ok),
 	})
 }
 
@@ -66,14 +86,34 @@ func GetBlacklist(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load blacklist"})
 		return
 	}
+
+	userIDs := make([]int64, 0, len(scammers))
+	for _, user := range scammers {
+		userIDs = append(userIDs, user.ID)
+	}
+	entries, err := activeBlacklistEntries(userIDs)
+	var aliases map[int64][]string
+	if err == nil {
+		aliases, err = userAliases(userIDs)
+	}
 	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("blacklist", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "blacklist").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load blacklist"})
+		return
+	}
 
 	response := make([]gin.H, 0, len(scammers))
 	for _, user := range scammers {
+		entry, ok := entries[user.ID]
 		response = append(response, gin.H{
-			"username":   user.Username,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"username":           user.Username,
+			"previous_usernames": aliases[user.ID],
+			"reason":             entry.Reason,
+			"listed_at":          blacklistListedAt(user, entry, ok),
+			"created_at":         user.CreatedAt,
+			"updated_at":         user.UpdatedAt,
 		})
 	}
 
@@ -83,3 +123,115 @@ func GetBlacklist(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+var errAlreadyBlacklisted = errors.New("пользователь уже в чёрном списке")
+
+// addToBlacklist вносит пользователя в чёрный список и создаёт запись с причиной и доказательствами
+func addToBlacklist(target blacklistTarget, managerID int64, reason string, evidence []string) (*models.BlacklistEntry, error) {
+	var entry *models.BlacklistEntry
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = addToBlacklistTx(tx, target, managerID, reason, evidence)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// addToBlacklistTx — addToBlacklist внутри уже открытой транзакции tx
+func addToBlacklistTx(tx *gorm.DB, target blacklistTarget, managerID int64, reason string, evidence []string) (*models.BlacklistEntry, error) {
+	user, err := resolveBlacklistUser(tx, target)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsScammer {
+		return nil, errAlreadyBlacklisted
+	}
+	if err := tx.Model(user).Update("is_scammer", true).Error; err != nil {
+		return nil, err
+	}
+
+	entry := models.BlacklistEntry{
+		UserID:   user.ID,
+		Username: user.Username,
+		Reason:   reason,
+		AddedBy:  managerID,
+	}
+	if user.TelegramID != nil {
+		entry.TelegramID = *user.TelegramID
+	}
+	for _, fileID := range evidence {
+		entry.Evidence = append(entry.Evidence, models.BlacklistEvidence{FileID: fileID})
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	if err := recordAudit(tx, managerID, auditBlacklistAdd, auditEntityUser, user.ID,
+		map[string]interface{}{"is_scammer": false},
+		map[string]interface{}{"is_scammer": true, "reason": reason, "evidence": len(evidence)}); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// removeFromBlacklist снимает пользователя с чёрного списка, закрывая действующие записи.
+// Возвращает false, если пользователь в списке не значился.
+func removeFromBlacklist(target blacklistTarget, managerID int64) (bool, error) {
+	removed := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := findBlacklistUser(tx, target, true)
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(user).Update("is_scammer", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.BlacklistEntry{}).
+			Where("user_id = ? AND removed_at IS NULL", user.ID).
+			Updates(map[string]interface{}{
+				"removed_at": time.Now(),
+				"removed_by": managerID,
+			}).Error; err != nil {
+			return err
+		}
+		removed = true
+		return recordAudit(tx, managerID, auditBlacklistRemove, auditEntityUser, user.ID,
+			map[string]interface{}{"is_scammer": true}, map[string]interface{}{"is_scammer": false})
+	})
+	return removed, err
+}
+
+// activeBlacklistEntries возвращает действующие записи чёрного списка по ID пользователей
+// (для каждого пользователя — последняя запись)
+func activeBlacklistEntries(userIDs []int64) (map[int64]models.BlacklistEntry, error) {
+	result := make(map[int64]models.BlacklistEntry, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	var entries []models.BlacklistEntry
+	if err := db.DB.
+		Where("user_id IN ? AND removed_at IS NULL", userIDs).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		result[entry.UserID] = entry
+	}
+	return result, nil
+}
+
+// blacklistListedAt — дата внесения в список. Для записей, сделанных до появления
+// истории, используем дату последнего изменения пользователя.
+func blacklistListedAt(user models.User, entry models.BlacklistEntry, ok bool) time.Time {
+	if ok {
+		return entry.CreatedAt
+	}
+	return user.UpdatedAt
+}
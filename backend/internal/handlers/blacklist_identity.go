@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// identityCheckInterval — как часто повторно сверять пользователя Mini App с чёрным списком,
+// если его username не менялся
+const identityCheckInterval = 10 * time.Minute
+
+var errUsernameTaken = errors.New("username уже закреплён за другим пользователем")
+
+// blacklistTarget — пользователь, указанный менеджером или клиентом: по username и/или Telegram ID
+type blacklistTarget struct {
+	Username   string
+	TelegramID int64
+}
+
+// parseBlacklistTarget разбирает @username или числовой Telegram ID
+// (username в Telegram не может состоять только из цифр)
+func parseBlacklistTarget(value string) blacklistTarget {
+	value = normalizeUsername(value)
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil && id > 0 {
+		return blacklistTarget{TelegramID: id}
+	}
+	return blacklistTarget{Username: value}
+}
+
+func (t blacklistTarget) empty() bool {
+	return t.Username == "" && t.TelegramID == 0
+}
+
+func (t blacklistTarget) String() string {
+	switch {
+	case t.Username != "" && t.TelegramID != 0:
+		return fmt.Sprintf("@%s, ID %d", t.Username, t.TelegramID)
+	case t.TelegramID != 0:
+		return fmt.Sprintf("ID %d", t.TelegramID)
+	default:
+		return "@" + t.Username
+	}
+}
+
+// findBlacklistUser ищет пользователя по Telegram ID, текущему username или прежним username.
+// При onlyScammers учитываются только пользователи из чёрного списка.
+func findBlacklistUser(tx *gorm.DB, target blacklistTarget, onlyScammers bool) (*models.User, error) {
+	scope := func() *gorm.DB {
+		query := tx.Model(&models.User{})
+		if onlyScammers {
+			query = query.Where("is_scammer = ?", true)
+		}
+		return query
+	}
+
+	var user models.User
+	if target.TelegramID != 0 {
+		err := scope().Where("telegram_id = ?", target.TelegramID).First(&user).Error
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) || target.Username == "" {
+			return &user, err
+		}
+	}
+
+	err := scope().Where("LOWER(username) = LOWER(?)", target.Username).First(&user).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &user, err
+	}
+
+	err = scope().
+		Where("id IN (?)", tx.Model(&models.UserAlias{}).Select("user_id").Where("LOWER(username) = LOWER(?)", target.Username)).
+		Order("updated_at DESC").
+		First(&user).Error
+	return &user, err
+}
+
+// renameUser сохраняет прежний username пользователя как псевдоним и записывает новый
+func renameUser(tx *gorm.DB, user *models.User, username string) error {
+	if username == "" || strings.EqualFold(user.Username, username) {
+		return nil
+	}
+
+	var taken int64
+	if err := tx.Model(&models.User{}).
+		Where("LOWER(username) = LOWER(?) AND id <> ?", username, user.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errUsernameTaken
+	}
+
+	if user.Username != "" {
+		alias := models.UserAlias{UserID: user.ID, Username: user.Username}
+		if err := tx.Where("user_id = ? AND LOWER(username) = LOWER(?)", user.ID, user.Username).
+			FirstOrCreate(&alias).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(user).Update("username", username).Error; err != nil {
+		return err
+	}
+	user.Username = username
+	return nil
+}
+
+// resolveBlacklistUser находит пользователя для внесения в чёрный список или создаёт его.
+// Известный Telegram ID привязывается к записи, смена username сохраняется в псевдонимах.
+func resolveBlacklistUser(tx *gorm.DB, target blacklistTarget) (*models.User, error) {
+	user, err := findBlacklistUser(tx, target, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if target.Username == "" {
+			return nil, fmt.Errorf("для нового пользователя нужен username")
+		}
+		user = &models.User{Username: target.Username}
+		if target.TelegramID != 0 {
+			id := target.TelegramID
+			user.TelegramID = &id
+		}
+		return user, tx.Create(user).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if target.TelegramID != 0 {
+		if user.TelegramID == nil {
+			if err := tx.Model(user).Update("telegram_id", target.TelegramID).Error; err != nil {
+				return nil, err
+			}
+			id := target.TelegramID
+			user.TelegramID = &id
+		} else if *user.TelegramID != target.TelegramID {
+			// Username теперь принадлежит другому аккаунту — не смешиваем их историю
+			return nil, errUsernameTaken
+		}
+		if err := renameUser(tx, user, target.Username); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// userAliases возвращает прежние username пользователей по их ID
+func userAliases(userIDs []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	var aliases []models.UserAlias
+	if err := db.DB.Where("user_id IN ?", userIDs).Order("created_at ASC").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		result[alias.UserID] = append(result[alias.UserID], alias.Username)
+	}
+	return result, nil
+}
+
+var identityChecks = struct {
+	sync.Mutex
+	seen map[int64]identitySeen
+}{seen: make(map[int64]identitySeen)}
+
+type identitySeen struct {
+	username  string
+	checkedAt time.Time
+}
+
+// BlacklistIdentityMiddleware сверяет пользователя Mini App с чёрным списком по Telegram ID.
+// Если пользователь из списка зашёл под новым username, менеджеры получают уведомление.
+// Должен стоять после TMAuthMiddleware.
+func BlacklistIdentityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := middleware.AuthUserID(c); ok {
+			username := middleware.AuthUsername(c)
+			if shouldCheckIdentity(userID, username) {
+				go checkBlacklistIdentity(userID, username)
+			}
+		}
+		c.Next()
+	}
+}
+
+func shouldCheckIdentity(userID int64, username string) bool {
+	now := time.Now()
+	identityChecks.Lock()
+	defer identityChecks.Unlock()
+
+	last, ok := identityChecks.seen[userID]
+	if ok && last.username == username && now.Sub(last.checkedAt) < identityCheckInterval {
+		return false
+	}
+	identityChecks.seen[userID] = identitySeen{username: username, checkedAt: now}
+
+	// Не даём карте расти бесконечно
+	if len(identityChecks.seen) > 10000 {
+		for id, seen := range identityChecks.seen {
+			if now.Sub(seen.checkedAt) >= identityCheckInterval {
+				delete(identityChecks.seen, id)
+			}
+		}
+	}
+	return true
+}
+
+func checkBlacklistIdentity(userID int64, username string) {
+	if db.DB == nil {
+		return
+	}
+
+	var user models.User
+	err := db.DB.Where("telegram_id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if username == "" {
+			return
+		}
+		// Пользователь внесён только по username — запоминаем его Telegram ID
+		result := db.DB.Model(&models.User{}).
+			Where("LOWER(username) = LOWER(?) AND is_scammer = ? AND telegram_id IS NULL", username, true).
+			Update("telegram_id", userID)
+		if result.Error != nil {
+			log.Printf("Не удалось привязать Telegram ID %d к @%s: %v", userID, username, result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("Telegram ID %d привязан к @%s из чёрного списка", userID, username)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка проверки пользователя %d по чёрному списку: %v", userID, err)
+		return
+	}
+
+	if username == "" || strings.EqualFold(user.Username, username) {
+		return
+	}
+
+	previous := user.Username
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return renameUser(tx, &user, username)
+	}); err != nil {
+		log.Printf("Не удалось обновить username пользователя %d (@%s → @%s): %v", userID, previous, username, err)
+	}
+
+	if !user.IsScammer {
+		return
+	}
+
+	log.Printf("Пользователь из чёрного списка %d зашёл под новым username: @%s → @%s", userID, previous, username)
+
+	text := fmt.Sprintf("⚠️ Пользователь из чёрного списка сменил username\n\nID: %d\nБыл: @%s\nСейчас: @%s", userID, previous, username)
+	if entries, err := activeBlacklistEntries([]int64{user.ID}); err == nil {
+		if entry, ok := entries[user.ID]; ok && entry.Reason != "" {
+			text += fmt.Sprintf("\nПричина: %s", entry.Reason)
+		}
+	}
+	notifyManagers(permManageBlacklist, text)
+}
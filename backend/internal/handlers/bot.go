@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,6 +14,8 @@ import (
 	"youtube-market/internal/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -20,6 +23,11 @@ const (
 	commandNewAd           = "/newad"
 	commandAdDetails       = "/ad"
 	commandCancel          = "/cancel"
+	commandAudit           = "/audit"
+	commandRefund          = "/refund"
+	commandPayments        = "/payments"
+	commandSlots           = "/slots"
+	commandReviews         = "/reviews"
 	sessionTimeoutDuration = 30 * time.Minute
 )
 
@@ -142,8 +150,14 @@ const (
 	stageAwaitBlacklistAction
 	stageAwaitBlacklistAdd
 	stageAwaitBlacklistRemove
+	stageAwaitBlacklistReason
+	stageAwaitBlacklistEvidence
+	stageAwaitBlacklistHistory
 	stageAwaitFindAdID
 	stageAwaitSelectAd
+	stageAwaitRejectReason
+	stageAwaitManagerID
+	stageAwaitDealTerms
 )
 
 type adOperation int
@@ -162,6 +176,17 @@ type adSession struct {
 	LastActivity  time.Time
 	ChatID        int64
 	BotMessageIDs []int // ID сообщений бота для удаления
+	// Позиция в очереди модерации (ModerationTotal == 0 — объявление открыто не из очереди)
+	ModerationPage  int
+	ModerationTotal int
+	// Черновик записи чёрного списка при добавлении
+	Blacklist blacklistDraft
+	// Черновик галереи; PhotosEdited — галерея открывалась и при сохранении заменяет фото объявления
+	Photos        []models.AdPhoto
+	PhotosEdited  bool
+	PhotoPromptID int
+	// PremiumQueued — премиум-мест нет, после сохранения объявление встаёт в очередь
+	PremiumQueued bool
 }
 
 var (
@@ -219,41 +244,66 @@ func RunManagerBot() {
 		return
 	}
 
+	// MANAGER_ID задаёт только владельцев, остальные менеджеры и роли хранятся в базе
 	managerIDsStr := os.Getenv("MANAGER_ID")
-	managerIDs, err := parseManagerIDs(managerIDsStr)
+	ownerIDs, err := parseManagerIDs(managerIDsStr)
 	if err != nil {
-		log.Printf("MANAGER_ID not set or invalid (%v), manager bot disabled", err)
+		var owners int64
+		if countErr := db.DB.Model(&models.Manager{}).Where("role = ?", models.ManagerRoleOwner).Count(&owners).Error; countErr != nil || owners == 0 {
+			log.Printf("MANAGER_ID not set or invalid (%v) and no owners in database, manager bot disabled", err)
+			return
+		}
+		ownerIDs = nil
+	}
+	if err := bootstrapOwners(ownerIDs); err != nil {
+		log.Printf("failed to bootstrap owners from MANAGER_ID: %v, manager bot disabled", err)
 		return
 	}
 
-	bot, err := tgbotapi.NewBotAPI(botToken)
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(botToken, telegramAPIURL()+"/bot%s/%s")
 	if err != nil {
 		log.Fatal("bot init failed:", err)
 	}
 
 	setBotToken(botToken)
+	setManagerBot(bot, ownerIDs)
+	initSessionStore()
 	startAdSchedulers(bot)
 
-	log.Printf("Manager bot started for user IDs: %v", managerIDs)
+	log.Printf("Manager bot started, owners from MANAGER_ID: %v", ownerIDs)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	updates := receiveUpdates(bot)
 
 	for update := range updates {
 		switch {
+		case update.PreCheckoutQuery != nil:
+			handlePreCheckoutQuery(bot, update.PreCheckoutQuery)
+		case update.Message != nil && update.Message.SuccessfulPayment != nil:
+			// Оплату присылают пользователи, а не менеджеры, поэтому разбираем её до проверки роли
+			handleSuccessfulPayment(bot, update.Message)
 		case update.Message != nil:
-			handleManagerMessage(bot, managerIDs, update.Message)
+			handleManagerMessage(bot, update.Message)
+			persistSession(update.Message.Chat.ID)
 		case update.CallbackQuery != nil:
-			handleCallbackQuery(bot, managerIDs, update.CallbackQuery)
+			handleCallbackQuery(bot, update.CallbackQuery)
+			if update.CallbackQuery.Message != nil {
+				persistSession(update.CallbackQuery.Message.Chat.ID)
+			}
 		}
 	}
 }
 
-func handleManagerMessage(bot *tgbotapi.BotAPI, managerIDs []int64, msg *tgbotapi.Message) {
-	if msg.From == nil || !isManager(msg.From.ID, managerIDs) {
+func handleManagerMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	if msg.From == nil {
+		return
+	}
+	manager, ok := loadManager(msg.From.ID)
+	if !ok {
+		// Остальные пользователи работают с ботом в клиентском режиме
+		handleClientMessage(bot, msg)
 		return
 	}
+	touchManagerUsername(manager, msg.From.UserName)
 
 	// Удаляем сообщение менеджера с задержкой (не сразу)
 	go func() {
@@ -261,6 +311,20 @@ func handleManagerMessage(bot *tgbotapi.BotAPI, managerIDs []int64, msg *tgbotap
 		deleteMessage(bot, msg.Chat.ID, msg.MessageID)
 	}()
 
+	// Роль могли понизить посреди диалога: такой ввод не принимаем и начинаем с чистой сессии
+	if session := getSession(msg.Chat.ID); session != nil && !roleAllows(manager.Role, stagePermission(session.Stage)) {
+		clearSession(msg.Chat.ID)
+		sendText(bot, msg.Chat.ID, "⛔ Недостаточно прав для продолжения этого действия.")
+	}
+
+	// Пересланные фото на шаге с фото добавляются в галерею, а на шаге доказательств — к записи
+	// чёрного списка: пересланный скриншот не считается указанием пользователя
+	if session := getSession(msg.Chat.ID); session != nil && len(msg.Photo) > 0 &&
+		(session.Stage == stageAwaitPhoto || session.Stage == stageAwaitBlacklistEvidence) {
+		handleSessionInput(bot, msg, session)
+		return
+	}
+
 	// Обработка пересланных сообщений от пользователей (для получения ID) - проверяем ПЕРВЫМ
 	if msg.ForwardFrom != nil {
 		handleForwardedMessage(bot, msg)
@@ -279,10 +343,48 @@ func handleManagerMessage(bot *tgbotapi.BotAPI, managerIDs []int64, msg *tgbotap
 	}
 
 	if isCommand(text, commandNewAd) {
+		if !roleAllows(manager.Role, permManageAds) {
+			sendText(bot, msg.Chat.ID, "⛔ Недостаточно прав для создания объявлений.")
+			return
+		}
 		startCreateSession(bot, msg.Chat.ID)
 		return
 	}
 
+	if isCommand(text, commandAudit) {
+		handleAuditCommand(bot, msg.Chat.ID, text)
+		return
+	}
+
+	// Менеджер может быть и владельцем объявлений
+	if isClientCommand(text) {
+		handleClientMessage(bot, msg)
+		return
+	}
+
+	if isCommand(text, commandReviews) {
+		handleReviewsCommand(bot, msg.Chat.ID, text)
+		return
+	}
+
+	if isCommand(text, commandSlots) {
+		handleSlotsCommand(bot, msg.Chat.ID, msg.From.ID, text, roleAllows(manager.Role, permManagePremiumSlots))
+		return
+	}
+
+	if isCommand(text, commandRefund) || isCommand(text, commandPayments) {
+		if !roleAllows(manager.Role, permManagePayments) {
+			sendText(bot, msg.Chat.ID, "⛔ Недостаточно прав для работы с платежами.")
+			return
+		}
+		if isCommand(text, commandRefund) {
+			handleRefundCommand(bot, msg.Chat.ID, msg.From.ID, text)
+		} else {
+			handlePaymentsCommand(bot, msg.Chat.ID)
+		}
+		return
+	}
+
 	// Обработка текстового ввода в активной сессии
 	if session := getSession(msg.Chat.ID); session != nil && session.Stage != stageNone {
 		handleSessionInput(bot, msg, session)
@@ -372,6 +474,22 @@ func handleForwardedMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 		return
 	}
 
+	// Чёрный список: пересланное сообщение указывает пользователя по Telegram ID
+	switch session.Stage {
+	case stageAwaitBlacklistAdd:
+		handleBlacklistForwarded(bot, msg.Chat.ID, userID, username, session)
+		return
+	case stageAwaitBlacklistRemove:
+		removeBlacklistTarget(bot, msg.Chat.ID, msg.From.ID, blacklistTarget{TelegramID: userID})
+		return
+	case stageAwaitBlacklistHistory:
+		showBlacklistHistory(bot, msg.Chat.ID, blacklistTarget{TelegramID: userID})
+		return
+	case stageAwaitManagerID:
+		showManagerRoles(bot, msg.Chat.ID, userID)
+		return
+	}
+
 	// Если мы ищем объявление и получили пересланное сообщение
 	if session.Stage == stageAwaitFindAdID {
 		// Ищем все объявления по ClientID
@@ -407,8 +525,21 @@ func handleForwardedMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
 	sendText(bot, msg.Chat.ID, fmt.Sprintf("✅ Получен ID пользователя: %d\n\nДля создания объявления используйте /newad", userID))
 }
 
-func handleCallbackQuery(bot *tgbotapi.BotAPI, managerIDs []int64, callback *tgbotapi.CallbackQuery) {
-	if callback.From == nil || !isManager(callback.From.ID, managerIDs) {
+func handleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	if callback.From == nil {
+		return
+	}
+	// Кнопки из напоминаний владельцам объявлений и управления подписками доступны всем
+	if strings.HasPrefix(callback.Data, "client_renew_") || strings.HasPrefix(callback.Data, "sub_delete_") {
+		handleClientCallback(bot, callback)
+		return
+	}
+	manager, ok := loadManager(callback.From.ID)
+	if !ok {
+		return
+	}
+	if !roleAllows(manager.Role, callbackPermission(callback.Data)) {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "⛔ Недостаточно прав для этого действия"))
 		return
 	}
 
@@ -436,6 +567,16 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, managerIDs []int64, callback *tgb
 	switch {
 	case data == "menu_main":
 		showMainMenu(bot, chatID)
+	case data == "menu_managers":
+		showManagers(bot, chatID)
+	case data == "manager_add":
+		startManagerAdd(bot, chatID)
+	case strings.HasPrefix(data, "manager_view_"):
+		handleManagerView(bot, chatID, data)
+	case strings.HasPrefix(data, "manager_role_"):
+		handleManagerRoleCallback(bot, chatID, callback.From.ID, data)
+	case strings.HasPrefix(data, "manager_remove_"):
+		handleManagerRemove(bot, chatID, callback.From.ID, data)
 	case data == "menu_new_ad":
 		startCreateSession(bot, chatID)
 	case data == "menu_find_ad":
@@ -448,6 +589,44 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, managerIDs []int64, callback *tgb
 		startBlacklistAdd(bot, chatID)
 	case data == "blacklist_remove":
 		startBlacklistRemove(bot, chatID)
+	case data == "blacklist_history":
+		startBlacklistHistory(bot, chatID)
+	case data == "blacklist_evidence_done":
+		handleBlacklistEvidenceDone(bot, chatID, callback.From.ID)
+	case data == "menu_moderation":
+		showModerationQueue(bot, chatID, 0)
+	case strings.HasPrefix(data, "moderation_page_"):
+		handleModerationPage(bot, chatID, data)
+	case data == "moderation_approve":
+		handleModerationApprove(bot, chatID, callback.From.ID)
+	case data == "moderation_reject":
+		handleModerationReject(bot, chatID)
+	case data == "menu_client_requests":
+		showClientRequestQueue(bot, chatID)
+	case strings.HasPrefix(data, "client_req_done_"):
+		handleClientRequestDone(bot, chatID, callback.From.ID, data)
+	case strings.HasPrefix(data, "client_req_reject_"):
+		handleClientRequestReject(bot, chatID, callback.From.ID, data)
+	case data == "menu_reviews":
+		showReviewQueue(bot, chatID)
+	case strings.HasPrefix(data, "review_"):
+		handleReviewModeration(bot, chatID, callback.From.ID, data)
+	case data == "menu_deals":
+		showDeals(bot, chatID)
+	case strings.HasPrefix(data, "deal_view_"):
+		handleDealView(bot, chatID, data)
+	case strings.HasPrefix(data, "deal_new_"):
+		startDealCreate(bot, chatID, data)
+	case strings.HasPrefix(data, "deal_to_"):
+		handleDealTransition(bot, chatID, callback.From.ID, data)
+	case data == "menu_reports":
+		showReportQueue(bot, chatID, 0)
+	case strings.HasPrefix(data, "report_page_"):
+		handleReportPage(bot, chatID, data)
+	case strings.HasPrefix(data, "report_accept_"):
+		handleReportAccept(bot, chatID, callback.From.ID, data)
+	case strings.HasPrefix(data, "report_dismiss_"):
+		handleReportDismiss(bot, chatID, callback.From.ID, data)
 	case strings.HasPrefix(data, "ad_action_"):
 		handleAdActionCallback(bot, chatID, data)
 	case data == "category_edit":
@@ -468,34 +647,44 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, managerIDs []int64, callback *tgb
 		handleTagCallback(bot, chatID, data)
 	case strings.HasPrefix(data, "duration_"):
 		handleDurationCallback(bot, chatID, data)
+	case strings.HasPrefix(data, "premium_waitlist_"):
+		handlePremiumWaitlist(bot, chatID, callback.From.ID, data)
+	case strings.HasPrefix(data, "premium_unqueue_"):
+		handlePremiumUnqueue(bot, chatID, callback.From.ID, data)
 	case strings.HasPrefix(data, "premium_"):
 		handlePremiumCallback(bot, chatID, data)
 	case data == "save_from_settings":
-		handleSaveFromSettings(bot, chatID)
+		handleSaveFromSettings(bot, chatID, callback.From.ID)
 	case data == "confirm_yes":
-		handleConfirmYes(bot, chatID)
+		handleConfirmYes(bot, chatID, callback.From.ID)
 	case data == "confirm_no":
 		handleConfirmNo(bot, chatID)
 	case data == "back":
 		handleBack(bot, chatID)
-	case data == "skip_photo":
-		handleSkipPhoto(bot, chatID)
+	case data == "skip_photo", data == "photos_done":
+		handlePhotosDone(bot, chatID)
+	case data == "photos_clear":
+		handlePhotosClear(bot, chatID)
 	case data == "skip_user_id":
 		handleSkipUserID(bot, chatID)
 	case data == "skip_username":
 		handleSkipUsername(bot, chatID)
 	case strings.HasPrefix(data, "renew_duration_"):
-		handleRenewDurationCallback(bot, chatID, data)
+		handleRenewDurationCallback(bot, chatID, callback.From.ID, data)
 	case data == "ad_edit":
 		handleAdEdit(bot, chatID)
 	case data == "ad_renew":
 		handleAdRenew(bot, chatID)
 	case data == "ad_remove":
-		handleAdRemove(bot, chatID)
+		handleAdRemove(bot, chatID, callback.From.ID)
 	case data == "ad_publish":
-		handleAdPublish(bot, chatID)
+		handleAdPublish(bot, chatID, callback.From.ID)
 	case strings.HasPrefix(data, "select_ad_"):
 		handleSelectAd(bot, chatID, data)
+	case strings.HasPrefix(data, "ad_history_"):
+		handleAdHistory(bot, chatID, data)
+	case strings.HasPrefix(data, "ad_restore_"):
+		handleAdRestore(bot, chatID, callback.From.ID, data)
 	case data == "edit_after_preview":
 		handleEditAfterPreview(bot, chatID)
 	}
@@ -504,17 +693,68 @@ func handleCallbackQuery(bot *tgbotapi.BotAPI, managerIDs []int64, callback *tgb
 func showMainMenu(bot *tgbotapi.BotAPI, chatID int64) {
 	clearSession(chatID)
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
+	// Бот работает в личных чатах, поэтому chatID совпадает с Telegram ID менеджера
+	role := ""
+	if manager, ok := loadManager(chatID); ok {
+		role = manager.Role
+	}
+
+	moderationLabel := "🕓 Очередь модерации"
+	if pending, err := pendingAdsCount(); err == nil && pending > 0 {
+		moderationLabel = fmt.Sprintf("🕓 Очередь модерации (%d)", pending)
+	}
+	reportsLabel := "📣 Жалобы"
+	if pending, err := pendingReportsCount(); err == nil && pending > 0 {
+		reportsLabel = fmt.Sprintf("📣 Жалобы (%d)", pending)
+	}
+	reviewsLabel := "⭐ Отзывы"
+	if pending, err := pendingReviewsCount(); err == nil && pending > 0 {
+		reviewsLabel = fmt.Sprintf("⭐ Отзывы (%d)", pending)
+	}
+	dealsLabel := "🤝 Сделки"
+	if open, err := openDealsCount(); err == nil && open > 0 {
+		dealsLabel = fmt.Sprintf("🤝 Сделки (%d)", open)
+	}
+	requestsLabel := "📥 Заявки клиентов"
+	if pending, err := pendingClientRequestsCount(); err == nil && pending > 0 {
+		requestsLabel = fmt.Sprintf("📥 Заявки клиентов (%d)", pending)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if roleAllows(role, permManageAds) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("➕ Создать объявление", "menu_new_ad"),
-		),
+		))
+	}
+	rows = append(rows,
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔍 Найти объявление", "menu_find_ad"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(moderationLabel, "menu_moderation"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(requestsLabel, "menu_client_requests"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(reportsLabel, "menu_reports"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(reviewsLabel, "menu_reviews"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(dealsLabel, "menu_deals"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🚫 Чёрный список", "menu_blacklist"),
 		),
 	)
+	if roleAllows(role, permManageManagers) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👥 Менеджеры", "menu_managers"),
+		))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	msg := tgbotapi.NewMessage(chatID, "📋 *Меню менеджера*\n\nВыберите действие:")
 	msg.ParseMode = "Markdown"
@@ -535,6 +775,7 @@ func showBlacklistMenu(bot *tgbotapi.BotAPI, chatID int64) {
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📋 Просмотр", "blacklist_view"),
+			tgbotapi.NewInlineKeyboardButtonData("📜 История", "blacklist_history"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", "blacklist_add"),
@@ -589,6 +830,16 @@ func showBlacklist(bot *tgbotapi.BotAPI, chatID int64) {
 		return
 	}
 
+	userIDs := make([]int64, 0, len(scammers))
+	for _, user := range scammers {
+		userIDs = append(userIDs, user.ID)
+	}
+	entries, err := activeBlacklistEntries(userIDs)
+	if err != nil {
+		log.Printf("Ошибка загрузки записей чёрного списка: %v", err)
+		entries = map[int64]models.BlacklistEntry{}
+	}
+
 	var text strings.Builder
 	text.WriteString("📋 *Чёрный список:*\n\n")
 	for i, user := range scammers {
@@ -596,7 +847,11 @@ func showBlacklist(bot *tgbotapi.BotAPI, chatID int64) {
 			text.WriteString(fmt.Sprintf("\n... и ещё %d пользователей", len(scammers)-50))
 			break
 		}
-		text.WriteString(fmt.Sprintf("• @%s\n", user.Username))
+		text.WriteString(fmt.Sprintf("• @%s", escapeMarkdown(user.Username)))
+		if entry, ok := entries[user.ID]; ok && entry.Reason != "" {
+			text.WriteString(fmt.Sprintf(" — %s", escapeMarkdown(truncate(entry.Reason, 60))))
+		}
+		text.WriteString("\n")
 	}
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -634,7 +889,7 @@ func startBlacklistAdd(bot *tgbotapi.BotAPI, chatID int64) {
 		),
 	)
 
-	msg := tgbotapi.NewMessage(chatID, "➕ *Добавить в чёрный список*\n\nОтправьте username (например: @username)")
+	msg := tgbotapi.NewMessage(chatID, "➕ *Добавить в чёрный список*\n\nОтправьте username (например: @username), Telegram ID или перешлите сообщение пользователя")
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
 
@@ -658,7 +913,7 @@ func startBlacklistRemove(bot *tgbotapi.BotAPI, chatID int64) {
 		),
 	)
 
-	msg := tgbotapi.NewMessage(chatID, "➖ *Удалить из чёрного списка*\n\nОтправьте username (например: @username)")
+	msg := tgbotapi.NewMessage(chatID, "➖ *Удалить из чёрного списка*\n\nОтправьте username (например: @username), Telegram ID или перешлите сообщение пользователя")
 	msg.ParseMode = "Markdown"
 	msg.ReplyMarkup = keyboard
 
@@ -706,7 +961,6 @@ func startFindAdSession(bot *tgbotapi.BotAPI, chatID int64) {
 func startCreateSession(bot *tgbotapi.BotAPI, chatID int64) {
 	session := &adSession{
 		Operation:     opCreate,
-		Stage:         stageAwaitPhoto,
 		LastActivity:  time.Now(),
 		ChatID:        chatID,
 		BotMessageIDs: []int{},
@@ -714,25 +968,7 @@ func startCreateSession(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 	setSession(chatID, session)
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⏭ Пропустить", "skip_photo"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("◀️ Отмена", "menu_main"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, "📸 *Шаг 1: Фото*\n\nОтправьте фото объявления или пропустите этот шаг.")
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
-
-	sentMsg, err := bot.Send(msg)
-	if err == nil {
-		addBotMessage(chatID, sentMsg.MessageID)
-		// Удаляем предыдущие сообщения после отправки нового
-		go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
-	}
+	startPhotoStage(bot, chatID, session)
 }
 
 func handleAdActionCallback(bot *tgbotapi.BotAPI, chatID int64, data string) {
@@ -746,25 +982,7 @@ func handleAdEdit(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 
 	session.Operation = opEdit
-	session.Stage = stageAwaitPhoto
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⏭ Пропустить", "skip_photo"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", fmt.Sprintf("ad_action_%d", session.Ad.ID)),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, "📸 *Шаг 1: Фото*\n\nОтправьте новое фото или пропустите этот шаг.")
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
-
-	sentMsg, err := bot.Send(msg)
-	if err == nil {
-		addBotMessage(chatID, sentMsg.MessageID)
-	}
+	startPhotoStage(bot, chatID, session)
 }
 
 func handleAdRenew(bot *tgbotapi.BotAPI, chatID int64) {
@@ -800,18 +1018,20 @@ func handleAdRenew(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 }
 
-func handleAdRemove(bot *tgbotapi.BotAPI, chatID int64) {
+func handleAdRemove(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
 	session := getSession(chatID)
 	if session == nil {
 		return
 	}
 
-	if err := setAdStatus(session.Ad.ID, models.AdStatusInactive); err != nil {
+	if err := setAdStatus(session.Ad.ID, models.AdStatusInactive, managerID); err != nil {
 		sendText(bot, chatID, "❌ Не удалось обновить объявление.")
 		return
 	}
 
 	notifyUser(bot, session.Ad.UserID, fmt.Sprintf("Ваше объявление «%s» снято с биржи. Свяжитесь с %s для повторной публикации.", session.Ad.Title, managerHelpLink))
+	// Снятое объявление освобождает премиум-место и теряет место в очереди
+	promotePremiumWaitlist(bot)
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -843,9 +1063,21 @@ func handleSessionInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, session *ad
 	case stageAwaitFindAdID:
 		handleFindAdIDInput(bot, msg.Chat.ID, text, session)
 	case stageAwaitBlacklistAdd:
-		handleBlacklistAddInput(bot, msg.Chat.ID, text)
+		handleBlacklistAddInput(bot, msg.Chat.ID, text, session)
+	case stageAwaitBlacklistReason:
+		handleBlacklistReasonInput(bot, msg.Chat.ID, text, session)
+	case stageAwaitBlacklistEvidence:
+		handleBlacklistEvidenceInput(bot, msg, session)
 	case stageAwaitBlacklistRemove:
-		handleBlacklistRemoveInput(bot, msg.Chat.ID, text)
+		handleBlacklistRemoveInput(bot, msg.Chat.ID, msg.From.ID, text)
+	case stageAwaitBlacklistHistory:
+		handleBlacklistHistoryInput(bot, msg.Chat.ID, text)
+	case stageAwaitRejectReason:
+		handleRejectReasonInput(bot, msg.Chat.ID, msg.From.ID, text, session)
+	case stageAwaitManagerID:
+		handleManagerIDInput(bot, msg.Chat.ID, text)
+	case stageAwaitDealTerms:
+		handleDealTermsInput(bot, msg.Chat.ID, msg.From.ID, text, session)
 	case stageAwaitPhoto:
 		handlePhotoStage(bot, msg, session)
 	case stageAwaitTitle:
@@ -890,108 +1122,6 @@ func handleSessionInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, session *ad
 	}
 }
 
-func handleBlacklistAddInput(bot *tgbotapi.BotAPI, chatID int64, text string) {
-	username := normalizeUsername(text)
-	if username == "" {
-		sendText(bot, chatID, "❌ Введите username в формате @username")
-		return
-	}
-
-	db.DB.FirstOrCreate(&models.User{}, models.User{Username: username}).Updates(map[string]interface{}{"IsScammer": true})
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_blacklist"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Добавлен в чёрный список: @%s", username))
-	msg.ReplyMarkup = keyboard
-
-	sentMsg, err := bot.Send(msg)
-	if err == nil {
-		addBotMessage(chatID, sentMsg.MessageID)
-		// Удаляем предыдущие сообщения после отправки результата
-		session := getSession(chatID)
-		if session != nil {
-			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
-		}
-	}
-
-	clearSession(chatID)
-}
-
-func handleBlacklistRemoveInput(bot *tgbotapi.BotAPI, chatID int64, text string) {
-	username := normalizeUsername(text)
-	if username == "" {
-		sendText(bot, chatID, "❌ Введите username в формате @username")
-		return
-	}
-
-	result := db.DB.Where("username = ?", username).Updates(&models.User{IsScammer: false})
-	if result.Error != nil {
-		sendText(bot, chatID, "❌ Ошибка во время обновления чёрного списка.")
-		return
-	}
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_blacklist"),
-		),
-	)
-
-	var msgText string
-	if result.RowsAffected == 0 {
-		msgText = fmt.Sprintf("❌ Пользователь @%s не найден в чёрном списке", username)
-	} else {
-		msgText = fmt.Sprintf("✅ Удалён из чёрного списка: @%s", username)
-	}
-
-	msg := tgbotapi.NewMessage(chatID, msgText)
-	msg.ReplyMarkup = keyboard
-
-	sentMsg, err := bot.Send(msg)
-	if err == nil {
-		addBotMessage(chatID, sentMsg.MessageID)
-		// Удаляем предыдущие сообщения после отправки результата
-		session := getSession(chatID)
-		if session != nil {
-			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
-		}
-	}
-
-	clearSession(chatID)
-}
-
-func handleSkipPhoto(bot *tgbotapi.BotAPI, chatID int64) {
-	session := getSession(chatID)
-	if session == nil {
-		return
-	}
-
-	session.Ad.PhotoID = ""
-	session.Ad.PhotoPath = ""
-	session.Stage = stageAwaitTitle
-
-	showTitlePrompt(bot, chatID, session)
-}
-
-func handlePhotoStage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, session *adSession) {
-	if len(msg.Photo) > 0 {
-		photo := msg.Photo[len(msg.Photo)-1]
-		file, err := bot.GetFile(tgbotapi.FileConfig{FileID: photo.FileID})
-		if err != nil {
-			sendText(bot, msg.Chat.ID, "❌ Не удалось сохранить фото, попробуйте ещё раз.")
-			return
-		}
-		session.Ad.PhotoID = photo.FileID
-		session.Ad.PhotoPath = file.FilePath
-	}
-
-	session.Stage = stageAwaitTitle
-	showTitlePrompt(bot, msg.Chat.ID, session)
-}
-
 func showTitlePrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -1342,15 +1472,19 @@ func showPremiumPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
 	if session.Operation != opCreate {
 		exclude = &session.Ad.ID
 	}
-	count, err := activePremiumCount(exclude)
+	free, limit, err := premiumSlotFree(db.DB, session.Ad.Category, exclude)
 	if err != nil {
 		sendText(bot, chatID, "❌ Не удалось проверить лимит премиум-объявлений.")
 		return
 	}
 
+	yes := tgbotapi.NewInlineKeyboardButtonData("✅ Да", "premium_yes")
+	if !free && !session.Ad.IsPremium {
+		yes = tgbotapi.NewInlineKeyboardButtonData("⏳ В очередь", "premium_queue")
+	}
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Да", "premium_yes"),
+			yes,
 			tgbotapi.NewInlineKeyboardButtonData("❌ Нет", "premium_no"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
@@ -1359,8 +1493,8 @@ func showPremiumPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
 	)
 
 	text := "⭐ *Шаг 9: Премиум размещение*\n\nПремиум объявление будет отображаться вверху списка."
-	if count >= 3 {
-		text += "\n\n⚠️ Лимит премиум-объявлений (3) исчерпан. Сначала снимите одно из текущих."
+	if !free && !session.Ad.IsPremium {
+		text += "\n\n" + premiumLimitText(session.Ad.Category, limit) + " Объявление можно поставить в очередь: оно станет премиум, когда место освободится."
 	}
 
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -1384,18 +1518,23 @@ func handlePremiumCallback(bot *tgbotapi.BotAPI, chatID int64, data string) {
 		if session.Operation != opCreate {
 			exclude = &session.Ad.ID
 		}
-		count, err := activePremiumCount(exclude)
+		free, limit, err := premiumSlotFree(db.DB, session.Ad.Category, exclude)
 		if err != nil {
 			sendText(bot, chatID, "❌ Не удалось проверить лимит премиум-объявлений.")
 			return
 		}
-		if count >= 3 {
-			sendText(bot, chatID, "⚠️ Лимит премиум-объявлений (3) исчерпан. Сначала снимите одно из текущих.")
+		if !free && !session.Ad.IsPremium {
+			sendText(bot, chatID, premiumLimitText(session.Ad.Category, limit)+" Выберите «⏳ В очередь» или снимите одно из текущих.")
 			return
 		}
 		session.Ad.IsPremium = true
+		session.PremiumQueued = false
+	} else if data == "premium_queue" {
+		session.Ad.IsPremium = false
+		session.PremiumQueued = true
 	} else {
 		session.Ad.IsPremium = false
+		session.PremiumQueued = false
 	}
 
 	// После выбора премиума показываем предпросмотр (если ClientID уже установлен) или продолжаем
@@ -1533,16 +1672,7 @@ func handleFindAdResults(bot *tgbotapi.BotAPI, chatID int64, ads []models.Ad, se
 			textBuilder.WriteString(fmt.Sprintf("\n... и ещё %d объявлений", len(ads)-10))
 			break
 		}
-		var status string
-		switch ad.Status {
-		case models.AdStatusExpired:
-			status = "🔴 Истекло"
-		case models.AdStatusInactive:
-			status = "⚫ Снято"
-		default:
-			status = "🟢 Активно"
-		}
-		textBuilder.WriteString(fmt.Sprintf("%d. %s - %s\n", ad.ID, ad.Title, status))
+		textBuilder.WriteString(fmt.Sprintf("%d. %s - %s\n", ad.ID, ad.Title, adStatusLabel(ad.Status)))
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
 				fmt.Sprintf("#%d: %s", ad.ID, truncate(ad.Title, 30)),
@@ -1604,6 +1734,7 @@ func handleSelectAd(bot *tgbotapi.BotAPI, chatID int64, data string) {
 		session.Ad = ad
 		session.Stage = stageAwaitAction
 		session.LastActivity = time.Now()
+		session.PremiumQueued = false
 	}
 
 	showAdDetailsWithActions(bot, chatID, ad)
@@ -1614,8 +1745,20 @@ func showAdDetailsWithActions(bot *tgbotapi.BotAPI, chatID int64, ad models.Ad)
 
 	var rows [][]tgbotapi.InlineKeyboardButton
 
-	// Если объявление не выложено (статус inactive или неактивно)
-	if ad.Status == models.AdStatusInactive || ad.ExpiresAt.Before(time.Now()) {
+	session := getSession(chatID)
+	inQueue := session != nil && session.ModerationTotal > 0 && ad.Status == models.AdStatusPending
+	if inQueue {
+		text = fmt.Sprintf("🕓 *Очередь модерации: %d из %d*\n\n", session.ModerationPage+1, session.ModerationTotal) + text
+	}
+
+	if ad.Status == models.AdStatusPending {
+		// Объявление подано пользователем и ждёт решения менеджера
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Одобрить", "moderation_approve"),
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Отклонить", "moderation_reject"),
+		))
+	} else if ad.Status == models.AdStatusInactive || ad.ExpiresAt.Before(time.Now()) {
+		// Если объявление не выложено (статус inactive или неактивно)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("✅ Выложить", "ad_publish"),
 		))
@@ -1623,6 +1766,7 @@ func showAdDetailsWithActions(bot *tgbotapi.BotAPI, chatID int64, ad models.Ad)
 
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", "ad_edit"),
+		tgbotapi.NewInlineKeyboardButtonData("📜 История", fmt.Sprintf("ad_history_%d", ad.ID)),
 	))
 
 	if ad.Status == models.AdStatusActive {
@@ -1632,6 +1776,41 @@ func showAdDetailsWithActions(bot *tgbotapi.BotAPI, chatID int64, ad models.Ad)
 		))
 	}
 
+	if premiumBookingEligible(ad) {
+		queue, queued, err := adPremiumQueueStatus(ad)
+		if err != nil {
+			log.Printf("Ошибка загрузки очереди на премиум для объявления %d: %v", ad.ID, err)
+		} else if queued {
+			text += "\n\n" + formatPremiumQueueStatus(queue)
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✖️ Убрать из очереди на премиум", fmt.Sprintf("premium_unqueue_%d", ad.ID)),
+			))
+		} else {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("⏳ В очередь на премиум", fmt.Sprintf("premium_waitlist_%d", ad.ID)),
+			))
+		}
+	}
+
+	if ad.UserID != 0 && ad.Status != models.AdStatusPending {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🤝 Сделка через гаранта", fmt.Sprintf("deal_new_%d", ad.ID)),
+		))
+	}
+
+	if inQueue {
+		var nav []tgbotapi.InlineKeyboardButton
+		if session.ModerationPage > 0 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("⬅️", fmt.Sprintf("moderation_page_%d", session.ModerationPage-1)))
+		}
+		if session.ModerationPage < session.ModerationTotal-1 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("➡️", fmt.Sprintf("moderation_page_%d", session.ModerationPage+1)))
+		}
+		if len(nav) > 0 {
+			rows = append(rows, nav)
+		}
+	}
+
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_main"),
 	))
@@ -1646,19 +1825,19 @@ func showAdDetailsWithActions(bot *tgbotapi.BotAPI, chatID int64, ad models.Ad)
 	if err == nil {
 		addBotMessage(chatID, sentMsg.MessageID)
 		// Удаляем предыдущие сообщения после отправки деталей объявления
-		session := getSession(chatID)
 		if session != nil {
 			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
 		}
 	}
 }
 
-func handleAdPublish(bot *tgbotapi.BotAPI, chatID int64) {
+func handleAdPublish(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
 	session := getSession(chatID)
 	if session == nil {
 		return
 	}
 
+	before := session.Ad
 	// Активируем объявление
 	session.Ad.Status = models.AdStatusActive
 	session.Ad.PreExpiryNotified = false
@@ -1667,7 +1846,16 @@ func handleAdPublish(bot *tgbotapi.BotAPI, chatID int64) {
 		session.Ad.ExpiresAt = time.Now().Add(7 * 24 * time.Hour)
 	}
 
-	if err := db.DB.Save(&session.Ad).Error; err != nil {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&session.Ad).Error; err != nil {
+			return err
+		}
+		if err := saveAdRevision(tx, session.Ad, managerID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditAdPublish, auditEntityAd, session.Ad.ID, before, session.Ad)
+	}); err != nil {
+		log.Printf("Ошибка публикации объявления %d: %v", session.Ad.ID, err)
 		sendText(bot, chatID, "❌ Не удалось выложить объявление.")
 		return
 	}
@@ -1769,6 +1957,8 @@ func showAllSettingsPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSessio
 	premiumLabel := "нет"
 	if session.Ad.IsPremium {
 		premiumLabel = "да"
+	} else if session.PremiumQueued {
+		premiumLabel = "в очереди"
 	}
 	text.WriteString(fmt.Sprintf("⭐ Премиум: %s\n", premiumLabel))
 
@@ -1826,7 +2016,7 @@ func showAllSettingsPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSessio
 }
 
 // handleSaveFromSettings сохраняет объявление из экрана настроек
-func handleSaveFromSettings(bot *tgbotapi.BotAPI, chatID int64) {
+func handleSaveFromSettings(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
 	session := getSession(chatID)
 	if session == nil {
 		return
@@ -1846,7 +2036,7 @@ func handleSaveFromSettings(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 
 	// Сохраняем объявление
-	if err := persistAd(bot, session); err != nil {
+	if err := persistAd(bot, session, managerID); err != nil {
 		sendText(bot, chatID, "❌ Не удалось сохранить объявление: "+err.Error())
 		return
 	}
@@ -1863,6 +2053,8 @@ func handleSaveFromSettings(bot *tgbotapi.BotAPI, chatID int64) {
 	var text string
 	if session.Operation == opCreate {
 		text = fmt.Sprintf("✅ Объявление #%d опубликовано.", session.Ad.ID)
+	} else if session.Ad.Status == models.AdStatusPending {
+		text = fmt.Sprintf("✅ Объявление #%d обновлено и по-прежнему ждёт модерации.", session.Ad.ID)
 	} else {
 		text = fmt.Sprintf("✅ Объявление #%d обновлено.", session.Ad.ID)
 	}
@@ -1876,7 +2068,7 @@ func handleSaveFromSettings(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 }
 
-func handleConfirmYes(bot *tgbotapi.BotAPI, chatID int64) {
+func handleConfirmYes(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
 	session := getSession(chatID)
 	if session == nil {
 		return
@@ -1889,7 +2081,7 @@ func handleConfirmYes(bot *tgbotapi.BotAPI, chatID int64) {
 		}
 	}
 
-	if err := persistAd(bot, session); err != nil {
+	if err := persistAd(bot, session, managerID); err != nil {
 		sendText(bot, chatID, "❌ Не удалось сохранить объявление: "+err.Error())
 		return
 	}
@@ -1906,6 +2098,8 @@ func handleConfirmYes(bot *tgbotapi.BotAPI, chatID int64) {
 	var text string
 	if session.Operation == opCreate {
 		text = fmt.Sprintf("✅ Объявление #%d опубликовано.", session.Ad.ID)
+	} else if session.Ad.Status == models.AdStatusPending {
+		text = fmt.Sprintf("✅ Объявление #%d обновлено и по-прежнему ждёт модерации.", session.Ad.ID)
 	} else {
 		text = fmt.Sprintf("✅ Объявление #%d обновлено.", session.Ad.ID)
 	}
@@ -1928,7 +2122,7 @@ func handleConfirmNo(bot *tgbotapi.BotAPI, chatID int64) {
 	showMainMenu(bot, chatID)
 }
 
-func handleRenewDurationCallback(bot *tgbotapi.BotAPI, chatID int64, data string) {
+func handleRenewDurationCallback(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
 	session := getSession(chatID)
 	if session == nil {
 		return
@@ -1941,10 +2135,20 @@ func handleRenewDurationCallback(bot *tgbotapi.BotAPI, chatID int64, data string
 		return
 	}
 
+	before := session.Ad
 	session.Ad.Status = models.AdStatusActive
 	session.Ad.PreExpiryNotified = false
 	session.Ad.ExpiresAt = time.Now().Add(time.Duration(days) * 24 * time.Hour)
-	if err := db.DB.Save(&session.Ad).Error; err != nil {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&session.Ad).Error; err != nil {
+			return err
+		}
+		if err := saveAdRevision(tx, session.Ad, managerID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditAdRenew, auditEntityAd, session.Ad.ID, before, session.Ad)
+	}); err != nil {
+		log.Printf("Ошибка продления объявления %d: %v", session.Ad.ID, err)
 		sendText(bot, chatID, "❌ Не удалось обновить объявление.")
 		return
 	}
@@ -2047,26 +2251,6 @@ func handleBack(bot *tgbotapi.BotAPI, chatID int64) {
 	}
 }
 
-func showPhotoPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⏭ Пропустить", "skip_photo"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", getBackCallback(session)),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, "📸 *Шаг 1: Фото*\n\nОтправьте фото объявления или пропустите этот шаг.")
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
-
-	sentMsg, err := bot.Send(msg)
-	if err == nil {
-		addBotMessage(chatID, sentMsg.MessageID)
-	}
-}
-
 func getBackCallback(session *adSession) string {
 	// Определяем, куда вернуться при нажатии "Назад"
 	if session.Operation == opEdit && session.Stage == stageAwaitPhoto {
@@ -2075,35 +2259,54 @@ func getBackCallback(session *adSession) string {
 	return "back"
 }
 
-func persistAd(bot *tgbotapi.BotAPI, session *adSession) error {
-	// Валидация обязательных полей
-	if session.Ad.Title == "" {
+// validateAdFields проверяет обязательные поля объявления и согласованность
+// категории, режима и тега со справочниками бота
+func validateAdFields(ad *models.Ad) error {
+	if ad.Title == "" {
 		return fmt.Errorf("заголовок не может быть пустым")
 	}
-	if session.Ad.Desc == "" {
+	if ad.Desc == "" {
 		return fmt.Errorf("описание не может быть пустым")
 	}
-	// Username опционален - если не указан, оставляем пустым (не используем user_{id})
-	// Это нормально, так как для поиска в профиле используется client_id, а не username
-	if session.Ad.Username == "" {
-		log.Printf("Предупреждение: Username не указан, оставляем пустым. ClientID=%s", session.Ad.ClientID)
-	}
-	if session.Ad.Category == "" {
+	if ad.Category == "" {
 		return fmt.Errorf("категория не может быть пустой")
 	}
+	if _, ok := categoryLabels[ad.Category]; !ok {
+		return fmt.Errorf("неизвестная категория: %s", ad.Category)
+	}
 	// Для категории "other" режим автоматически устанавливается как "general"
 	// Также исправляем, если случайно сохранилось русское название "Объявление"
-	if session.Ad.Category == "other" {
-		if session.Ad.Mode == "" || session.Ad.Mode == "Объявление" {
-			session.Ad.Mode = "general"
+	if ad.Category == "other" {
+		if ad.Mode == "" || ad.Mode == "Объявление" {
+			ad.Mode = "general"
 		}
 	}
-	if session.Ad.Mode == "" {
+	if ad.Mode == "" {
 		return fmt.Errorf("режим не может быть пустым")
 	}
-	if session.Ad.Tag == "" {
+	if _, ok := modeLabels[ad.Category][ad.Mode]; !ok {
+		return fmt.Errorf("режим %s недоступен для категории %s", ad.Mode, ad.Category)
+	}
+	if ad.Tag == "" {
 		return fmt.Errorf("тег не может быть пустым")
 	}
+	if _, ok := tagLabels[ad.Category][ad.Tag]; !ok {
+		return fmt.Errorf("тег %s недоступен для категории %s", ad.Tag, ad.Category)
+	}
+	return nil
+}
+
+// persistAd сохраняет объявление из сессии от имени менеджера managerID
+func persistAd(bot *tgbotapi.BotAPI, session *adSession, managerID int64) error {
+	// Валидация обязательных полей
+	if err := validateAdFields(&session.Ad); err != nil {
+		return err
+	}
+	// Username опционален - если не указан, оставляем пустым (не используем user_{id})
+	// Это нормально, так как для поиска в профиле используется client_id, а не username
+	if session.Ad.Username == "" {
+		log.Printf("Предупреждение: Username не указан, оставляем пустым. ClientID=%s", session.Ad.ClientID)
+	}
 	if session.Ad.ClientID == "" {
 		return fmt.Errorf("ID клиента не может быть пустым")
 	}
@@ -2120,8 +2323,15 @@ func persistAd(bot *tgbotapi.BotAPI, session *adSession) error {
 		}
 	}
 
+	// Правка объявления из очереди модерации его не публикует: срок и статус выставит «✅ Одобрить»
+	keepPending := session.Operation == opEdit && session.Ad.Status == models.AdStatusPending
+
 	now := time.Now()
-	if session.DurationDays > 0 {
+	if keepPending {
+		if session.DurationDays > 0 {
+			session.Ad.RequestedDays = session.DurationDays
+		}
+	} else if session.DurationDays > 0 {
 		session.Ad.ExpiresAt = now.Add(time.Duration(session.DurationDays) * 24 * time.Hour)
 	} else if session.Ad.ExpiresAt.IsZero() && session.Operation == opCreate {
 		// Если срок не установлен, устанавливаем по умолчанию 7 дней
@@ -2129,42 +2339,131 @@ func persistAd(bot *tgbotapi.BotAPI, session *adSession) error {
 	}
 
 	session.Ad.PreExpiryNotified = false
-	session.Ad.Status = models.AdStatusActive
+	if !keepPending {
+		session.Ad.Status = models.AdStatusActive
+		session.Ad.RejectReason = ""
+	}
 
 	log.Printf("Сохранение объявления: Title=%s, Username=%s, ClientID=%s, UserID=%d, Category=%s, Mode=%s, Tag=%s",
 		session.Ad.Title, session.Ad.Username, session.Ad.ClientID, session.Ad.UserID, session.Ad.Category, session.Ad.Mode, session.Ad.Tag)
 
-	switch session.Operation {
-	case opCreate:
-		if err := db.DB.Create(&session.Ad).Error; err != nil {
-			log.Printf("Ошибка создания объявления: %v", err)
-			return err
+	if session.PhotosEdited {
+		session.Ad.PhotoCount = len(session.Photos)
+	}
+
+	// published — объявление только что появилось в ленте: создано или снова стало активным
+	published := session.Operation == opCreate
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var before *models.Ad
+		action := auditAdCreate
+		switch session.Operation {
+		case opCreate:
+			if session.Ad.IsPremium {
+				if err := checkPremiumSlot(tx, session.Ad); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&session.Ad).Error; err != nil {
+				log.Printf("Ошибка создания объявления: %v", err)
+				return err
+			}
+			log.Printf("Объявление создано: ID=%d, Username=%s, ClientID=%s, UserID=%d", session.Ad.ID, session.Ad.Username, session.Ad.ClientID, session.Ad.UserID)
+		case opEdit:
+			action = auditAdUpdate
+			var stored models.Ad
+			if err := tx.First(&stored, session.Ad.ID).Error; err != nil {
+				log.Printf("Ошибка загрузки объявления %d: %v", session.Ad.ID, err)
+				return err
+			}
+			before = &stored
+			if keepPending != (stored.Status == models.AdStatusPending) {
+				// Пока менеджер правил, объявление одобрили, отклонили или пользователь отправил его на модерацию
+				return fmt.Errorf("статус объявления изменился, пока вы его правили: откройте его заново")
+			}
+			published = !keepPending && stored.Status != models.AdStatusActive
+			if session.Ad.IsPremium && (!stored.IsPremium || stored.Category != session.Ad.Category) {
+				if err := checkPremiumSlot(tx, session.Ad); err != nil {
+					return err
+				}
+			}
+			if err := tx.Save(&session.Ad).Error; err != nil {
+				log.Printf("Ошибка обновления объявления: %v", err)
+				return err
+			}
+			log.Printf("Объявление обновлено: ID=%d, Username=%s, ClientID=%s, UserID=%d", session.Ad.ID, session.Ad.Username, session.Ad.ClientID, session.Ad.UserID)
+		}
+
+		if session.PhotosEdited {
+			if err := replaceAdPhotos(tx, session.Ad.ID, session.Photos); err != nil {
+				log.Printf("Ошибка сохранения фото объявления %d: %v", session.Ad.ID, err)
+				return err
+			}
 		}
-		log.Printf("Объявление создано: ID=%d, Username=%s, ClientID=%s, UserID=%d", session.Ad.ID, session.Ad.Username, session.Ad.ClientID, session.Ad.UserID)
-	case opEdit:
-		if err := db.DB.Save(&session.Ad).Error; err != nil {
-			log.Printf("Ошибка обновления объявления: %v", err)
+
+		if err := saveAdRevision(tx, session.Ad, managerID, nil); err != nil {
+			log.Printf("Ошибка сохранения версии объявления %d: %v", session.Ad.ID, err)
 			return err
 		}
-		log.Printf("Объявление обновлено: ID=%d, Username=%s, ClientID=%s, UserID=%d", session.Ad.ID, session.Ad.Username, session.Ad.ClientID, session.Ad.UserID)
+
+		return recordAudit(tx, managerID, action, auditEntityAd, session.Ad.ID, before, session.Ad)
+	})
+	if err != nil {
+		return err
 	}
 
 	// Уведомляем пользователя о публикации объявления
-	if session.Ad.UserID != 0 {
+	if keepPending {
+		log.Printf("Объявление %d изменено менеджером %d и ждёт модерации", session.Ad.ID, managerID)
+	} else if session.Ad.UserID != 0 {
 		message := fmt.Sprintf("✅ Ваше объявление «%s» опубликовано до %s.\n\nДля управления обратитесь к %s.", session.Ad.Title, session.Ad.ExpiresAt.Format("02.01.2006"), managerHelpLink)
 		notifyUser(bot, session.Ad.UserID, message)
 	} else {
 		log.Printf("Предупреждение: UserID равен 0, уведомление не отправлено. ClientID=%s", session.Ad.ClientID)
 	}
 
+	if session.PremiumQueued && !session.Ad.IsPremium {
+		if _, err := enqueuePremium(session.Ad.ID, managerID); err != nil && !errors.Is(err, errPremiumQueued) {
+			log.Printf("Ошибка постановки объявления %d в очередь на премиум: %v", session.Ad.ID, err)
+		}
+	}
+	// Премиум могли снять или перенести в другую категорию — место достаётся следующему в очереди
+	promotePremiumWaitlist(bot)
+
+	if published {
+		go notifySubscribers(bot, session.Ad)
+	}
+	if session.Operation == opEdit {
+		event := favoriteEventEdited
+		if published || session.DurationDays > 0 {
+			event = favoriteEventRenewed
+		}
+		go notifyFavorites(bot, session.Ad, event)
+	}
+
 	return nil
 }
 
-func setAdStatus(adID uint, status string) error {
-	return db.DB.Model(&models.Ad{}).Where("id = ?", adID).Updates(map[string]interface{}{
-		"status":              status,
-		"pre_expiry_notified": false,
-	}).Error
+// setAdStatus меняет статус объявления от имени менеджера actorID
+func setAdStatus(adID uint, status string, actorID int64) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var ad models.Ad
+		if err := tx.First(&ad, adID).Error; err != nil {
+			return err
+		}
+		previous := ad.Status
+		if err := tx.Model(&ad).Updates(map[string]interface{}{
+			"status":              status,
+			"pre_expiry_notified": false,
+		}).Error; err != nil {
+			return err
+		}
+		ad.Status = status
+		if err := saveAdRevision(tx, ad, actorID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorID, auditAdStatus, auditEntityAd, adID,
+			map[string]interface{}{"status": previous}, map[string]interface{}{"status": status})
+	})
 }
 
 func notifyUser(bot *tgbotapi.BotAPI, chatID int64, message string) {
@@ -2179,12 +2478,17 @@ func notifyUser(bot *tgbotapi.BotAPI, chatID int64, message string) {
 
 func persistSessionsCleanup() {
 	sessionRegistry.Lock()
-	defer sessionRegistry.Unlock()
 	for chatID, session := range sessionRegistry.data {
 		if time.Since(session.LastActivity) > sessionTimeoutDuration {
 			delete(sessionRegistry.data, chatID)
 		}
 	}
+	sessionRegistry.Unlock()
+
+	// Просроченные сессии удаляются и из внешнего хранилища
+	if err := getSessionStore().Cleanup(); err != nil {
+		log.Printf("bot session store cleanup failed: %v", err)
+	}
 }
 
 // deleteMessageWithEffect удаляет сообщение с эффектом "таноса" (редактирование перед удалением)
@@ -2323,18 +2627,43 @@ func setSession(chatID int64, session *adSession) {
 	session.ChatID = chatID
 	sessionRegistry.data[chatID] = session
 	sessionRegistry.Unlock()
+	persistSession(chatID)
 }
 
 func getSession(chatID int64) *adSession {
+	sessionRegistry.Lock()
+	session := sessionRegistry.data[chatID]
+	sessionRegistry.Unlock()
+	if session != nil {
+		return session
+	}
+
+	// Сессии нет в памяти — возможно, сервер был перезапущен посреди диалога
+	stored, err := getSessionStore().Load(chatID)
+	if err != nil {
+		log.Printf("failed to load bot session %d: %v", chatID, err)
+		return nil
+	}
+	if stored == nil {
+		return nil
+	}
+
 	sessionRegistry.Lock()
 	defer sessionRegistry.Unlock()
-	return sessionRegistry.data[chatID]
+	if existing := sessionRegistry.data[chatID]; existing != nil {
+		return existing
+	}
+	sessionRegistry.data[chatID] = stored
+	return stored
 }
 
 func clearSession(chatID int64) {
 	sessionRegistry.Lock()
 	delete(sessionRegistry.data, chatID)
 	sessionRegistry.Unlock()
+	if err := getSessionStore().Delete(chatID); err != nil {
+		log.Printf("failed to delete bot session %d: %v", chatID, err)
+	}
 }
 
 func isCommand(text, cmd string) bool {
@@ -2391,6 +2720,8 @@ func renderAdSummaryWithExpiry(ad models.Ad) string {
 		statusLabel = "Истекло"
 	case models.AdStatusInactive:
 		statusLabel = "Снято"
+	case models.AdStatusPending:
+		statusLabel = "На модерации"
 	default:
 		statusLabel = "Активно"
 	}
@@ -2428,6 +2759,9 @@ func renderAdSummaryWithExpiry(ad models.Ad) string {
 	if ad.Status == models.AdStatusActive {
 		text += fmt.Sprintf("\n⏱ *Действительно до:* %s", ad.ExpiresAt.Format("02.01.2006 15:04"))
 	}
+	if ad.Status == models.AdStatusPending && ad.RequestedDays > 0 {
+		text += fmt.Sprintf("\n⏱ *Запрошенный срок:* %d дн.", ad.RequestedDays)
+	}
 
 	return text
 }
@@ -2516,6 +2850,43 @@ func sendText(bot *tgbotapi.BotAPI, chatID int64, text string) {
 	}
 }
 
+// sendPhotos отправляет фотографии альбомами (Telegram принимает в альбоме от 2 до 10 фото)
+// и запоминает сообщения для последующего удаления. Сообщения возвращаются в порядке файлов.
+func sendPhotos(bot *tgbotapi.BotAPI, chatID int64, files []tgbotapi.RequestFileData) ([]tgbotapi.Message, error) {
+	var sent []tgbotapi.Message
+	for len(files) > 0 {
+		chunk := files
+		if len(chunk) > 10 {
+			chunk = chunk[:10]
+		}
+		files = files[len(chunk):]
+
+		if len(chunk) == 1 {
+			msg, err := bot.Send(tgbotapi.NewPhoto(chatID, chunk[0]))
+			if err != nil {
+				return sent, err
+			}
+			addBotMessage(chatID, msg.MessageID)
+			sent = append(sent, msg)
+			continue
+		}
+
+		media := make([]interface{}, 0, len(chunk))
+		for _, file := range chunk {
+			media = append(media, tgbotapi.NewInputMediaPhoto(file))
+		}
+		messages, err := bot.SendMediaGroup(tgbotapi.NewMediaGroup(chatID, media))
+		if err != nil {
+			return sent, err
+		}
+		for _, msg := range messages {
+			addBotMessage(chatID, msg.MessageID)
+		}
+		sent = append(sent, messages...)
+	}
+	return sent, nil
+}
+
 func startAdSchedulers(bot *tgbotapi.BotAPI) {
 	go func() {
 		ticker := time.NewTicker(time.Minute * 30)
@@ -2524,6 +2895,7 @@ func startAdSchedulers(bot *tgbotapi.BotAPI) {
 			persistSessionsCleanup()
 			processPreExpiry(bot)
 			processExpired(bot)
+			processSubscriptionDigests(bot)
 		}
 	}()
 }
@@ -2539,11 +2911,11 @@ func processPreExpiry(bot *tgbotapi.BotAPI) {
 	}
 
 	for _, ad := range ads {
+		notifyFavorites(bot, ad, favoriteEventExpiring)
 		if ad.UserID == 0 {
 			continue
 		}
-		text := fmt.Sprintf("Напоминание: срок действия вашего объявления «%s» истекает %s. Свяжитесь с %s, чтобы продлить размещение.", ad.Title, ad.ExpiresAt.Format("02.01.2006 15:04"), managerHelpLink)
-		notifyUser(bot, ad.UserID, text)
+		sendPreExpiryReminder(bot, ad)
 		if err := db.DB.Model(&models.Ad{}).Where("id = ?", ad.ID).Update("pre_expiry_notified", true).Error; err != nil {
 			log.Printf("pre-expiry flag update failed for ad %d: %v", ad.ID, err)
 		}
@@ -2560,17 +2932,39 @@ func processExpired(bot *tgbotapi.BotAPI) {
 	}
 
 	for _, ad := range ads {
-		if err := db.DB.Model(&models.Ad{}).Where("id = ?", ad.ID).Updates(map[string]interface{}{
-			"status":              models.AdStatusExpired,
-			"pre_expiry_notified": false,
-		}).Error; err != nil {
+		expired := false
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			// Пока шёл обход, объявление могли продлить или снять
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ad, ad.ID).Error; err != nil {
+				return err
+			}
+			if ad.Status != models.AdStatusActive || ad.ExpiresAt.After(now) {
+				return nil
+			}
+			if err := tx.Model(&ad).Updates(map[string]interface{}{
+				"status":              models.AdStatusExpired,
+				"pre_expiry_notified": false,
+			}).Error; err != nil {
+				return err
+			}
+			ad.Status = models.AdStatusExpired
+			expired = true
+			return saveAdRevision(tx, ad, 0, nil)
+		})
+		if err != nil {
 			log.Printf("failed to mark ad %d expired: %v", ad.ID, err)
 			continue
 		}
+		if !expired {
+			continue
+		}
 
 		if ad.UserID != 0 {
 			text := fmt.Sprintf("Ваше объявление «%s» больше не отображается на бирже. Свяжитесь с %s, чтобы поднять его снова.", ad.Title, managerHelpLink)
 			notifyUser(bot, ad.UserID, text)
 		}
 	}
+
+	// Освободившиеся премиум-места занимают объявления из очереди
+	promotePremiumWaitlist(bot)
 }
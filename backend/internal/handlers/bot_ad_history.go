@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// adHistoryLimit — сколько последних версий объявления показывает «История»
+const adHistoryLimit = 10
+
+func handleAdHistory(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	adID, err := strconv.ParseUint(strings.TrimPrefix(data, "ad_history_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	// Берём на одну ревизию больше, чтобы показать изменения и в самой старой из выведенных
+	var revisions []models.AdRevision
+	if err := db.DB.Where("ad_id = ?", adID).
+		Order("id DESC").
+		Limit(adHistoryLimit + 1).
+		Find(&revisions).Error; err != nil {
+		log.Printf("Ошибка загрузки истории объявления %d: %v", adID, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить историю объявления.")
+		return
+	}
+
+	backRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ К объявлению", fmt.Sprintf("select_ad_%d", adID)),
+	)
+
+	if len(revisions) == 0 {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📜 У объявления #%d ещё нет сохранённых версий.", adID))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(backRow)
+		sendAdHistory(bot, chatID, msg)
+		return
+	}
+
+	shown := revisions
+	if len(shown) > adHistoryLimit {
+		shown = shown[:adHistoryLimit]
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "📜 История объявления #%d\n", adID)
+	for i, rev := range shown {
+		text.WriteString("\n")
+		text.WriteString(formatAdRevision(rev, revisions, i))
+	}
+
+	// Самая новая ревизия совпадает с текущим объявлением, восстанавливать её незачем
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, rev := range shown[1:] {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("↩️ #%d", rev.ID), fmt.Sprintf("ad_restore_%d", rev.ID)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, backRow)
+
+	msg := tgbotapi.NewMessage(chatID, truncate(text.String(), 4000))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sendAdHistory(bot, chatID, msg)
+}
+
+// formatAdRevision описывает ревизию revisions[i] и её отличия от предыдущей revisions[i+1]
+func formatAdRevision(rev models.AdRevision, revisions []models.AdRevision, i int) string {
+	header := fmt.Sprintf("Версия #%d · %s", rev.ID, rev.CreatedAt.Format("02.01.2006 15:04"))
+	if rev.EditorID != 0 {
+		header += fmt.Sprintf(" · ID %d", rev.EditorID)
+	}
+	if i == 0 {
+		header += " · текущая"
+	}
+	if rev.RestoredFrom != nil {
+		header += fmt.Sprintf("\n   восстановлена из версии #%d", *rev.RestoredFrom)
+	}
+
+	if i+1 >= len(revisions) {
+		return header + "\n   первая сохранённая версия"
+	}
+
+	changes := diffAdRevisions(revisions[i+1], rev)
+	if len(changes) == 0 {
+		return header + fmt.Sprintf("\n   текст без изменений, статус: %s", rev.Status)
+	}
+	for _, change := range changes {
+		header += fmt.Sprintf("\n   %s: %s → %s", change.Field, formatRevisionValue(change.From), formatRevisionValue(change.To))
+	}
+	return header
+}
+
+func formatRevisionValue(value string) string {
+	if value == "" {
+		return "—"
+	}
+	return "«" + truncate(value, 60) + "»"
+}
+
+func sendAdHistory(bot *tgbotapi.BotAPI, chatID int64, msg tgbotapi.MessageConfig) {
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+// handleAdRestore возвращает объявление к выбранной ревизии и записывает это как новую ревизию
+func handleAdRestore(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	revID, err := strconv.ParseUint(strings.TrimPrefix(data, "ad_restore_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	var rev models.AdRevision
+	if err := db.DB.First(&rev, uint(revID)).Error; err != nil {
+		sendText(bot, chatID, "❌ Версия не найдена.")
+		return
+	}
+
+	var ad models.Ad
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ad, rev.AdID).Error; err != nil {
+			return err
+		}
+		before := ad
+		applyAdRevision(&ad, rev)
+
+		// Премиум остаётся за объявлением, но при смене категории занимает место уже в новой
+		if ad.IsPremium && ad.Status == models.AdStatusActive && before.Category != ad.Category {
+			if err := checkPremiumSlot(tx, ad); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Save(&ad).Error; err != nil {
+			return err
+		}
+		restoredFrom := rev.ID
+		if err := saveAdRevision(tx, ad, managerID, &restoredFrom); err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditAdRestore, auditEntityAd, ad.ID, before, ad)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Объявление не найдено.")
+		return
+	case errors.Is(err, errPremiumLimit):
+		sendText(bot, chatID, "⚠️ Не удалось восстановить версию: "+err.Error()+". Снимите одно из премиум-объявлений в этой категории или восстановите версию с текущей категорией.")
+		return
+	case err != nil:
+		log.Printf("Ошибка восстановления объявления %d из версии %d: %v", rev.AdID, rev.ID, err)
+		sendText(bot, chatID, "❌ Не удалось восстановить версию.")
+		return
+	}
+	log.Printf("Объявление %d восстановлено из версии %d менеджером %d", ad.ID, rev.ID, managerID)
+
+	sendText(bot, chatID, fmt.Sprintf("✅ Объявление #%d восстановлено из версии #%d.", ad.ID, rev.ID))
+	go notifyFavorites(bot, ad, favoriteEventEdited)
+	promotePremiumWaitlist(bot)
+	handleSelectAd(bot, chatID, fmt.Sprintf("select_ad_%d", ad.ID))
+}
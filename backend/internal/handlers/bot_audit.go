@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// auditHistoryLimit — сколько последних событий показывает /audit
+const auditHistoryLimit = 20
+
+// handleAuditCommand показывает историю действий по объявлению (/audit #123)
+// или по пользователю (/audit @username, /audit 123456789)
+func handleAuditCommand(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	arg := strings.TrimSpace(text[len(commandAudit):])
+	if arg == "" {
+		sendText(bot, chatID, "Использование: /audit @username, /audit <Telegram ID> или /audit #<ID объявления>")
+		return
+	}
+
+	var (
+		events []models.AuditEvent
+		title  string
+		err    error
+	)
+	if strings.HasPrefix(arg, "#") {
+		adID, parseErr := strconv.ParseUint(strings.TrimPrefix(arg, "#"), 10, 64)
+		if parseErr != nil || adID == 0 {
+			sendText(bot, chatID, "❌ Неверный номер объявления.")
+			return
+		}
+		title = fmt.Sprintf("объявлению #%d", adID)
+		err = db.DB.
+			Where("entity_type = ? AND entity_id = ?", auditEntityAd, strconv.FormatUint(adID, 10)).
+			Order("created_at DESC").
+			Limit(auditHistoryLimit).
+			Find(&events).Error
+	} else {
+		target := parseBlacklistTarget(arg)
+		if target.empty() {
+			sendText(bot, chatID, "❌ Укажите @username или Telegram ID.")
+			return
+		}
+		title = target.String()
+		events, err = userAuditEvents(target)
+	}
+	if err != nil {
+		log.Printf("Ошибка загрузки журнала аудита по %s: %v", arg, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить журнал.")
+		return
+	}
+
+	if len(events) == 0 {
+		sendText(bot, chatID, fmt.Sprintf("📜 По %s в журнале ничего нет.", title))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📜 Последние действия по %s:\n", title)
+	for _, event := range events {
+		b.WriteString("\n")
+		b.WriteString(formatAuditEvent(event))
+	}
+	sendText(bot, chatID, truncate(b.String(), 4000))
+}
+
+// userAuditEvents собирает события по самому пользователю, по его объявлениям
+// и, если известен Telegram ID, действия, совершённые им как менеджером, и смену его роли
+func userAuditEvents(target blacklistTarget) ([]models.AuditEvent, error) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	user, err := findBlacklistUser(db.DB, target, false)
+	switch {
+	case err == nil:
+		clauses = append(clauses, "(entity_type = ? AND entity_id = ?)")
+		args = append(args, auditEntityUser, strconv.FormatInt(user.ID, 10))
+		if target.Username == "" {
+			target.Username = user.Username
+		}
+		if target.TelegramID == 0 && user.TelegramID != nil {
+			target.TelegramID = *user.TelegramID
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// Удалённые объявления тоже интересны: история по ним сохраняется
+	ads := db.DB.Unscoped().Model(&models.Ad{}).Select("CAST(id AS text)")
+	switch {
+	case target.Username != "" && target.TelegramID != 0:
+		ads = ads.Where("LOWER(username) = LOWER(?) OR client_id = ?", target.Username, strconv.FormatInt(target.TelegramID, 10))
+	case target.Username != "":
+		ads = ads.Where("LOWER(username) = LOWER(?)", target.Username)
+	default:
+		ads = ads.Where("client_id = ?", strconv.FormatInt(target.TelegramID, 10))
+	}
+	clauses = append(clauses, "(entity_type = ? AND entity_id IN (?))")
+	args = append(args, auditEntityAd, ads)
+
+	if target.TelegramID != 0 {
+		clauses = append(clauses, "actor_id = ?", "(entity_type = ? AND entity_id = ?)")
+		args = append(args, target.TelegramID, auditEntityManager, strconv.FormatInt(target.TelegramID, 10))
+	}
+
+	var events []models.AuditEvent
+	err = db.DB.
+		Where(strings.Join(clauses, " OR "), args...).
+		Order("created_at DESC").
+		Limit(auditHistoryLimit).
+		Find(&events).Error
+	return events, err
+}
+
+func formatAuditEvent(event models.AuditEvent) string {
+	label := auditActionLabels[event.Action]
+	if label == "" {
+		label = event.Action
+	}
+
+	entity := "#" + event.EntityID
+	switch event.EntityType {
+	case auditEntityUser:
+		entity = "пользователя ID " + event.EntityID
+	case auditEntityManager:
+		entity = "· менеджер ID " + event.EntityID
+	}
+
+	// Действия планировщика и автоматические возвраты записываются с нулевым ActorID
+	actor := "система"
+	if event.ActorID != 0 {
+		actor = strconv.FormatInt(event.ActorID, 10)
+	}
+	line := fmt.Sprintf("%s · %s %s %s", event.CreatedAt.Format("02.01.2006 15:04"), actor, label, entity)
+
+	var changes map[string]auditChange
+	if err := json.Unmarshal([]byte(event.Changes), &changes); err != nil || len(changes) == 0 {
+		return line
+	}
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		change := changes[key]
+		line += fmt.Sprintf("\n   %s: %s → %s", key, formatAuditValue(change.From), formatAuditValue(change.To))
+	}
+	return line
+}
+
+func formatAuditValue(value interface{}) string {
+	if value == nil {
+		return "—"
+	}
+	if s, ok := value.(string); ok {
+		if s == "" {
+			return "—"
+		}
+		return "«" + truncate(s, 60) + "»"
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return truncate(string(raw), 60)
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	maxBlacklistEvidence     = 10
+	maxBlacklistReasonLen    = 1024
+	blacklistHistoryPageSize = 20
+)
+
+// blacklistDraft — данные, собираемые ботом перед внесением пользователя в чёрный список
+type blacklistDraft struct {
+	Username   string
+	TelegramID int64 // известен, если менеджер переслал сообщение или ввёл ID
+	Reason     string
+	Evidence   []string // file_id скриншотов
+}
+
+func (d blacklistDraft) target() blacklistTarget {
+	return blacklistTarget{Username: d.Username, TelegramID: d.TelegramID}
+}
+
+func blacklistBackKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_blacklist"),
+		),
+	)
+}
+
+// sendBlacklistResult отправляет итоговое сообщение сценария и завершает сессию
+func sendBlacklistResult(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = blacklistBackKeyboard()
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		// Удаляем предыдущие сообщения после отправки результата
+		session := getSession(chatID)
+		if session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+
+	clearSession(chatID)
+}
+
+// handleBlacklistAddInput принимает username или числовой Telegram ID.
+// Для нового пользователя без username дополнительно запрашивается username.
+func handleBlacklistAddInput(bot *tgbotapi.BotAPI, chatID int64, text string, session *adSession) {
+	target := parseBlacklistTarget(text)
+	if target.empty() {
+		sendText(bot, chatID, "❌ Введите username в формате @username, Telegram ID или перешлите сообщение пользователя")
+		return
+	}
+	if target.TelegramID != 0 {
+		session.Blacklist.TelegramID = target.TelegramID
+	} else {
+		session.Blacklist.Username = target.Username
+	}
+	continueBlacklistAdd(bot, chatID, session)
+}
+
+// handleBlacklistForwarded вносит в черновик автора пересланного сообщения
+func handleBlacklistForwarded(bot *tgbotapi.BotAPI, chatID int64, userID int64, username string, session *adSession) {
+	session.Blacklist.TelegramID = userID
+	if username != "" {
+		session.Blacklist.Username = username
+	}
+	continueBlacklistAdd(bot, chatID, session)
+}
+
+func continueBlacklistAdd(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
+	draft := session.Blacklist
+	target := draft.target()
+
+	existing, err := findBlacklistUser(db.DB, target, false)
+	if err == nil && existing.IsScammer {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("ℹ️ %s уже в чёрном списке (сейчас @%s)", target, existing.Username))
+		return
+	}
+
+	// Пользователь по ID ещё неизвестен — без username запись не создать
+	if draft.Username == "" && err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Telegram ID получен: %d\n\nТеперь отправьте текущий username пользователя (например: @username)", draft.TelegramID))
+		msg.ReplyMarkup = blacklistBackKeyboard()
+		sentMsg, sendErr := bot.Send(msg)
+		if sendErr == nil {
+			addBotMessage(chatID, sentMsg.MessageID)
+		}
+		return
+	}
+	if draft.Username == "" {
+		session.Blacklist.Username = existing.Username
+	}
+
+	session.Stage = stageAwaitBlacklistReason
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📝 *Причина внесения %s*\n\nКратко опишите, что произошло (не более %d символов).", escapeMarkdown(session.Blacklist.target().String()), maxBlacklistReasonLen))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = blacklistBackKeyboard()
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+	}
+}
+
+func handleBlacklistReasonInput(bot *tgbotapi.BotAPI, chatID int64, text string, session *adSession) {
+	reason := truncate(strings.TrimSpace(text), maxBlacklistReasonLen)
+	if reason == "" {
+		sendText(bot, chatID, "❌ Причина не может быть пустой.")
+		return
+	}
+
+	session.Blacklist.Reason = reason
+	session.Stage = stageAwaitBlacklistEvidence
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Сохранить", "blacklist_evidence_done"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Отмена", "menu_blacklist"),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📎 *Доказательства*\n\nОтправьте скриншоты (до %d шт.), затем нажмите «Сохранить». Если доказательств нет, просто нажмите «Сохранить».", maxBlacklistEvidence))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+	}
+}
+
+func handleBlacklistEvidenceInput(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, session *adSession) {
+	if len(msg.Photo) == 0 {
+		sendText(bot, msg.Chat.ID, "❌ Отправьте скриншот как фото или нажмите «Сохранить».")
+		return
+	}
+	if len(session.Blacklist.Evidence) >= maxBlacklistEvidence {
+		sendText(bot, msg.Chat.ID, fmt.Sprintf("❌ Можно приложить не более %d скриншотов. Нажмите «Сохранить».", maxBlacklistEvidence))
+		return
+	}
+
+	photo := msg.Photo[len(msg.Photo)-1]
+	session.Blacklist.Evidence = append(session.Blacklist.Evidence, photo.FileID)
+	sendText(bot, msg.Chat.ID, fmt.Sprintf("📎 Скриншот добавлен (%d/%d)", len(session.Blacklist.Evidence), maxBlacklistEvidence))
+}
+
+// handleBlacklistEvidenceDone сохраняет запись чёрного списка от имени менеджера managerID
+func handleBlacklistEvidenceDone(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
+	session := getSession(chatID)
+	if session == nil || session.Stage != stageAwaitBlacklistEvidence || session.Blacklist.Username == "" {
+		return
+	}
+	draft := session.Blacklist
+
+	entry, err := addToBlacklist(draft.target(), managerID, draft.Reason, draft.Evidence)
+	if errors.Is(err, errAlreadyBlacklisted) {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("ℹ️ %s уже в чёрном списке", draft.target()))
+		return
+	}
+	if errors.Is(err, errUsernameTaken) {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("❌ @%s закреплён за другим Telegram ID. Проверьте данные и начните заново.", draft.Username))
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка добавления @%s в чёрный список: %v", draft.Username, err)
+		sendText(bot, chatID, "❌ Ошибка во время обновления чёрного списка.")
+		return
+	}
+	log.Printf("Менеджер %d добавил %s в чёрный список (запись %d, доказательств: %d)", managerID, draft.target(), entry.ID, len(draft.Evidence))
+
+	sendBlacklistResult(bot, chatID, fmt.Sprintf("✅ Добавлен в чёрный список: %s\nПричина: %s\nДоказательств: %d", draft.target(), draft.Reason, len(draft.Evidence)))
+}
+
+func handleBlacklistRemoveInput(bot *tgbotapi.BotAPI, chatID int64, managerID int64, text string) {
+	target := parseBlacklistTarget(text)
+	if target.empty() {
+		sendText(bot, chatID, "❌ Введите username в формате @username или Telegram ID")
+		return
+	}
+	removeBlacklistTarget(bot, chatID, managerID, target)
+}
+
+func removeBlacklistTarget(bot *tgbotapi.BotAPI, chatID int64, managerID int64, target blacklistTarget) {
+	removed, err := removeFromBlacklist(target, managerID)
+	if err != nil {
+		log.Printf("Ошибка удаления %s из чёрного списка: %v", target, err)
+		sendText(bot, chatID, "❌ Ошибка во время обновления чёрного списка.")
+		return
+	}
+
+	if !removed {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("❌ Пользователь %s не найден в чёрном списке", target))
+		return
+	}
+	log.Printf("Менеджер %d удалил %s из чёрного списка", managerID, target)
+	sendBlacklistResult(bot, chatID, fmt.Sprintf("✅ Удалён из чёрного списка: %s", target))
+}
+
+func startBlacklistHistory(bot *tgbotapi.BotAPI, chatID int64) {
+	session := &adSession{
+		Stage:        stageAwaitBlacklistHistory,
+		LastActivity: time.Now(),
+		ChatID:       chatID,
+	}
+	setSession(chatID, session)
+
+	msg := tgbotapi.NewMessage(chatID, "📜 *История чёрного списка*\n\nОтправьте username (например: @username), Telegram ID или перешлите сообщение пользователя")
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = blacklistBackKeyboard()
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+	}
+}
+
+// handleBlacklistHistoryInput показывает все внесения и снятия пользователя с доказательствами
+func handleBlacklistHistoryInput(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	target := parseBlacklistTarget(text)
+	if target.empty() {
+		sendText(bot, chatID, "❌ Введите username в формате @username или Telegram ID")
+		return
+	}
+	showBlacklistHistory(bot, chatID, target)
+}
+
+func showBlacklistHistory(bot *tgbotapi.BotAPI, chatID int64, target blacklistTarget) {
+	user, err := findBlacklistUser(db.DB, target, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("📜 Для %s записей в истории нет", target))
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка поиска пользователя %s: %v", target, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить историю.")
+		return
+	}
+
+	var entries []models.BlacklistEntry
+	if err := db.DB.Preload("Evidence").
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(blacklistHistoryPageSize).
+		Find(&entries).Error; err != nil {
+		log.Printf("Ошибка загрузки истории чёрного списка %s: %v", target, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить историю.")
+		return
+	}
+
+	if len(entries) == 0 {
+		sendBlacklistResult(bot, chatID, fmt.Sprintf("📜 Для %s записей в истории нет", target))
+		return
+	}
+
+	aliases, err := userAliases([]int64{user.ID})
+	if err != nil {
+		log.Printf("Ошибка загрузки прежних username пользователя %d: %v", user.ID, err)
+	}
+
+	username := user.Username
+	var history strings.Builder
+	history.WriteString(fmt.Sprintf("📜 *История @%s:*\n", escapeMarkdown(username)))
+	if user.TelegramID != nil {
+		history.WriteString(fmt.Sprintf("Telegram ID: %d\n", *user.TelegramID))
+	}
+	if previous := aliases[user.ID]; len(previous) > 0 {
+		history.WriteString(fmt.Sprintf("Прежние username: @%s\n", escapeMarkdown(strings.Join(previous, ", @"))))
+	}
+	var evidence []tgbotapi.RequestFileData
+	for _, entry := range entries {
+		history.WriteString(fmt.Sprintf("\n➕ %s — внёс менеджер %d\n", entry.CreatedAt.Format("02.01.2006 15:04"), entry.AddedBy))
+		if entry.Reason != "" {
+			history.WriteString(fmt.Sprintf("Причина: %s\n", escapeMarkdown(entry.Reason)))
+		}
+		if len(entry.Evidence) > 0 {
+			history.WriteString(fmt.Sprintf("Доказательств: %d\n", len(entry.Evidence)))
+		}
+		if entry.RemovedAt != nil {
+			history.WriteString(fmt.Sprintf("➖ %s — снял менеджер %d\n", entry.RemovedAt.Format("02.01.2006 15:04"), entry.RemovedBy))
+		}
+		for _, item := range entry.Evidence {
+			if len(evidence) < maxBlacklistEvidence {
+				evidence = append(evidence, tgbotapi.FileID(item.FileID))
+			}
+		}
+	}
+
+	// Скриншоты отправляем альбомом до текста, чтобы кнопка «Назад» оставалась последней
+	if _, err := sendPhotos(bot, chatID, evidence); err != nil {
+		log.Printf("Ошибка отправки доказательств @%s: %v", username, err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, history.String())
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = blacklistBackKeyboard()
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+	}
+
+	clearSession(chatID)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Команды владельцев объявлений (клиентский режим бота)
+const (
+	commandMyAds  = "/myads"
+	commandRenew  = "/renew"
+	commandRemove = "/remove"
+	commandStatus = "/status"
+	// commandSubscriptions доступна и клиентам, и менеджерам
+	commandSubscriptions = "/subscriptions"
+)
+
+// clientRenewDefaultDays — срок продления, если в /renew он не указан
+const clientRenewDefaultDays = 7
+
+// clientMyAdsLimit — сколько объявлений показывает /myads
+const clientMyAdsLimit = 20
+
+const clientHelpText = "👋 Здесь можно управлять своими объявлениями на бирже.\n\n" +
+	"/myads — мои объявления\n" +
+	"/status <ID> — статус объявления и заявок по нему\n" +
+	"/renew <ID> [1|7|14|30] — попросить продлить объявление (по умолчанию на 7 дней)\n" +
+	"/remove <ID> — попросить снять объявление с биржи\n" +
+	"/subscriptions — сохранённые поиски и оповещения о новых объявлениях\n\n" +
+	"Заявки рассматривают менеджеры, результат придёт сюда же. Вопросы — " + managerHelpLink
+
+func isClientCommand(text string) bool {
+	return isCommand(text, commandMyAds) || isCommand(text, commandRenew) ||
+		isCommand(text, commandRemove) || isCommand(text, commandStatus) ||
+		isCommand(text, commandSubscriptions)
+}
+
+func adStatusLabel(status string) string {
+	switch status {
+	case models.AdStatusExpired:
+		return "🔴 Истекло"
+	case models.AdStatusInactive:
+		return "⚫ Снято"
+	case models.AdStatusPending:
+		return "🟡 На модерации"
+	default:
+		return "🟢 Активно"
+	}
+}
+
+// handleClientMessage обрабатывает сообщения пользователей, которые не являются менеджерами
+func handleClientMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	if msg.From == nil || msg.Chat == nil || !msg.Chat.IsPrivate() {
+		return
+	}
+	text := strings.TrimSpace(msg.Text)
+	userID := msg.From.ID
+
+	switch {
+	case isCommand(text, commandMyAds):
+		showClientAds(bot, msg.Chat.ID, userID)
+	case isCommand(text, commandRenew):
+		args := strings.Fields(text[len(commandRenew):])
+		if len(args) == 0 || len(args) > 2 {
+			sendText(bot, msg.Chat.ID, "Использование: /renew <ID объявления> [1|7|14|30]")
+			return
+		}
+		days := clientRenewDefaultDays
+		if len(args) == 2 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || !isValidDuration(parsed) {
+				sendText(bot, msg.Chat.ID, "❌ Срок продления: 1, 7, 14 или 30 дней.")
+				return
+			}
+			days = parsed
+		}
+		adID, ok := parseClientAdID(args[0])
+		if !ok {
+			sendText(bot, msg.Chat.ID, "Использование: /renew <ID объявления> [1|7|14|30]")
+			return
+		}
+		submitClientRequest(bot, msg.Chat.ID, userID, adID, models.ClientRequestRenew, days)
+	case isCommand(text, commandRemove):
+		adID, ok := parseClientAdID(strings.TrimSpace(text[len(commandRemove):]))
+		if !ok {
+			sendText(bot, msg.Chat.ID, "Использование: /remove <ID объявления>")
+			return
+		}
+		submitClientRequest(bot, msg.Chat.ID, userID, adID, models.ClientRequestRemove, 0)
+	case isCommand(text, commandStatus):
+		arg := strings.TrimSpace(text[len(commandStatus):])
+		if arg == "" {
+			showClientRequests(bot, msg.Chat.ID, userID)
+			return
+		}
+		adID, ok := parseClientAdID(arg)
+		if !ok {
+			sendText(bot, msg.Chat.ID, "Использование: /status <ID объявления>")
+			return
+		}
+		showClientAdStatus(bot, msg.Chat.ID, userID, adID)
+	case isCommand(text, commandSubscriptions):
+		showSubscriptions(bot, msg.Chat.ID, userID)
+	default:
+		sendText(bot, msg.Chat.ID, clientHelpText)
+	}
+}
+
+func parseClientAdID(arg string) (uint, bool) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// handleClientCallback обрабатывает кнопки клиентского режима: client_renew_<ID>_<дни> и sub_delete_<ID>
+func handleClientCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	if callback.Message == nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+
+	if strings.HasPrefix(callback.Data, "sub_delete_") {
+		handleSubscriptionDelete(bot, chatID, callback.From.ID, callback.Data)
+		return
+	}
+	if !strings.HasPrefix(callback.Data, "client_renew_") {
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "client_renew_"), "_")
+	if len(parts) != 2 {
+		return
+	}
+	adID, ok := parseClientAdID(parts[0])
+	days, err := strconv.Atoi(parts[1])
+	if !ok || err != nil || !isValidDuration(days) {
+		return
+	}
+	submitClientRequest(bot, chatID, callback.From.ID, adID, models.ClientRequestRenew, days)
+}
+
+// clientAds возвращает объявления пользователя так же, как GetMyAds: по client_id или user_id
+func clientAds(userID int64) ([]models.Ad, error) {
+	var ads []models.Ad
+	err := db.DB.Where("client_id = ? OR user_id = ?", strconv.FormatInt(userID, 10), userID).
+		Order(gorm.Expr("CASE WHEN status = ? THEN 0 WHEN status = ? THEN 1 ELSE 2 END, updated_at DESC", models.AdStatusActive, models.AdStatusExpired)).
+		Limit(clientMyAdsLimit).
+		Find(&ads).Error
+	return ads, err
+}
+
+func showClientAds(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	ads, err := clientAds(userID)
+	if err != nil {
+		log.Printf("Ошибка загрузки объявлений пользователя %d: %v", userID, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить объявления. Попробуйте позже.")
+		return
+	}
+	if len(ads) == 0 {
+		sendText(bot, chatID, fmt.Sprintf("У вас пока нет объявлений. Чтобы разместить объявление, напишите %s.", managerHelpLink))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("📋 Ваши объявления:\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, ad := range ads {
+		fmt.Fprintf(&b, "\n#%d «%s» — %s", ad.ID, truncate(ad.Title, 50), adStatusLabel(ad.Status))
+		if ad.IsPremium && ad.Status == models.AdStatusActive {
+			b.WriteString(" ⭐")
+		}
+		if !ad.ExpiresAt.IsZero() && ad.Status != models.AdStatusPending {
+			fmt.Fprintf(&b, ", до %s", ad.ExpiresAt.Format("02.01.2006"))
+		}
+		if ad.Status == models.AdStatusActive || ad.Status == models.AdStatusExpired {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🔄 Продлить #%d на 7 дней", ad.ID), fmt.Sprintf("client_renew_%d_7", ad.ID)),
+			))
+		}
+	}
+	b.WriteString("\n\nПодробнее: /status <ID>. Снять объявление: /remove <ID>.")
+
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("failed to send client ads to %d: %v", chatID, err)
+	}
+}
+
+// loadClientAd загружает объявление пользователя; чужие объявления для него как будто не существуют
+func loadClientAd(adID uint, userID int64) (models.Ad, error) {
+	var ad models.Ad
+	if err := db.DB.First(&ad, adID).Error; err != nil {
+		return ad, err
+	}
+	if !ownsAd(ad, userID) {
+		return ad, gorm.ErrRecordNotFound
+	}
+	return ad, nil
+}
+
+func showClientAdStatus(bot *tgbotapi.BotAPI, chatID int64, userID int64, adID uint) {
+	ad, err := loadClientAd(adID, userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Ошибка загрузки объявления %d: %v", adID, err)
+		}
+		sendText(bot, chatID, "❌ Объявление не найдено среди ваших. Список: /myads")
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d «%s»\nСтатус: %s", ad.ID, ad.Title, adStatusLabel(ad.Status))
+	if ad.Status == models.AdStatusPending {
+		b.WriteString("\nОбъявление проверяет модератор.")
+	} else if !ad.ExpiresAt.IsZero() {
+		fmt.Fprintf(&b, "\nСрок размещения: до %s", ad.ExpiresAt.Format("02.01.2006 15:04"))
+	}
+	if ad.IsPremium && ad.Status == models.AdStatusActive {
+		b.WriteString("\n⭐ Премиум")
+	} else if queue, queued, err := adPremiumQueueStatus(ad); err == nil && queued {
+		b.WriteString("\n" + formatPremiumQueueStatus(queue))
+	}
+
+	var requests []models.ClientRequest
+	if err := db.DB.Where("ad_id = ? AND user_id = ?", ad.ID, userID).Order("id DESC").Limit(5).Find(&requests).Error; err != nil {
+		log.Printf("Ошибка загрузки заявок по объявлению %d: %v", ad.ID, err)
+	}
+	if len(requests) > 0 {
+		b.WriteString("\n\nЗаявки:")
+		for _, request := range requests {
+			b.WriteString("\n" + formatClientRequest(request))
+		}
+	}
+	sendText(bot, chatID, b.String())
+}
+
+func showClientRequests(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	var requests []models.ClientRequest
+	if err := db.DB.Where("user_id = ?", userID).Order("id DESC").Limit(10).Find(&requests).Error; err != nil {
+		log.Printf("Ошибка загрузки заявок пользователя %d: %v", userID, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить заявки. Попробуйте позже.")
+		return
+	}
+	if len(requests) == 0 {
+		sendText(bot, chatID, "Заявок пока нет. Статус объявления: /status <ID>, список объявлений: /myads")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("📨 Ваши заявки:")
+	for _, request := range requests {
+		fmt.Fprintf(&b, "\n#%d · объявление #%d · %s", request.ID, request.AdID, formatClientRequest(request))
+	}
+	sendText(bot, chatID, b.String())
+}
+
+// sendPreExpiryReminder отправляет владельцу напоминание с кнопками заявки на продление
+func sendPreExpiryReminder(bot *tgbotapi.BotAPI, ad models.Ad) {
+	text := fmt.Sprintf("Напоминание: срок действия вашего объявления «%s» истекает %s. Продлить размещение можно кнопкой ниже или командой /renew %d.",
+		ad.Title, ad.ExpiresAt.Format("02.01.2006 15:04"), ad.ID)
+	msg := tgbotapi.NewMessage(ad.UserID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Продлить на 7 дней", fmt.Sprintf("client_renew_%d_7", ad.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔄 На 30 дней", fmt.Sprintf("client_renew_%d_30", ad.ID)),
+		),
+	)
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("failed to notify user %d: %v", ad.UserID, err)
+	}
+}
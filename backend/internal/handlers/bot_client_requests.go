@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// clientRequestOpenLimit — сколько нерассмотренных заявок может быть у одного пользователя
+const clientRequestOpenLimit = 5
+
+// clientRequestQueueLimit — сколько заявок за раз показывает «📥 Заявки клиентов»
+const clientRequestQueueLimit = 10
+
+var (
+	errClientRequestExists = errors.New("такая заявка по этому объявлению уже ждёт рассмотрения")
+	errClientRequestLimit  = errors.New("слишком много заявок ждут рассмотрения, дождитесь ответа менеджера")
+	errClientRequestStatus = errors.New("для объявления в таком статусе эта заявка не нужна")
+	errClientRequestDone   = errors.New("client request already handled")
+)
+
+var clientRequestStatusLabels = map[string]string{
+	models.ClientRequestPending:  "⏳ ждёт менеджера",
+	models.ClientRequestDone:     "✅ выполнена",
+	models.ClientRequestRejected: "🚫 отклонена",
+}
+
+func clientRequestTitle(request models.ClientRequest) string {
+	if request.Kind == models.ClientRequestRenew {
+		return fmt.Sprintf("продлить на %d дн.", request.Days)
+	}
+	return "снять с биржи"
+}
+
+func formatClientRequest(request models.ClientRequest) string {
+	return fmt.Sprintf("%s · %s · %s", request.CreatedAt.Format("02.01.2006 15:04"), clientRequestTitle(request), clientRequestStatusLabels[request.Status])
+}
+
+func pendingClientRequestsCount() (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.ClientRequest{}).Where("status = ?", models.ClientRequestPending).Count(&count).Error
+	return count, err
+}
+
+// createClientRequest сохраняет заявку владельца объявления
+func createClientRequest(userID int64, adID uint, kind string, days int) (*models.ClientRequest, models.Ad, error) {
+	var (
+		request models.ClientRequest
+		ad      models.Ad
+	)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ad, adID).Error; err != nil {
+			return err
+		}
+		if !ownsAd(ad, userID) {
+			return gorm.ErrRecordNotFound
+		}
+		switch kind {
+		case models.ClientRequestRenew:
+			if ad.Status == models.AdStatusPending {
+				return errClientRequestStatus
+			}
+		case models.ClientRequestRemove:
+			if ad.Status == models.AdStatusInactive {
+				return errClientRequestStatus
+			}
+		}
+
+		var open []models.ClientRequest
+		if err := tx.Where("user_id = ? AND status = ?", userID, models.ClientRequestPending).Find(&open).Error; err != nil {
+			return err
+		}
+		for _, existing := range open {
+			if existing.AdID == adID && existing.Kind == kind {
+				return errClientRequestExists
+			}
+		}
+		if len(open) >= clientRequestOpenLimit {
+			return errClientRequestLimit
+		}
+
+		request = models.ClientRequest{AdID: adID, UserID: userID, Kind: kind, Days: days, Status: models.ClientRequestPending}
+		return tx.Create(&request).Error
+	})
+	if err != nil {
+		return nil, ad, err
+	}
+	return &request, ad, nil
+}
+
+// submitClientRequest принимает заявку от пользователя и передаёт её менеджерам
+func submitClientRequest(bot *tgbotapi.BotAPI, chatID int64, userID int64, adID uint, kind string, days int) {
+	request, ad, err := createClientRequest(userID, adID, kind, days)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Объявление не найдено среди ваших. Список: /myads")
+		return
+	case errors.Is(err, errClientRequestExists), errors.Is(err, errClientRequestLimit), errors.Is(err, errClientRequestStatus):
+		sendText(bot, chatID, "ℹ️ Заявка не отправлена: "+err.Error()+". Статус: /status")
+		return
+	case err != nil:
+		log.Printf("Ошибка сохранения заявки пользователя %d по объявлению %d: %v", userID, adID, err)
+		sendText(bot, chatID, "❌ Не удалось отправить заявку. Попробуйте позже.")
+		return
+	}
+	log.Printf("Заявка %d от пользователя %d: %s, объявление %d", request.ID, userID, request.Kind, adID)
+
+	sendText(bot, chatID, fmt.Sprintf("📨 Заявка #%d «%s» по объявлению #%d отправлена менеджерам. Ответ придёт сюда, статус — /status %d.", request.ID, clientRequestTitle(*request), ad.ID, ad.ID))
+	notifyManagersAboutClientRequest(bot, *request, ad)
+}
+
+// notifyManagersAboutClientRequest присылает заявку менеджерам, которые могут её выполнить
+func notifyManagersAboutClientRequest(bot *tgbotapi.BotAPI, request models.ClientRequest, ad models.Ad) {
+	managerIDs, err := managersWith(permManageAds)
+	if err != nil {
+		log.Printf("Ошибка загрузки списка менеджеров: %v", err)
+		return
+	}
+	text := fmt.Sprintf("📥 Заявка клиента #%d: %s\nОбъявление #%d «%s» — %s\nКлиент: ID %d",
+		request.ID, clientRequestTitle(request), ad.ID, ad.Title, adStatusLabel(ad.Status), request.UserID)
+	for _, managerID := range managerIDs {
+		msg := tgbotapi.NewMessage(managerID, text)
+		msg.ReplyMarkup = clientRequestKeyboard(request)
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("failed to notify manager %d: %v", managerID, err)
+		}
+	}
+}
+
+func clientRequestKeyboard(request models.ClientRequest) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Выполнить", fmt.Sprintf("client_req_done_%d", request.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Отклонить", fmt.Sprintf("client_req_reject_%d", request.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📄 Объявление #%d", request.AdID), fmt.Sprintf("select_ad_%d", request.AdID)),
+		),
+	)
+}
+
+// showClientRequestQueue показывает нерассмотренные заявки клиентов, старые — первыми
+func showClientRequestQueue(bot *tgbotapi.BotAPI, chatID int64) {
+	var requests []models.ClientRequest
+	if err := db.DB.Where("status = ?", models.ClientRequestPending).
+		Order("id").
+		Limit(clientRequestQueueLimit).
+		Find(&requests).Error; err != nil {
+		log.Printf("Ошибка загрузки заявок клиентов: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить заявки.")
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var b strings.Builder
+	if len(requests) == 0 {
+		b.WriteString("📥 Новых заявок клиентов нет")
+	} else {
+		b.WriteString("📥 Заявки клиентов\n")
+		for _, request := range requests {
+			fmt.Fprintf(&b, "\n#%d · %s · объявление #%d · ID %d · %s",
+				request.ID, request.CreatedAt.Format("02.01 15:04"), request.AdID, request.UserID, clientRequestTitle(request))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ #%d", request.ID), fmt.Sprintf("client_req_done_%d", request.ID)),
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🚫 #%d", request.ID), fmt.Sprintf("client_req_reject_%d", request.ID)),
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📄 #%d", request.AdID), fmt.Sprintf("select_ad_%d", request.AdID)),
+			))
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+// lockPendingClientRequest блокирует заявку, пока её не рассмотрели
+func lockPendingClientRequest(tx *gorm.DB, requestID uint) (models.ClientRequest, error) {
+	var request models.ClientRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, requestID).Error; err != nil {
+		return request, err
+	}
+	if request.Status != models.ClientRequestPending {
+		return request, errClientRequestDone
+	}
+	return request, nil
+}
+
+func closeClientRequest(tx *gorm.DB, request *models.ClientRequest, status string, managerID int64) error {
+	now := time.Now()
+	request.Status = status
+	request.HandledBy = managerID
+	request.HandledAt = &now
+	return tx.Save(request).Error
+}
+
+// handleClientRequestDone выполняет заявку ("client_req_done_<ID>"): продлевает или снимает объявление
+func handleClientRequestDone(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	requestID, err := strconv.ParseUint(strings.TrimPrefix(data, "client_req_done_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	var (
+		request models.ClientRequest
+		ad      models.Ad
+	)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if request, err = lockPendingClientRequest(tx, uint(requestID)); err != nil {
+			return err
+		}
+		if err := tx.First(&ad, request.AdID).Error; err != nil {
+			return err
+		}
+		before := ad
+
+		switch request.Kind {
+		case models.ClientRequestRenew:
+			// Продлеваем от текущего срока, чтобы заявка до истечения не съедала оставшиеся дни
+			base := ad.ExpiresAt
+			if base.Before(time.Now()) {
+				base = time.Now()
+			}
+			ad.ExpiresAt = base.Add(time.Duration(request.Days) * 24 * time.Hour)
+			ad.Status = models.AdStatusActive
+			ad.PreExpiryNotified = false
+			if err := tx.Save(&ad).Error; err != nil {
+				return err
+			}
+			if err := saveAdRevision(tx, ad, managerID, nil); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, managerID, auditAdRenew, auditEntityAd, ad.ID, before, ad); err != nil {
+				return err
+			}
+		case models.ClientRequestRemove:
+			if err := tx.Model(&ad).Updates(map[string]interface{}{
+				"status":              models.AdStatusInactive,
+				"pre_expiry_notified": false,
+			}).Error; err != nil {
+				return err
+			}
+			ad.Status = models.AdStatusInactive
+			if err := saveAdRevision(tx, ad, managerID, nil); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, managerID, auditAdStatus, auditEntityAd, ad.ID,
+				map[string]interface{}{"status": before.Status}, map[string]interface{}{"status": models.AdStatusInactive}); err != nil {
+				return err
+			}
+		}
+		return closeClientRequest(tx, &request, models.ClientRequestDone, managerID)
+	})
+	if !handleClientRequestError(bot, chatID, uint(requestID), err) {
+		return
+	}
+	log.Printf("Заявка %d выполнена менеджером %d", request.ID, managerID)
+
+	if request.Kind == models.ClientRequestRenew {
+		notifyUser(bot, request.UserID, fmt.Sprintf("✅ Заявка #%d выполнена: объявление «%s» продлено до %s.", request.ID, ad.Title, ad.ExpiresAt.Format("02.01.2006")))
+		sendText(bot, chatID, fmt.Sprintf("✅ Заявка #%d выполнена: объявление #%d продлено до %s.", request.ID, ad.ID, ad.ExpiresAt.Format("02.01.2006 15:04")))
+		go notifyFavorites(bot, ad, favoriteEventRenewed)
+	} else {
+		notifyUser(bot, request.UserID, fmt.Sprintf("✅ Заявка #%d выполнена: объявление «%s» снято с биржи.", request.ID, ad.Title))
+		sendText(bot, chatID, fmt.Sprintf("✅ Заявка #%d выполнена: объявление #%d снято с биржи.", request.ID, ad.ID))
+		promotePremiumWaitlist(bot)
+	}
+	showClientRequestQueue(bot, chatID)
+}
+
+// handleClientRequestReject отклоняет заявку ("client_req_reject_<ID>")
+func handleClientRequestReject(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	requestID, err := strconv.ParseUint(strings.TrimPrefix(data, "client_req_reject_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	var request models.ClientRequest
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if request, err = lockPendingClientRequest(tx, uint(requestID)); err != nil {
+			return err
+		}
+		return closeClientRequest(tx, &request, models.ClientRequestRejected, managerID)
+	})
+	if !handleClientRequestError(bot, chatID, uint(requestID), err) {
+		return
+	}
+	log.Printf("Заявка %d отклонена менеджером %d", request.ID, managerID)
+
+	notifyUser(bot, request.UserID, fmt.Sprintf("🚫 Заявка #%d «%s» по объявлению #%d отклонена. Подробности — у %s.", request.ID, clientRequestTitle(request), request.AdID, managerHelpLink))
+	sendText(bot, chatID, fmt.Sprintf("🚫 Заявка #%d отклонена.", request.ID))
+	showClientRequestQueue(bot, chatID)
+}
+
+// handleClientRequestError сообщает менеджеру об ошибке; true — ошибки не было
+func handleClientRequestError(bot *tgbotapi.BotAPI, chatID int64, requestID uint, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errClientRequestDone):
+		sendText(bot, chatID, "ℹ️ Эту заявку уже рассмотрел другой менеджер.")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Заявка или объявление не найдены.")
+	default:
+		log.Printf("Ошибка обработки заявки %d: %v", requestID, err)
+		sendText(bot, chatID, "❌ Не удалось обработать заявку.")
+	}
+	return false
+}
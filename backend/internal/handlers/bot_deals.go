@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// dealQueueLimit — сколько открытых сделок показывает меню «🤝 Сделки»
+const dealQueueLimit = 20
+
+// dealTransitionLabels — подписи кнопок перехода в статус
+var dealTransitionLabels = map[string]string{
+	models.DealStatusFunded:    "💰 Оплата получена",
+	models.DealStatusDelivered: "📦 Товар передан",
+	models.DealStatusReleased:  "✅ Выплатить продавцу",
+	models.DealStatusDisputed:  "⚠️ Открыть спор",
+	models.DealStatusRefunded:  "↩️ Вернуть покупателю",
+}
+
+func openDealsCount() (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Deal{}).Where("status IN ?", dealOpenStatuses).Count(&count).Error
+	return count, err
+}
+
+func formatDealAmount(amount int64) string {
+	return fmt.Sprintf("%d ₽", amount)
+}
+
+func dealParty(username string, id int64) string {
+	if username != "" {
+		return fmt.Sprintf("@%s (ID %d)", username, id)
+	}
+	return fmt.Sprintf("ID %d", id)
+}
+
+func formatDeal(deal models.Deal, adTitle string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🤝 Сделка #%d · %s\n", deal.ID, dealStatusLabels[deal.Status])
+	fmt.Fprintf(&b, "Объявление #%d", deal.AdID)
+	if adTitle != "" {
+		fmt.Fprintf(&b, " «%s»", adTitle)
+	}
+	fmt.Fprintf(&b, "\nПродавец: %s\nПокупатель: %s\n", dealParty(deal.SellerUsername, deal.SellerID), dealParty(deal.BuyerUsername, deal.BuyerID))
+	fmt.Fprintf(&b, "Сумма: %s, комиссия гаранта: %s\n", formatDealAmount(deal.Amount), formatDealAmount(deal.Fee))
+	fmt.Fprintf(&b, "Открыта: %s", deal.CreatedAt.Format("02.01.2006 15:04"))
+	if deal.FundedAt != nil {
+		fmt.Fprintf(&b, "\nОплачена: %s", deal.FundedAt.Format("02.01.2006 15:04"))
+	}
+	if deal.DeliveredAt != nil {
+		fmt.Fprintf(&b, "\nТовар передан: %s", deal.DeliveredAt.Format("02.01.2006 15:04"))
+	}
+	if deal.ClosedAt != nil {
+		fmt.Fprintf(&b, "\nЗакрыта: %s", deal.ClosedAt.Format("02.01.2006 15:04"))
+	}
+	return b.String()
+}
+
+func dealAdTitle(adID uint) string {
+	var ad models.Ad
+	if err := db.DB.Unscoped().Select("id", "title").First(&ad, adID).Error; err != nil {
+		return ""
+	}
+	return ad.Title
+}
+
+// notifyDealParties сообщает покупателю и продавцу о новом статусе сделки
+func notifyDealParties(bot *tgbotapi.BotAPI, deal models.Deal, adTitle string) {
+	subject := fmt.Sprintf("#%d по объявлению «%s» на %s", deal.ID, adTitle, formatDealAmount(deal.Amount))
+	var text string
+	switch deal.Status {
+	case models.DealStatusCreated:
+		text = fmt.Sprintf("🤝 Открыта сделка через гаранта %s. Покупатель переводит оплату гаранту, продавец передаёт товар после подтверждения оплаты.", subject)
+	case models.DealStatusFunded:
+		text = fmt.Sprintf("💰 Гарант получил оплату по сделке %s. Продавец может передавать товар.", subject)
+	case models.DealStatusDelivered:
+		text = fmt.Sprintf("📦 Продавец передал товар по сделке %s. Гарант выплатит деньги после проверки.", subject)
+	case models.DealStatusReleased:
+		text = fmt.Sprintf("✅ Сделка %s завершена: гарант выплатил деньги продавцу.", subject)
+	case models.DealStatusDisputed:
+		text = fmt.Sprintf("⚠️ По сделке %s открыт спор. Менеджер свяжется с обеими сторонами.", subject)
+	case models.DealStatusRefunded:
+		text = fmt.Sprintf("↩️ Сделка %s отменена, оплата возвращается покупателю.", subject)
+	default:
+		return
+	}
+	notifyUser(bot, deal.BuyerID, text)
+	notifyUser(bot, deal.SellerID, text)
+}
+
+// showDeals показывает открытые сделки, старые — первыми
+func showDeals(bot *tgbotapi.BotAPI, chatID int64) {
+	var deals []models.Deal
+	if err := db.DB.Where("status IN ?", dealOpenStatuses).
+		Order("id").
+		Limit(dealQueueLimit).
+		Find(&deals).Error; err != nil {
+		log.Printf("Ошибка загрузки сделок: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить сделки.")
+		return
+	}
+
+	var b strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(deals) == 0 {
+		b.WriteString("🤝 Открытых сделок нет\n\nОткрыть сделку можно из карточки объявления.")
+	} else {
+		b.WriteString("🤝 Открытые сделки\n")
+	}
+	for _, deal := range deals {
+		fmt.Fprintf(&b, "\n#%d · %s · %s\n%s → %s\n", deal.ID, dealStatusLabels[deal.Status], formatDealAmount(deal.Amount),
+			dealParty(deal.BuyerUsername, deal.BuyerID), dealParty(deal.SellerUsername, deal.SellerID))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Сделка #%d", deal.ID), fmt.Sprintf("deal_view_%d", deal.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+	))
+	sendDealMessage(bot, chatID, b.String(), rows)
+}
+
+// sendDealMessage отправляет сообщение без Markdown: в username бывают подчёркивания
+func sendDealMessage(bot *tgbotapi.BotAPI, chatID int64, text string, rows [][]tgbotapi.InlineKeyboardButton) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+// showDeal показывает карточку сделки с кнопками допустимых переходов
+func showDeal(bot *tgbotapi.BotAPI, chatID int64, deal models.Deal) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, next := range dealTransitions[deal.Status] {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(dealTransitionLabels[next], fmt.Sprintf("deal_to_%d_%s", deal.ID, next)),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📄 Объявление", fmt.Sprintf("select_ad_%d", deal.AdID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ К сделкам", "menu_deals"),
+		),
+	)
+	sendDealMessage(bot, chatID, formatDeal(deal, dealAdTitle(deal.AdID)), rows)
+}
+
+// handleDealView обрабатывает deal_view_<ID>
+func handleDealView(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	dealID, err := strconv.ParseUint(strings.TrimPrefix(data, "deal_view_"), 10, 32)
+	if err != nil {
+		return
+	}
+	var deal models.Deal
+	if err := db.DB.First(&deal, dealID).Error; err != nil {
+		sendText(bot, chatID, "❌ Сделка не найдена.")
+		return
+	}
+	showDeal(bot, chatID, deal)
+}
+
+// startDealCreate обрабатывает deal_new_<ID объявления> и запрашивает условия сделки
+func startDealCreate(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	adID, err := strconv.ParseUint(strings.TrimPrefix(data, "deal_new_"), 10, 32)
+	if err != nil {
+		return
+	}
+	var ad models.Ad
+	if err := db.DB.First(&ad, adID).Error; err != nil {
+		sendText(bot, chatID, "❌ Объявление не найдено.")
+		return
+	}
+	if ad.UserID == 0 {
+		sendText(bot, chatID, "❌ У объявления не указан Telegram ID автора — продавца сделки.")
+		return
+	}
+
+	session := &adSession{
+		Stage:         stageAwaitDealTerms,
+		LastActivity:  time.Now(),
+		ChatID:        chatID,
+		BotMessageIDs: []int{},
+		Ad:            ad,
+	}
+	setSession(chatID, session)
+
+	text := fmt.Sprintf("🤝 Новая сделка по объявлению #%d «%s»\nПродавец: %s\n\n"+
+		"Введите покупателя и сумму в рублях: <@username или Telegram ID> <сумма> [комиссия]\n"+
+		"Например: @buyer 15000\nБез комиссии берётся %d%% от суммы.",
+		ad.ID, ad.Title, dealParty(normalizeUsername(ad.Username), ad.UserID), dealDefaultFeePercent)
+	sendDealMessage(bot, chatID, text, [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", fmt.Sprintf("select_ad_%d", ad.ID)),
+		),
+	})
+}
+
+// handleDealTermsInput разбирает «<покупатель> <сумма> [комиссия]» и открывает сделку
+func handleDealTermsInput(bot *tgbotapi.BotAPI, chatID int64, managerID int64, text string, session *adSession) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || len(fields) > 3 {
+		sendText(bot, chatID, "❌ Формат: <@username или Telegram ID> <сумма> [комиссия]")
+		return
+	}
+	amount, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || amount <= 0 {
+		sendText(bot, chatID, "❌ Сумма должна быть положительным целым числом.")
+		return
+	}
+	fee := dealFee(amount)
+	if len(fields) == 3 {
+		fee, err = strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || fee < 0 || fee > amount {
+			sendText(bot, chatID, "❌ Комиссия должна быть целым числом от 0 до суммы сделки.")
+			return
+		}
+	}
+
+	target := parseBlacklistTarget(fields[0])
+	buyerID, buyerUsername := target.TelegramID, target.Username
+	if buyerID == 0 {
+		buyerID, err = profileUserID(target.Username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			sendText(bot, chatID, fmt.Sprintf("❌ Пользователь %s ещё не заходил на биржу. Укажите его Telegram ID.", target))
+			return
+		}
+		if err != nil {
+			log.Printf("Ошибка поиска пользователя %s: %v", target, err)
+			sendText(bot, chatID, "❌ Не удалось найти покупателя.")
+			return
+		}
+	} else {
+		var user models.TelegramUser
+		if err := db.DB.First(&user, "telegram_id = ?", buyerID).Error; err == nil {
+			buyerUsername = user.Username
+		}
+	}
+
+	deal, err := createDeal(session.Ad, buyerID, buyerUsername, amount, fee, managerID)
+	switch {
+	case errors.Is(err, errDealSelf):
+		sendText(bot, chatID, "❌ Покупатель совпадает с продавцом.")
+		return
+	case errors.Is(err, errDealExists):
+		sendText(bot, chatID, "❌ У этого покупателя уже есть открытая сделка по объявлению.")
+		return
+	case errors.Is(err, errDealNoSeller):
+		sendText(bot, chatID, "❌ У объявления не указан Telegram ID автора.")
+		return
+	case err != nil:
+		log.Printf("Ошибка создания сделки по объявлению %d: %v", session.Ad.ID, err)
+		sendText(bot, chatID, "❌ Не удалось открыть сделку.")
+		return
+	}
+	log.Printf("Сделка %d по объявлению %d открыта менеджером %d: покупатель %d, сумма %d", deal.ID, deal.AdID, managerID, deal.BuyerID, deal.Amount)
+
+	session.Stage = stageAwaitAction
+	notifyDealParties(bot, *deal, session.Ad.Title)
+	showDeal(bot, chatID, *deal)
+}
+
+// handleDealTransition обрабатывает deal_to_<ID>_<статус>
+func handleDealTransition(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	idPart, status, ok := strings.Cut(strings.TrimPrefix(data, "deal_to_"), "_")
+	if !ok {
+		return
+	}
+	dealID, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil {
+		return
+	}
+
+	deal, err := transitionDeal(uint(dealID), status, managerID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Сделка не найдена.")
+		return
+	case errors.Is(err, errDealTransition):
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Сделка #%d уже в статусе «%s», действие недоступно.", deal.ID, dealStatusLabels[deal.Status]))
+		return
+	case err != nil:
+		log.Printf("Ошибка смены статуса сделки %d: %v", dealID, err)
+		sendText(bot, chatID, "❌ Не удалось обновить сделку.")
+		return
+	}
+	log.Printf("Сделка %d: статус %s, менеджер %d", deal.ID, deal.Status, managerID)
+
+	notifyDealParties(bot, deal, dealAdTitle(deal.AdID))
+	showDeal(bot, chatID, deal)
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// managerBot — запущенный бот для уведомлений из HTTP-обработчиков и владельцы из MANAGER_ID
+var managerBot = struct {
+	sync.RWMutex
+	api      *tgbotapi.BotAPI
+	ownerIDs []int64
+}{}
+
+func setManagerBot(bot *tgbotapi.BotAPI, ownerIDs []int64) {
+	managerBot.Lock()
+	defer managerBot.Unlock()
+	managerBot.api = bot
+	managerBot.ownerIDs = append([]int64(nil), ownerIDs...)
+}
+
+// isBootstrapOwner — владелец из MANAGER_ID: его роль восстанавливается при каждом старте,
+// поэтому менять её из бота бессмысленно
+func isBootstrapOwner(userID int64) bool {
+	managerBot.RLock()
+	defer managerBot.RUnlock()
+	return isManager(userID, managerBot.ownerIDs)
+}
+
+// notifyManagers отправляет сообщение менеджерам с правом perm; без запущенного бота ничего не делает
+func notifyManagers(perm permission, message string) {
+	managerBot.RLock()
+	bot := managerBot.api
+	managerBot.RUnlock()
+
+	if bot == nil {
+		return
+	}
+	managerIDs, err := managersWith(perm)
+	if err != nil {
+		log.Printf("Ошибка загрузки списка менеджеров: %v", err)
+		return
+	}
+	for _, managerID := range managerIDs {
+		notifyUser(bot, managerID, message)
+	}
+}
+
+func managerLabel(manager models.Manager) string {
+	if manager.Username != "" {
+		return fmt.Sprintf("@%s, ID %d", manager.Username, manager.TelegramID)
+	}
+	return fmt.Sprintf("ID %d", manager.TelegramID)
+}
+
+func showManagers(bot *tgbotapi.BotAPI, chatID int64) {
+	var managers []models.Manager
+	if err := db.DB.Order("created_at ASC").Find(&managers).Error; err != nil {
+		log.Printf("Ошибка загрузки менеджеров: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить список менеджеров.")
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, manager := range managers {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("👤 %s — %s", managerLabel(manager), managerRoleLabels[manager.Role]),
+				fmt.Sprintf("manager_view_%d", manager.TelegramID),
+			),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить менеджера", "manager_add"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_main"),
+		),
+	)
+
+	text := "👥 *Менеджеры*\n\n" +
+		"• Владелец — всё, включая управление менеджерами\n" +
+		"• Модератор — чёрный список и жалобы\n" +
+		"• Редактор объявлений — объявления и модерация\n" +
+		"• Наблюдатель — только просмотр"
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+func startManagerAdd(bot *tgbotapi.BotAPI, chatID int64) {
+	session := &adSession{
+		Stage:         stageAwaitManagerID,
+		LastActivity:  time.Now(),
+		ChatID:        chatID,
+		BotMessageIDs: []int{},
+	}
+	setSession(chatID, session)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_managers"),
+		),
+	)
+	msg := tgbotapi.NewMessage(chatID, "➕ *Новый менеджер*\n\nПерешлите сообщение пользователя или введите его Telegram ID.")
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+	}
+}
+
+func handleManagerIDInput(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	telegramID, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil || telegramID <= 0 {
+		sendText(bot, chatID, "❌ Введите числовой Telegram ID или перешлите сообщение пользователя.")
+		return
+	}
+	showManagerRoles(bot, chatID, telegramID)
+}
+
+// showManagerRoles показывает менеджера (или кандидата) и кнопки выбора роли
+func showManagerRoles(bot *tgbotapi.BotAPI, chatID int64, telegramID int64) {
+	manager := models.Manager{TelegramID: telegramID}
+	exists := db.DB.Where("telegram_id = ?", telegramID).First(&manager).Error == nil
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("👤 *%s*\n\n", escapeMarkdown(managerLabel(manager))))
+	if exists {
+		text.WriteString(fmt.Sprintf("Роль: %s\nДобавлен: %s", managerRoleLabels[manager.Role], manager.CreatedAt.Format("02.01.2006")))
+		if manager.AddedBy != 0 {
+			text.WriteString(fmt.Sprintf(", менеджером ID %d", manager.AddedBy))
+		}
+		text.WriteString("\n\n")
+	}
+	text.WriteString("Выберите роль:")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, role := range managerRoles {
+		label := managerRoleLabels[role]
+		if exists && role == manager.Role {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("manager_role_%d_%s", telegramID, role)),
+		))
+	}
+	if exists {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить из менеджеров", fmt.Sprintf("manager_remove_%d", telegramID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "menu_managers"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+func handleManagerView(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	telegramID, err := strconv.ParseInt(strings.TrimPrefix(data, "manager_view_"), 10, 64)
+	if err != nil {
+		return
+	}
+	showManagerRoles(bot, chatID, telegramID)
+}
+
+// checkManagerChange не даёт владельцу лишить прав себя или владельца из MANAGER_ID
+func checkManagerChange(bot *tgbotapi.BotAPI, chatID int64, actorID, telegramID int64) bool {
+	if telegramID == actorID {
+		sendText(bot, chatID, "❌ Нельзя менять собственную роль.")
+		return false
+	}
+	if isBootstrapOwner(telegramID) {
+		sendText(bot, chatID, "❌ Этот владелец задан в MANAGER_ID, его роль меняется только там.")
+		return false
+	}
+	return true
+}
+
+// handleManagerRoleCallback обрабатывает manager_role_<id>_<роль>
+func handleManagerRoleCallback(bot *tgbotapi.BotAPI, chatID int64, actorID int64, data string) {
+	// Роль сама может содержать "_" (ad_editor), поэтому режем только по первому
+	parts := strings.SplitN(strings.TrimPrefix(data, "manager_role_"), "_", 2)
+	if len(parts) != 2 {
+		return
+	}
+	telegramID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	role := parts[1]
+	if _, ok := rolePermissions[role]; !ok {
+		return
+	}
+	if !checkManagerChange(bot, chatID, actorID, telegramID) {
+		return
+	}
+
+	if err := setManagerRole(actorID, telegramID, role); err != nil {
+		log.Printf("Ошибка назначения роли %s пользователю %d: %v", role, telegramID, err)
+		sendText(bot, chatID, "❌ Не удалось сохранить роль.")
+		return
+	}
+	log.Printf("Менеджер %d назначил пользователю %d роль %s", actorID, telegramID, role)
+
+	notifyUser(bot, telegramID, fmt.Sprintf("👤 Вам назначена роль «%s» в боте биржи. Откройте /menu.", managerRoleLabels[role]))
+	sendText(bot, chatID, fmt.Sprintf("✅ Пользователю ID %d назначена роль «%s».", telegramID, managerRoleLabels[role]))
+	showManagers(bot, chatID)
+}
+
+func handleManagerRemove(bot *tgbotapi.BotAPI, chatID int64, actorID int64, data string) {
+	telegramID, err := strconv.ParseInt(strings.TrimPrefix(data, "manager_remove_"), 10, 64)
+	if err != nil {
+		return
+	}
+	if !checkManagerChange(bot, chatID, actorID, telegramID) {
+		return
+	}
+
+	removed, err := removeManager(actorID, telegramID)
+	if err != nil {
+		log.Printf("Ошибка удаления менеджера %d: %v", telegramID, err)
+		sendText(bot, chatID, "❌ Не удалось удалить менеджера.")
+		return
+	}
+	if removed {
+		log.Printf("Менеджер %d удалил менеджера %d", actorID, telegramID)
+		clearSession(telegramID)
+		sendText(bot, chatID, fmt.Sprintf("✅ Пользователь ID %d больше не менеджер.", telegramID))
+	}
+	showManagers(bot, chatID)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+const defaultModerationDays = 7
+
+func pendingAdsCount() (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Ad{}).Where("status = ?", models.AdStatusPending).Count(&count).Error
+	return count, err
+}
+
+// showModerationQueue показывает объявление из очереди модерации на позиции page
+// (очередь упорядочена по времени подачи, самые старые — первыми)
+func showModerationQueue(bot *tgbotapi.BotAPI, chatID int64, page int) {
+	total, err := pendingAdsCount()
+	if err != nil {
+		log.Printf("Ошибка подсчёта очереди модерации: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить очередь модерации.")
+		return
+	}
+
+	session := getSession(chatID)
+	if session == nil {
+		session = &adSession{
+			LastActivity:  time.Now(),
+			ChatID:        chatID,
+			BotMessageIDs: []int{},
+		}
+		setSession(chatID, session)
+	}
+
+	if total == 0 {
+		session.Stage = stageNone
+		session.ModerationTotal = 0
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+			),
+		)
+		msg := tgbotapi.NewMessage(chatID, "🕓 *Очередь модерации пуста*")
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = keyboard
+		sentMsg, err := bot.Send(msg)
+		if err == nil {
+			addBotMessage(chatID, sentMsg.MessageID)
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+		return
+	}
+
+	if page < 0 {
+		page = 0
+	}
+	if int64(page) >= total {
+		page = int(total) - 1
+	}
+
+	var ad models.Ad
+	if err := db.DB.Where("status = ?", models.AdStatusPending).
+		Order("created_at ASC, id ASC").
+		Offset(page).
+		First(&ad).Error; err != nil {
+		log.Printf("Ошибка загрузки объявления из очереди модерации: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить очередь модерации.")
+		return
+	}
+
+	session.Ad = ad
+	session.Operation = opEdit
+	session.Stage = stageAwaitAction
+	session.DurationDays = 0
+	session.LastActivity = time.Now()
+	session.ModerationPage = page
+	session.ModerationTotal = int(total)
+
+	showAdDetailsWithActions(bot, chatID, ad)
+}
+
+func handleModerationPage(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	page, err := strconv.Atoi(strings.TrimPrefix(data, "moderation_page_"))
+	if err != nil {
+		sendText(bot, chatID, "❌ Неверная страница.")
+		return
+	}
+	showModerationQueue(bot, chatID, page)
+}
+
+// handleModerationApprove публикует объявление на запрошенный пользователем срок
+func handleModerationApprove(bot *tgbotapi.BotAPI, chatID int64, managerID int64) {
+	session := getSession(chatID)
+	if session == nil || session.Ad.ID == 0 {
+		return
+	}
+
+	// Перечитываем объявление: пользователь мог изменить его, пока менеджер смотрел
+	var ad models.Ad
+	if err := db.DB.First(&ad, session.Ad.ID).Error; err != nil {
+		sendText(bot, chatID, "❌ Объявление не найдено.")
+		return
+	}
+	if ad.Status != models.AdStatusPending {
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Объявление #%d уже обработано.", ad.ID))
+		showModerationQueue(bot, chatID, session.ModerationPage)
+		return
+	}
+
+	days := ad.RequestedDays
+	if !isValidDuration(days) {
+		days = defaultModerationDays
+	}
+
+	before := ad
+	ad.Status = models.AdStatusActive
+	ad.PreExpiryNotified = false
+	ad.RejectReason = ""
+	ad.ExpiresAt = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&ad).Error; err != nil {
+			return err
+		}
+		if err := saveAdRevision(tx, ad, managerID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditAdApprove, auditEntityAd, ad.ID, before, ad)
+	}); err != nil {
+		log.Printf("Ошибка одобрения объявления %d: %v", ad.ID, err)
+		sendText(bot, chatID, "❌ Не удалось опубликовать объявление.")
+		return
+	}
+	log.Printf("Объявление %d одобрено менеджером (чат %d) на %d дн.", ad.ID, chatID, days)
+
+	notifyUser(bot, ad.UserID, fmt.Sprintf("✅ Ваше объявление «%s» прошло модерацию и опубликовано до %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006")))
+	go notifySubscribers(bot, ad)
+	// Объявление могло быть в избранном до того, как автор отправил правки на модерацию
+	go notifyFavorites(bot, ad, favoriteEventEdited)
+
+	sendText(bot, chatID, fmt.Sprintf("✅ Объявление #%d опубликовано до %s.", ad.ID, ad.ExpiresAt.Format("02.01.2006 15:04")))
+	showModerationQueue(bot, chatID, session.ModerationPage)
+}
+
+func handleModerationReject(bot *tgbotapi.BotAPI, chatID int64) {
+	session := getSession(chatID)
+	if session == nil || session.Ad.ID == 0 {
+		return
+	}
+
+	session.Stage = stageAwaitRejectReason
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", fmt.Sprintf("moderation_page_%d", session.ModerationPage)),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("🚫 *Отклонение объявления #%d*\n\nНапишите причину — она будет отправлена автору.", session.Ad.ID))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+	}
+}
+
+func handleRejectReasonInput(bot *tgbotapi.BotAPI, chatID int64, managerID int64, text string, session *adSession) {
+	reason := truncate(strings.TrimSpace(text), 512)
+	if reason == "" {
+		sendText(bot, chatID, "❌ Причина не может быть пустой.")
+		return
+	}
+
+	var rejected int64
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		changes := map[string]interface{}{
+			"status":              models.AdStatusInactive,
+			"reject_reason":       reason,
+			"pre_expiry_notified": false,
+		}
+		result := tx.Model(&models.Ad{}).
+			Where("id = ? AND status = ?", session.Ad.ID, models.AdStatusPending).
+			Updates(changes)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		rejected = result.RowsAffected
+		var ad models.Ad
+		if err := tx.First(&ad, session.Ad.ID).Error; err != nil {
+			return err
+		}
+		if err := saveAdRevision(tx, ad, managerID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditAdReject, auditEntityAd, session.Ad.ID,
+			map[string]interface{}{"status": models.AdStatusPending}, changes)
+	})
+	if err != nil {
+		log.Printf("Ошибка отклонения объявления %d: %v", session.Ad.ID, err)
+		sendText(bot, chatID, "❌ Не удалось отклонить объявление.")
+		return
+	}
+	if rejected == 0 {
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Объявление #%d уже обработано.", session.Ad.ID))
+		showModerationQueue(bot, chatID, session.ModerationPage)
+		return
+	}
+	log.Printf("Объявление %d отклонено менеджером (чат %d): %s", session.Ad.ID, chatID, reason)
+
+	notifyUser(bot, session.Ad.UserID, fmt.Sprintf("🚫 Ваше объявление «%s» не прошло модерацию.\n\nПричина: %s\n\nИсправьте объявление и отправьте его снова или свяжитесь с %s.", session.Ad.Title, reason, managerHelpLink))
+
+	sendText(bot, chatID, fmt.Sprintf("🚫 Объявление #%d отклонено.", session.Ad.ID))
+	showModerationQueue(bot, chatID, session.ModerationPage)
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// paymentsListLimit — сколько последних платежей показывает /payments
+const paymentsListLimit = 20
+
+var paymentStatusLabels = map[string]string{
+	models.PaymentStatusPending:  "ожидает оплаты",
+	models.PaymentStatusPaid:     "оплачен",
+	models.PaymentStatusFailed:   "оплачен, не применён",
+	models.PaymentStatusRefunded: "возвращён",
+}
+
+// handlePreCheckoutQuery подтверждает оплату, только если счёт актуален и покупку ещё можно применить.
+// Telegram ждёт ответа не дольше 10 секунд.
+func handlePreCheckoutQuery(bot *tgbotapi.BotAPI, query *tgbotapi.PreCheckoutQuery) {
+	err := validatePreCheckout(query)
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: query.ID, OK: err == nil}
+	if err != nil {
+		log.Printf("Оплата %q отклонена: %v", query.InvoicePayload, err)
+		answer.ErrorMessage = paymentErrorMessage(err)
+	}
+	if _, err := bot.Request(answer); err != nil {
+		log.Printf("answerPreCheckoutQuery failed: %v", err)
+	}
+}
+
+func validatePreCheckout(query *tgbotapi.PreCheckoutQuery) error {
+	paymentID, ok := parsePaymentPayload(query.InvoicePayload)
+	if !ok || query.From == nil {
+		return errPaymentMismatch
+	}
+
+	var payment models.Payment
+	if err := db.DB.First(&payment, paymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errPaymentMismatch
+		}
+		return err
+	}
+	if payment.Status != models.PaymentStatusPending {
+		return errPaymentAlreadyDone
+	}
+	if !paymentMatches(payment, query.From.ID, query.Currency, query.TotalAmount) {
+		return errPaymentMismatch
+	}
+
+	var ad models.Ad
+	if err := db.DB.First(&ad, payment.AdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errPaymentAdUnavailable
+		}
+		return err
+	}
+	return checkPaymentAllowed(db.DB, ad, payment)
+}
+
+// paymentMatches сверяет плательщика, валюту и сумму из Telegram с выставленным счётом
+func paymentMatches(payment models.Payment, payerID int64, currency string, amount int) bool {
+	return payment.UserID == payerID && payment.Currency == currency && payment.Amount == amount
+}
+
+// handleSuccessfulPayment применяет оплаченную покупку. Если за время оплаты покупка стала
+// невозможной (например, заняли последнее премиум-место), звёзды сразу возвращаются.
+func handleSuccessfulPayment(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	paid := msg.SuccessfulPayment
+	paymentID, ok := parsePaymentPayload(paid.InvoicePayload)
+	if !ok {
+		log.Printf("Оплата с неизвестным payload %q от %d, charge %s", paid.InvoicePayload, msg.Chat.ID, paid.TelegramPaymentChargeID)
+		return
+	}
+
+	payerID := msg.Chat.ID
+	if msg.From != nil {
+		payerID = msg.From.ID
+	}
+
+	var (
+		payment  models.Payment
+		ad       models.Ad
+		applyErr error
+	)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+			return err
+		}
+		// Повторная доставка того же обновления
+		if payment.Status != models.PaymentStatusPending {
+			return errPaymentAlreadyDone
+		}
+		// pre_checkout уже сверял счёт, но применяем только то, что действительно оплачено
+		if !paymentMatches(payment, payerID, paid.Currency, paid.TotalAmount) {
+			return errPaymentMismatch
+		}
+
+		now := time.Now()
+		payment.TelegramChargeID = paid.TelegramPaymentChargeID
+		payment.ProviderChargeID = paid.ProviderPaymentChargeID
+		payment.PaidAt = &now
+		payment.Status = models.PaymentStatusFailed
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ad, payment.AdID).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			applyErr = errPaymentAdUnavailable
+		case err != nil:
+			return err
+		default:
+			if payment.Product == models.PaymentProductPremium {
+				if err := lockPremiumCategory(tx, ad.Category); err != nil {
+					return err
+				}
+			}
+			applyErr = checkPaymentAllowed(tx, ad, payment)
+		}
+
+		if applyErr == nil {
+			before := ad
+			applyPayment(&ad, payment)
+			if err := tx.Save(&ad).Error; err != nil {
+				return err
+			}
+			if err := saveAdRevision(tx, ad, payment.UserID, nil); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, payment.UserID, auditAdPayment, auditEntityAd, ad.ID, before, ad); err != nil {
+				return err
+			}
+			payment.Status = models.PaymentStatusPaid
+		}
+		return tx.Save(&payment).Error
+	})
+	if errors.Is(err, errPaymentAlreadyDone) {
+		return
+	}
+	if errors.Is(err, errPaymentMismatch) {
+		// Счёт не трогаем: списание не соответствует ему, звёзды возвращаем тому, кто заплатил
+		log.Printf("Оплата %d не совпадает со счётом: от %d, %d %s, charge %s", paymentID, payerID, paid.TotalAmount, paid.Currency, paid.TelegramPaymentChargeID)
+		if err := refundStarCharge(bot, payerID, paid.TelegramPaymentChargeID); err != nil {
+			log.Printf("Ошибка возврата несовпавшей оплаты %d: %v", paymentID, err)
+			notifyManagers(permManagePayments, fmt.Sprintf("⚠️ Оплата по счёту #%d не совпала со счётом и не возвращена: от ID %d, %d %s, charge %s", paymentID, payerID, paid.TotalAmount, paid.Currency, paid.TelegramPaymentChargeID))
+			return
+		}
+		notifyUser(bot, msg.Chat.ID, "↩️ Оплата не совпала со счётом, звёзды возвращены. Запросите новый счёт.")
+		return
+	}
+	if err != nil {
+		// Деньги списаны, а запись не сохранилась: без charge id вернуть звёзды можно только вручную
+		log.Printf("Ошибка сохранения оплаты %d (charge %s): %v", paymentID, paid.TelegramPaymentChargeID, err)
+		notifyUser(bot, msg.Chat.ID, fmt.Sprintf("⚠️ Оплата получена, но не применилась. Напишите %s — мы всё исправим.", managerHelpLink))
+		notifyManagers(permManagePayments, fmt.Sprintf("⚠️ Не удалось сохранить оплату #%d, charge %s: %v", paymentID, paid.TelegramPaymentChargeID, err))
+		return
+	}
+
+	if applyErr != nil {
+		log.Printf("Оплата %d не применена (%v), возвращаем звёзды", payment.ID, applyErr)
+		if _, err := refundPayment(bot, payment.ID, 0); err != nil {
+			log.Printf("Ошибка автоматического возврата оплаты %d: %v", payment.ID, err)
+			notifyUser(bot, payment.UserID, fmt.Sprintf("⚠️ Оплату не удалось применить: %s. Звёзды вернёт менеджер %s.", paymentErrorMessage(applyErr), managerHelpLink))
+			notifyManagers(permManagePayments, fmt.Sprintf("⚠️ Оплата #%d не применена и не возвращена автоматически. Верните вручную: /refund %d", payment.ID, payment.ID))
+			return
+		}
+		notifyUser(bot, payment.UserID, fmt.Sprintf("↩️ Оплату не удалось применить: %s. Звёзды возвращены.", paymentErrorMessage(applyErr)))
+		return
+	}
+
+	log.Printf("Оплата %d применена: %s, объявление %d, %d XTR", payment.ID, payment.Product, ad.ID, payment.Amount)
+	if payment.Product == models.PaymentProductPremium {
+		notifyUser(bot, payment.UserID, fmt.Sprintf("⭐ Объявление «%s» теперь в премиуме до %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006")))
+	} else {
+		notifyUser(bot, payment.UserID, fmt.Sprintf("✅ Объявление «%s» продлено до %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006")))
+		go notifyFavorites(bot, ad, favoriteEventRenewed)
+	}
+	notifyManagers(permManagePayments, fmt.Sprintf("💫 Оплата #%d: %s по объявлению #%d, %d ⭐", payment.ID, paymentTitle(payment), ad.ID, payment.Amount))
+}
+
+// handleRefundCommand обрабатывает /refund <ID платежа>
+func handleRefundCommand(bot *tgbotapi.BotAPI, chatID int64, managerID int64, text string) {
+	arg := strings.TrimPrefix(strings.TrimSpace(text[len(commandRefund):]), "#")
+	paymentID, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || paymentID == 0 {
+		sendText(bot, chatID, "Использование: /refund <ID платежа>. Список платежей — /payments")
+		return
+	}
+
+	payment, err := refundPayment(bot, uint(paymentID), managerID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Платёж не найден.")
+		return
+	case errors.Is(err, errPaymentAlreadyDone):
+		sendText(bot, chatID, "ℹ️ Этот платёж нельзя вернуть: он не оплачен или уже возвращён.")
+		return
+	case err != nil:
+		log.Printf("Ошибка возврата платежа %d: %v", paymentID, err)
+		sendText(bot, chatID, fmt.Sprintf("❌ Не удалось вернуть платёж: %v", err))
+		return
+	}
+	log.Printf("Платёж %d возвращён менеджером %d", payment.ID, managerID)
+
+	notifyUser(bot, payment.UserID, fmt.Sprintf("↩️ Оплата «%s» по объявлению #%d возвращена, звёзды зачислены обратно.", paymentTitle(*payment), payment.AdID))
+	sendText(bot, chatID, fmt.Sprintf("✅ Платёж #%d возвращён: %d ⭐ пользователю ID %d.", payment.ID, payment.Amount, payment.UserID))
+}
+
+// handlePaymentsCommand показывает последние платежи
+func handlePaymentsCommand(bot *tgbotapi.BotAPI, chatID int64) {
+	var payments []models.Payment
+	if err := db.DB.Where("status <> ?", models.PaymentStatusPending).
+		Order("id DESC").
+		Limit(paymentsListLimit).
+		Find(&payments).Error; err != nil {
+		log.Printf("Ошибка загрузки платежей: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить платежи.")
+		return
+	}
+	if len(payments) == 0 {
+		sendText(bot, chatID, "💫 Оплат пока не было.")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("💫 Последние платежи:\n")
+	for _, payment := range payments {
+		paidAt := ""
+		if payment.PaidAt != nil {
+			paidAt = payment.PaidAt.Format("02.01.2006 15:04")
+		}
+		fmt.Fprintf(&b, "\n#%d · %s · %s · объявление #%d · ID %d · %d ⭐ · %s",
+			payment.ID, paidAt, paymentTitle(payment), payment.AdID, payment.UserID, payment.Amount, paymentStatusLabels[payment.Status])
+	}
+	b.WriteString("\n\nВозврат: /refund <ID платежа>")
+	sendText(bot, chatID, b.String())
+}
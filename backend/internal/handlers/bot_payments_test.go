@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestPaymentMatches(t *testing.T) {
+	payment := models.Payment{UserID: 1001, Currency: paymentCurrency, Amount: 250}
+
+	tests := []struct {
+		name     string
+		payerID  int64
+		currency string
+		amount   int
+		want     bool
+	}{
+		{"same payer, currency and amount", 1001, paymentCurrency, 250, true},
+		{"another payer", 2002, paymentCurrency, 250, false},
+		{"another currency", 1001, "USD", 250, false},
+		{"smaller amount", 1001, paymentCurrency, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paymentMatches(payment, tt.payerID, tt.currency, tt.amount); got != tt.want {
+				t.Fatalf("paymentMatches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// botAPICall — запрос к заглушке Bot API
+type botAPICall struct {
+	method string
+	params map[string]string
+}
+
+// fakeBotAPI отвечает на запросы бота как Bot API и запоминает их
+type fakeBotAPI struct {
+	mu    sync.Mutex
+	calls []botAPICall
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	params := make(map[string]string, len(r.PostForm))
+	for key := range r.PostForm {
+		params[key] = r.PostForm.Get(key)
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, botAPICall{method: method, params: params})
+	f.mu.Unlock()
+
+	result := `true`
+	switch method {
+	case "getMe":
+		result = `{"id":1,"is_bot":true,"first_name":"Market","username":"market_test_bot"}`
+	case "sendMessage":
+		result = fmt.Sprintf(`{"message_id":1,"date":%d,"chat":{"id":%s,"type":"private"}}`, time.Now().Unix(), params["chat_id"])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+}
+
+func (f *fakeBotAPI) callsOf(method string) []botAPICall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []botAPICall
+	for _, call := range f.calls {
+		if call.method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// openPaymentsTestDB подключается к TEST_DATABASE_URL и применяет миграции. Без базы тест пропускается.
+func openPaymentsTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	t.Setenv("DATABASE_URL", dsn)
+	t.Setenv("DB_MIGRATE_ON_START", "true")
+	t.Setenv("GIN_MODE", "release")
+
+	previous := db.DB
+	if err := db.Init(); err != nil {
+		t.Fatalf("db.Init: %v", err)
+	}
+	t.Cleanup(func() { db.DB = previous })
+}
+
+func reloadPayment(t *testing.T, id uint) models.Payment {
+	t.Helper()
+	var payment models.Payment
+	if err := db.DB.First(&payment, id).Error; err != nil {
+		t.Fatalf("load payment %d: %v", id, err)
+	}
+	return payment
+}
+
+func reloadAd(t *testing.T, id uint) models.Ad {
+	t.Helper()
+	var ad models.Ad
+	if err := db.DB.First(&ad, id).Error; err != nil {
+		t.Fatalf("load ad %d: %v", id, err)
+	}
+	return ad
+}
+
+// Полный путь оплаты через заглушку Bot API: счёт → pre_checkout → successful_payment
+// (в том числе повторный и не совпавший со счётом) → /refund
+func TestStarsPaymentFlow(t *testing.T) {
+	openPaymentsTestDB(t)
+
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint("test-token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatalf("NewBotAPIWithAPIEndpoint: %v", err)
+	}
+
+	const (
+		ownerID    int64 = 910001
+		strangerID int64 = 910002
+		managerID  int64 = 910003
+	)
+	ad := models.Ad{
+		UserID:    ownerID,
+		Username:  "pay_flow_owner",
+		Title:     "Канал для теста оплаты",
+		Category:  fmt.Sprintf("paytest-%d", time.Now().UnixNano()),
+		Status:    models.AdStatusActive,
+		ExpiresAt: time.Now().Add(72 * time.Hour),
+	}
+	if err := db.DB.Create(&ad).Error; err != nil {
+		t.Fatalf("create ad: %v", err)
+	}
+	payment := models.Payment{
+		AdID:     ad.ID,
+		UserID:   ownerID,
+		Product:  models.PaymentProductPremium,
+		Amount:   250,
+		Currency: paymentCurrency,
+		Status:   models.PaymentStatusPending,
+	}
+	if err := db.DB.Create(&payment).Error; err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+	t.Cleanup(func() {
+		db.DB.Unscoped().Where("ad_id = ?", ad.ID).Delete(&models.AdRevision{})
+		db.DB.Where("id = ?", payment.ID).Delete(&models.Payment{})
+		db.DB.Unscoped().Delete(&models.Ad{}, ad.ID)
+	})
+
+	// 1. Счёт
+	if err := sendStarsInvoice(bot, payment, ad); err != nil {
+		t.Fatalf("sendStarsInvoice: %v", err)
+	}
+	invoices := api.callsOf("sendInvoice")
+	if len(invoices) != 1 {
+		t.Fatalf("sendInvoice calls = %d, want 1", len(invoices))
+	}
+	payload := invoices[0].params["payload"]
+	if payload != paymentPayload(payment.ID) || invoices[0].params["currency"] != paymentCurrency {
+		t.Fatalf("unexpected invoice params: %v", invoices[0].params)
+	}
+
+	// 2. pre_checkout: чужой плательщик отклоняется, владелец — подтверждается
+	handlePreCheckoutQuery(bot, &tgbotapi.PreCheckoutQuery{
+		ID: "q-stranger", From: &tgbotapi.User{ID: strangerID}, Currency: paymentCurrency, TotalAmount: 250, InvoicePayload: payload,
+	})
+	handlePreCheckoutQuery(bot, &tgbotapi.PreCheckoutQuery{
+		ID: "q-owner", From: &tgbotapi.User{ID: ownerID}, Currency: paymentCurrency, TotalAmount: 250, InvoicePayload: payload,
+	})
+	answers := api.callsOf("answerPreCheckoutQuery")
+	if len(answers) != 2 {
+		t.Fatalf("answerPreCheckoutQuery calls = %d, want 2", len(answers))
+	}
+	if answers[0].params["ok"] != "false" || answers[1].params["ok"] != "true" {
+		t.Fatalf("pre_checkout answers = %v / %v, want false / true", answers[0].params, answers[1].params)
+	}
+
+	successful := func(from int64, amount int, chargeID string) *tgbotapi.Message {
+		return &tgbotapi.Message{
+			From: &tgbotapi.User{ID: from},
+			Chat: &tgbotapi.Chat{ID: from},
+			SuccessfulPayment: &tgbotapi.SuccessfulPayment{
+				Currency:                paymentCurrency,
+				TotalAmount:             amount,
+				InvoicePayload:          payload,
+				TelegramPaymentChargeID: chargeID,
+				ProviderPaymentChargeID: chargeID + "-provider",
+			},
+		}
+	}
+
+	// 3. successful_payment с суммой не по счёту не применяется, звёзды уходят обратно плательщику
+	handleSuccessfulPayment(bot, successful(ownerID, 1, "charge-wrong-amount"))
+	if got := reloadPayment(t, payment.ID); got.Status != models.PaymentStatusPending || got.TelegramChargeID != "" {
+		t.Fatalf("mismatched payment changed the invoice: status %s, charge %q", got.Status, got.TelegramChargeID)
+	}
+	refunds := api.callsOf("refundStarPayment")
+	if len(refunds) != 1 || refunds[0].params["telegram_payment_charge_id"] != "charge-wrong-amount" ||
+		refunds[0].params["user_id"] != fmt.Sprint(ownerID) {
+		t.Fatalf("mismatched payment refunds = %v", refunds)
+	}
+
+	// 4. Настоящая оплата и её повторная доставка
+	handleSuccessfulPayment(bot, successful(ownerID, 250, "charge-ok"))
+	paid := reloadPayment(t, payment.ID)
+	if paid.Status != models.PaymentStatusPaid || paid.TelegramChargeID != "charge-ok" || paid.PaidAt == nil {
+		t.Fatalf("payment after successful_payment: status %s, charge %q", paid.Status, paid.TelegramChargeID)
+	}
+	if !reloadAd(t, ad.ID).IsPremium {
+		t.Fatal("ad must be premium after payment")
+	}
+
+	var revisions int64
+	db.DB.Model(&models.AdRevision{}).Where("ad_id = ?", ad.ID).Count(&revisions)
+	messages := len(api.callsOf("sendMessage"))
+
+	handleSuccessfulPayment(bot, successful(ownerID, 250, "charge-ok"))
+	if again := reloadPayment(t, payment.ID); again.Status != models.PaymentStatusPaid || !again.PaidAt.Equal(*paid.PaidAt) {
+		t.Fatalf("duplicate successful_payment changed the payment: %+v", again)
+	}
+	var revisionsAfter int64
+	db.DB.Model(&models.AdRevision{}).Where("ad_id = ?", ad.ID).Count(&revisionsAfter)
+	if revisionsAfter != revisions {
+		t.Fatalf("duplicate successful_payment applied the purchase again: revisions %d → %d", revisions, revisionsAfter)
+	}
+	if got := len(api.callsOf("sendMessage")); got != messages {
+		t.Fatalf("duplicate successful_payment notified the user again: %d → %d messages", messages, got)
+	}
+
+	// 5. Возврат менеджером, повторный /refund ничего не возвращает
+	handleRefundCommand(bot, managerID, managerID, fmt.Sprintf("%s %d", commandRefund, payment.ID))
+	refunded := reloadPayment(t, payment.ID)
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundedBy != managerID {
+		t.Fatalf("payment after /refund: status %s, refunded by %d", refunded.Status, refunded.RefundedBy)
+	}
+	if reloadAd(t, ad.ID).IsPremium {
+		t.Fatal("ad must lose premium after refund")
+	}
+	handleRefundCommand(bot, managerID, managerID, fmt.Sprintf("%s %d", commandRefund, payment.ID))
+
+	refunds = api.callsOf("refundStarPayment")
+	if len(refunds) != 2 {
+		t.Fatalf("refundStarPayment calls = %d, want 2", len(refunds))
+	}
+	if refunds[1].params["telegram_payment_charge_id"] != "charge-ok" || refunds[1].params["user_id"] != fmt.Sprint(ownerID) {
+		t.Fatalf("refund params = %v", refunds[1].params)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxAdPhotos — сколько фото можно приложить к объявлению (как в одном альбоме Telegram)
+const maxAdPhotos = 10
+
+// startPhotoStage открывает шаг с фото. При редактировании в черновик загружается текущая галерея.
+func startPhotoStage(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
+	session.Stage = stageAwaitPhoto
+	session.Photos = nil
+	session.PhotosEdited = true
+
+	if session.Operation == opEdit && session.Ad.ID != 0 {
+		photos, err := loadAdPhotos(session.Ad.ID)
+		if err != nil {
+			log.Printf("Ошибка загрузки фото объявления %d: %v", session.Ad.ID, err)
+			// Не даём перезаписать галерею, которую не удалось прочитать
+			session.PhotosEdited = false
+		}
+		session.Photos = photos
+	}
+
+	showPhotoPrompt(bot, chatID, session)
+}
+
+func photoPromptContent(session *adSession) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := fmt.Sprintf("📸 *Шаг 1: Фото*\n\nОтправьте до %d фото альбомом или по одному: баннер, скриншоты аналитики, подтверждение дохода. Первое фото станет обложкой.", maxAdPhotos)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(session.Photos) > 0 {
+		text += fmt.Sprintf("\n\nДобавлено фото: %d/%d. Когда закончите, нажмите «Готово».", len(session.Photos), maxAdPhotos)
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ Готово", "photos_done"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить все фото", "photos_clear"),
+			),
+		)
+	} else {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Пропустить", "skip_photo"),
+		))
+	}
+
+	if session.Operation == opEdit {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", getBackCallback(session)),
+		))
+	} else {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("◀️ Отмена", "menu_main"),
+		))
+	}
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func showPhotoPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
+	text, keyboard := photoPromptContent(session)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = keyboard
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		session.PhotoPromptID = sentMsg.MessageID
+		addBotMessage(chatID, sentMsg.MessageID)
+		go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+	}
+}
+
+// refreshPhotoPrompt обновляет счётчик в уже отправленной подсказке, чтобы альбом
+// из нескольких фото не порождал по сообщению на каждое фото
+func refreshPhotoPrompt(bot *tgbotapi.BotAPI, chatID int64, session *adSession) {
+	if session.PhotoPromptID != 0 {
+		text, keyboard := photoPromptContent(session)
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, session.PhotoPromptID, text, keyboard)
+		edit.ParseMode = "Markdown"
+		if _, err := bot.Send(edit); err == nil {
+			return
+		}
+	}
+	showPhotoPrompt(bot, chatID, session)
+}
+
+func handlePhotoStage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, session *adSession) {
+	if len(msg.Photo) == 0 {
+		sendText(bot, msg.Chat.ID, "❌ Отправьте фото или нажмите «Готово» / «Пропустить».")
+		return
+	}
+	if len(session.Photos) >= maxAdPhotos {
+		sendText(bot, msg.Chat.ID, fmt.Sprintf("❌ Можно приложить не более %d фото. Нажмите «Готово».", maxAdPhotos))
+		return
+	}
+
+	photo := msg.Photo[len(msg.Photo)-1]
+	// Ключ в хранилище появится после фоновой загрузки; до тех пор фото отдаётся по FileID
+	session.Photos = append(session.Photos, models.AdPhoto{FileID: photo.FileID})
+	go prefetchAdPhoto(photo.FileID)
+	refreshPhotoPrompt(bot, msg.Chat.ID, session)
+}
+
+// adPhotoPrefetchTTL — сколько помнить ключ загруженного фото, пока объявление не сохранено
+const adPhotoPrefetchTTL = time.Hour
+
+type prefetchedAdPhoto struct {
+	key      string
+	storedAt time.Time
+}
+
+// prefetchedAdPhotos — ключи фото, уже загруженных в хранилище, по file_id
+var prefetchedAdPhotos = struct {
+	sync.Mutex
+	keys map[string]prefetchedAdPhoto
+}{keys: make(map[string]prefetchedAdPhoto)}
+
+// prefetchAdPhoto скачивает фото из Telegram в хранилище вне цикла обновлений бота:
+// загрузка может занять до минуты, а pre_checkout_query и остальные обновления ждать не могут
+func prefetchAdPhoto(fileID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	key, err := fetchAdPhoto(ctx, fileID)
+	if err != nil {
+		// Не страшно: Mini App скачает фото по FileID при первом показе
+		log.Printf("Не удалось заранее сохранить фото %s: %v", fileID, err)
+		return
+	}
+
+	now := time.Now()
+	prefetchedAdPhotos.Lock()
+	for id, photo := range prefetchedAdPhotos.keys {
+		if now.Sub(photo.storedAt) > adPhotoPrefetchTTL {
+			delete(prefetchedAdPhotos.keys, id)
+		}
+	}
+	prefetchedAdPhotos.keys[fileID] = prefetchedAdPhoto{key: key, storedAt: now}
+	prefetchedAdPhotos.Unlock()
+
+	// Объявление могли сохранить раньше, чем закончилась загрузка
+	if err := db.DB.Model(&models.AdPhoto{}).Where("file_id = ? AND key = ?", fileID, "").Update("key", key).Error; err != nil {
+		log.Printf("Не удалось сохранить ключ фото %s: %v", fileID, err)
+	}
+}
+
+// prefetchedAdPhotoKey возвращает ключ фото, если фоновая загрузка уже закончилась
+func prefetchedAdPhotoKey(fileID string) string {
+	prefetchedAdPhotos.Lock()
+	defer prefetchedAdPhotos.Unlock()
+	return prefetchedAdPhotos.keys[fileID].key
+}
+
+// handlePhotosDone завершает шаг с фото; «Пропустить» делает то же самое без фото
+func handlePhotosDone(bot *tgbotapi.BotAPI, chatID int64) {
+	session := getSession(chatID)
+	if session == nil || session.Stage != stageAwaitPhoto {
+		return
+	}
+
+	session.Stage = stageAwaitTitle
+	showTitlePrompt(bot, chatID, session)
+}
+
+func handlePhotosClear(bot *tgbotapi.BotAPI, chatID int64) {
+	session := getSession(chatID)
+	if session == nil || session.Stage != stageAwaitPhoto {
+		return
+	}
+
+	session.Photos = nil
+	session.PhotosEdited = true
+	refreshPhotoPrompt(bot, chatID, session)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"youtube-market/internal/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// premiumQueueListLimit — сколько заявок очереди каждой категории показывает /slots
+const premiumQueueListLimit = 10
+
+// categoryOrder — порядок категорий в /slots
+var categoryOrder = []string{"services", "buysell", "other"}
+
+func formatPremiumQueueStatus(status premiumQueueStatus) string {
+	text := fmt.Sprintf("⏳ В очереди на премиум: %d-е место", status.Position)
+	if status.StartsAt != nil {
+		text += fmt.Sprintf(", ориентировочно с %s", status.StartsAt.Format("02.01.2006 15:04"))
+	}
+	return text
+}
+
+// handlePremiumWaitlist ставит объявление в очередь на премиум ("premium_waitlist_<ID>")
+func handlePremiumWaitlist(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	adID, err := strconv.ParseUint(strings.TrimPrefix(data, "premium_waitlist_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	_, err = enqueuePremium(uint(adID), managerID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Объявление не найдено.")
+		return
+	case errors.Is(err, errPremiumQueued):
+		sendText(bot, chatID, "ℹ️ Объявление уже в очереди на премиум.")
+		return
+	case errors.Is(err, errPremiumNotEligible):
+		sendText(bot, chatID, "⚠️ В очередь ставятся только активные объявления без премиума.")
+		return
+	case err != nil:
+		log.Printf("Ошибка постановки объявления %d в очередь на премиум: %v", adID, err)
+		sendText(bot, chatID, "❌ Не удалось поставить объявление в очередь.")
+		return
+	}
+	log.Printf("Объявление %d поставлено в очередь на премиум менеджером %d", adID, managerID)
+
+	// Если место уже свободно, объявление поднимется сразу
+	promotePremiumWaitlist(bot)
+	handleSelectAd(bot, chatID, fmt.Sprintf("select_ad_%d", adID))
+}
+
+// handlePremiumUnqueue убирает объявление из очереди на премиум ("premium_unqueue_<ID>")
+func handlePremiumUnqueue(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	adID, err := strconv.ParseUint(strings.TrimPrefix(data, "premium_unqueue_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	if err := cancelPremiumBooking(uint(adID), managerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			sendText(bot, chatID, "ℹ️ Объявления уже нет в очереди.")
+		} else {
+			log.Printf("Ошибка снятия объявления %d из очереди на премиум: %v", adID, err)
+			sendText(bot, chatID, "❌ Не удалось убрать объявление из очереди.")
+		}
+		return
+	}
+	log.Printf("Объявление %d убрано из очереди на премиум менеджером %d", adID, managerID)
+
+	handleSelectAd(bot, chatID, fmt.Sprintf("select_ad_%d", adID))
+}
+
+// handleSlotsCommand обрабатывает /slots — занятость премиум-мест и очереди по категориям —
+// и /slots <категория> <лимит> для владельцев
+func handleSlotsCommand(bot *tgbotapi.BotAPI, chatID int64, managerID int64, text string, canEdit bool) {
+	args := strings.Fields(strings.TrimSpace(text[len(commandSlots):]))
+	if len(args) == 0 {
+		showPremiumSlots(bot, chatID)
+		return
+	}
+
+	if !canEdit {
+		sendText(bot, chatID, "⛔ Недостаточно прав для изменения лимитов премиум-мест.")
+		return
+	}
+	usage := fmt.Sprintf("Использование: /slots <категория> <лимит от 0 до %d>. Категории: %s", maxPremiumSlotLimit, strings.Join(categoryOrder, ", "))
+	if len(args) != 2 {
+		sendText(bot, chatID, usage)
+		return
+	}
+	category, ok := parseCategoryArg(args[0])
+	limit, err := strconv.Atoi(args[1])
+	if !ok || err != nil || limit < 0 || limit > maxPremiumSlotLimit {
+		sendText(bot, chatID, usage)
+		return
+	}
+
+	if err := setPremiumSlotLimit(category, limit, managerID); err != nil {
+		log.Printf("Ошибка сохранения лимита премиум-мест для %s: %v", category, err)
+		sendText(bot, chatID, "❌ Не удалось сохранить лимит.")
+		return
+	}
+	log.Printf("Лимит премиум-мест в категории %s изменён на %d менеджером %d", category, limit, managerID)
+
+	sendText(bot, chatID, fmt.Sprintf("✅ Лимит премиум-мест в категории «%s»: %d.", categoryLabel(category), limit))
+	// При увеличении лимита места сразу достаются очереди
+	promotePremiumWaitlist(bot)
+	showPremiumSlots(bot, chatID)
+}
+
+// parseCategoryArg принимает код категории или её русское название
+func parseCategoryArg(arg string) (string, bool) {
+	if _, ok := categoryLabels[arg]; ok {
+		return arg, true
+	}
+	for label, value := range categoryValues {
+		if strings.EqualFold(label, arg) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func showPremiumSlots(bot *tgbotapi.BotAPI, chatID int64) {
+	var b strings.Builder
+	b.WriteString("⭐ Премиум-места по категориям\n")
+	for _, category := range categoryOrder {
+		limit, err := premiumSlotLimit(db.DB, category)
+		if err != nil {
+			log.Printf("Ошибка загрузки лимита премиум-мест для %s: %v", category, err)
+			sendText(bot, chatID, "❌ Не удалось загрузить премиум-места.")
+			return
+		}
+		taken, err := activePremiumCount(db.DB, category, nil)
+		if err != nil {
+			log.Printf("Ошибка подсчёта премиум-объявлений для %s: %v", category, err)
+			sendText(bot, chatID, "❌ Не удалось загрузить премиум-места.")
+			return
+		}
+		queue, err := premiumQueue(category)
+		if err != nil {
+			log.Printf("Ошибка загрузки очереди на премиум для %s: %v", category, err)
+			sendText(bot, chatID, "❌ Не удалось загрузить премиум-места.")
+			return
+		}
+
+		fmt.Fprintf(&b, "\n%s: занято %d из %d, в очереди %d", categoryLabel(category), taken, limit, len(queue))
+		for i, entry := range queue {
+			if i == premiumQueueListLimit {
+				fmt.Fprintf(&b, "\n   … и ещё %d", len(queue)-i)
+				break
+			}
+			fmt.Fprintf(&b, "\n   %d. #%d «%s»", i+1, entry.Ad.ID, truncate(entry.Ad.Title, 40))
+			if startsAt, err := estimatePremiumStart(category, i); err == nil && startsAt != nil {
+				fmt.Fprintf(&b, " · ~%s", startsAt.Format("02.01 15:04"))
+			}
+		}
+	}
+	b.WriteString("\n\nИзменить лимит: /slots <категория> <лимит>")
+	sendText(bot, chatID, b.String())
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errReportHandled — жалобу уже принял или отклонил другой менеджер
+var errReportHandled = errors.New("report already handled")
+
+func pendingReportsCount() (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.ScamReport{}).Where("status = ?", models.ReportStatusPending).Count(&count).Error
+	return count, err
+}
+
+// parseReportCallback разбирает callback вида <prefix><reportID>_<page>
+func parseReportCallback(data, prefix string) (uint, int, error) {
+	parts := strings.Split(strings.TrimPrefix(data, prefix), "_")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid callback %q", data)
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint(id), page, nil
+}
+
+// showReportQueue показывает жалобу из очереди на позиции page (старые — первыми)
+func showReportQueue(bot *tgbotapi.BotAPI, chatID int64, page int) {
+	total, err := pendingReportsCount()
+	if err != nil {
+		log.Printf("Ошибка подсчёта жалоб: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить жалобы.")
+		return
+	}
+
+	session := getSession(chatID)
+	if session == nil {
+		session = &adSession{
+			LastActivity:  time.Now(),
+			ChatID:        chatID,
+			BotMessageIDs: []int{},
+		}
+		setSession(chatID, session)
+	}
+	session.Stage = stageNone
+
+	if total == 0 {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+			),
+		)
+		msg := tgbotapi.NewMessage(chatID, "📣 *Новых жалоб нет*")
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = keyboard
+		sentMsg, err := bot.Send(msg)
+		if err == nil {
+			addBotMessage(chatID, sentMsg.MessageID)
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+		return
+	}
+
+	if page < 0 {
+		page = 0
+	}
+	if int64(page) >= total {
+		page = int(total) - 1
+	}
+
+	var report models.ScamReport
+	if err := db.DB.Preload("Screenshots").
+		Where("status = ?", models.ReportStatusPending).
+		Order("created_at ASC, id ASC").
+		Offset(page).
+		First(&report).Error; err != nil {
+		log.Printf("Ошибка загрузки жалобы из очереди: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить жалобы.")
+		return
+	}
+
+	if err := sendReportScreenshots(bot, chatID, &report); err != nil {
+		log.Printf("Ошибка отправки скриншотов жалобы %d: %v", report.ID, err)
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📣 Жалоба #%d (%d из %d)\n\n", report.ID, page+1, total))
+	text.WriteString(fmt.Sprintf("На: %s\n", reportTargetLabel(report)))
+	text.WriteString(fmt.Sprintf("Категория: %s\n", reportCategoryLabels[report.Category]))
+	if report.ReporterUsername != "" {
+		text.WriteString(fmt.Sprintf("От: @%s (ID %d)\n", report.ReporterUsername, report.ReporterID))
+	} else {
+		text.WriteString(fmt.Sprintf("От: ID %d\n", report.ReporterID))
+	}
+	text.WriteString(fmt.Sprintf("Дата: %s\n", report.CreatedAt.Format("02.01.2006 15:04")))
+	text.WriteString(fmt.Sprintf("Скриншотов: %d\n", len(report.Screenshots)))
+
+	target := blacklistTarget{Username: report.TargetUsername, TelegramID: report.TargetTelegramID}
+	if listed, err := findBlacklistUser(db.DB, target, true); err == nil {
+		text.WriteString(fmt.Sprintf("⚠️ Уже в чёрном списке как @%s\n", listed.Username))
+	}
+	text.WriteString("\n" + report.Text)
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Принять", fmt.Sprintf("report_accept_%d_%d", report.ID, page)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Отклонить", fmt.Sprintf("report_dismiss_%d_%d", report.ID, page)),
+		),
+	}
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("⬅️ Пред.", fmt.Sprintf("report_page_%d", page-1)))
+	}
+	if int64(page+1) < total {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("След. ➡️", fmt.Sprintf("report_page_%d", page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+	))
+
+	// Текст жалобы пользовательский, поэтому отправляем без Markdown
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+	}
+}
+
+// sendReportScreenshots показывает скриншоты жалобы. Файлы, ещё не загруженные в Telegram,
+// отправляются из базы, а полученные file_id сохраняются для повторного использования.
+func sendReportScreenshots(bot *tgbotapi.BotAPI, chatID int64, report *models.ScamReport) error {
+	if len(report.Screenshots) == 0 {
+		return nil
+	}
+
+	files := make([]tgbotapi.RequestFileData, 0, len(report.Screenshots))
+	for _, screenshot := range report.Screenshots {
+		if screenshot.FileID != "" {
+			files = append(files, tgbotapi.FileID(screenshot.FileID))
+		} else {
+			files = append(files, tgbotapi.FileBytes{
+				Name:  fmt.Sprintf("report-%d-%d%s", report.ID, screenshot.ID, reportScreenshotExt(screenshot.ContentType)),
+				Bytes: screenshot.Data,
+			})
+		}
+	}
+
+	sent, err := sendPhotos(bot, chatID, files)
+	for i := range report.Screenshots {
+		screenshot := &report.Screenshots[i]
+		if screenshot.FileID != "" || i >= len(sent) || len(sent[i].Photo) == 0 {
+			continue
+		}
+		screenshot.FileID = sent[i].Photo[len(sent[i].Photo)-1].FileID
+		if err := db.DB.Model(&models.ReportScreenshot{}).
+			Where("id = ?", screenshot.ID).
+			Update("file_id", screenshot.FileID).Error; err != nil {
+			log.Printf("Не удалось сохранить file_id скриншота %d: %v", screenshot.ID, err)
+		}
+	}
+	return err
+}
+
+func reportScreenshotExt(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".jpg"
+	}
+}
+
+func handleReportPage(bot *tgbotapi.BotAPI, chatID int64, data string) {
+	page, err := strconv.Atoi(strings.TrimPrefix(data, "report_page_"))
+	if err != nil {
+		sendText(bot, chatID, "❌ Неверная страница.")
+		return
+	}
+	showReportQueue(bot, chatID, page)
+}
+
+// loadPendingReport загружает жалобу для решения; если она уже обработана, сообщает об этом
+func loadPendingReport(bot *tgbotapi.BotAPI, chatID int64, reportID uint, page int) (*models.ScamReport, bool) {
+	var report models.ScamReport
+	if err := db.DB.Preload("Screenshots").First(&report, reportID).Error; err != nil {
+		sendText(bot, chatID, "❌ Жалоба не найдена.")
+		showReportQueue(bot, chatID, page)
+		return nil, false
+	}
+	if report.Status != models.ReportStatusPending {
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Жалоба #%d уже обработана.", report.ID))
+		showReportQueue(bot, chatID, page)
+		return nil, false
+	}
+	return &report, true
+}
+
+// handleReportAccept вносит пользователя из жалобы в чёрный список, прикладывая скриншоты как доказательства
+func handleReportAccept(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	reportID, page, err := parseReportCallback(data, "report_accept_")
+	if err != nil {
+		sendText(bot, chatID, "❌ Неверная жалоба.")
+		return
+	}
+	report, ok := loadPendingReport(bot, chatID, reportID, page)
+	if !ok {
+		return
+	}
+
+	// Доказательства в чёрном списке хранятся как file_id, поэтому догружаем недостающие
+	var evidence []string
+	for _, screenshot := range report.Screenshots {
+		if screenshot.FileID == "" {
+			if err := sendReportScreenshots(bot, chatID, report); err != nil {
+				log.Printf("Ошибка загрузки скриншотов жалобы %d: %v", report.ID, err)
+			}
+			break
+		}
+	}
+	for _, screenshot := range report.Screenshots {
+		if screenshot.FileID != "" {
+			evidence = append(evidence, screenshot.FileID)
+		}
+	}
+
+	target := blacklistTarget{Username: report.TargetUsername, TelegramID: report.TargetTelegramID}
+	reason := truncate(fmt.Sprintf("Жалоба #%d, %s: %s", report.ID, reportCategoryLabels[report.Category], report.Text), maxBlacklistReasonLen)
+
+	// Решение и запись в чёрный список — одной транзакцией: жалобу принимает только один менеджер
+	alreadyListed := false
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.ScamReport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", report.ID, models.ReportStatusPending).
+			Take(&locked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errReportHandled
+		}
+		if err != nil {
+			return err
+		}
+
+		var entryID *uint
+		entry, err := addToBlacklistTx(tx, target, managerID, reason, evidence)
+		switch {
+		case errors.Is(err, errAlreadyBlacklisted):
+			alreadyListed = true
+		case err != nil:
+			return err
+		default:
+			entryID = &entry.ID
+		}
+
+		result := tx.Model(&models.ScamReport{}).
+			Where("id = ? AND status = ?", report.ID, models.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":             models.ReportStatusAccepted,
+				"reviewed_by":        managerID,
+				"reviewed_at":        time.Now(),
+				"blacklist_entry_id": entryID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReportHandled
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errReportHandled):
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Жалоба #%d уже обработана.", report.ID))
+		showReportQueue(bot, chatID, page)
+		return
+	case err != nil:
+		log.Printf("Ошибка принятия жалобы %d (%s в чёрный список): %v", report.ID, target, err)
+		sendText(bot, chatID, fmt.Sprintf("❌ Не удалось принять жалобу и добавить %s в чёрный список: %v", target, err))
+		return
+	}
+	if alreadyListed {
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ %s уже в чёрном списке, жалоба отмечена принятой.", target))
+	}
+	log.Printf("Жалоба %d принята менеджером %d", report.ID, managerID)
+
+	notifyUser(bot, report.ReporterID, fmt.Sprintf("✅ Ваша жалоба #%d на %s рассмотрена: пользователь внесён в чёрный список. Спасибо, что помогаете бирже!", report.ID, target))
+
+	sendText(bot, chatID, fmt.Sprintf("✅ Жалоба #%d принята, %s в чёрном списке.", report.ID, target))
+	showReportQueue(bot, chatID, page)
+}
+
+func handleReportDismiss(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	reportID, page, err := parseReportCallback(data, "report_dismiss_")
+	if err != nil {
+		sendText(bot, chatID, "❌ Неверная жалоба.")
+		return
+	}
+	report, ok := loadPendingReport(bot, chatID, reportID, page)
+	if !ok {
+		return
+	}
+
+	result := db.DB.Model(&models.ScamReport{}).
+		Where("id = ? AND status = ?", report.ID, models.ReportStatusPending).
+		Updates(map[string]interface{}{
+			"status":      models.ReportStatusDismissed,
+			"reviewed_by": managerID,
+			"reviewed_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("Ошибка отклонения жалобы %d: %v", report.ID, result.Error)
+		sendText(bot, chatID, "❌ Не удалось обновить жалобу.")
+		return
+	}
+	if result.RowsAffected == 0 {
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Жалоба #%d уже обработана.", report.ID))
+		showReportQueue(bot, chatID, page)
+		return
+	}
+	log.Printf("Жалоба %d отклонена менеджером %d", report.ID, managerID)
+
+	target := blacklistTarget{Username: report.TargetUsername, TelegramID: report.TargetTelegramID}
+	notifyUser(bot, report.ReporterID, fmt.Sprintf("ℹ️ Ваша жалоба #%d на %s рассмотрена и отклонена: оснований для внесения в чёрный список недостаточно. Если у вас есть дополнительные доказательства, свяжитесь с %s.", report.ID, target, managerHelpLink))
+
+	sendText(bot, chatID, fmt.Sprintf("🗑 Жалоба #%d отклонена.", report.ID))
+	showReportQueue(bot, chatID, page)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reviewQueueLimit — сколько отзывов показывает очередь и /reviews
+const reviewQueueLimit = 10
+
+var errReviewUnchanged = errors.New("review already has this status")
+
+func pendingReviewsCount() (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Review{}).Where("status = ?", models.ReviewStatusPending).Count(&count).Error
+	return count, err
+}
+
+func reviewStatusLabel(review models.Review) string {
+	switch review.Status {
+	case models.ReviewStatusPending:
+		return "🟡 на проверке"
+	case models.ReviewStatusHidden:
+		return "🙈 скрыт"
+	default:
+		if review.Verified {
+			return "🤝 опубликован, сделка подтверждена"
+		}
+		return "🟢 опубликован"
+	}
+}
+
+func formatReview(review models.Review) string {
+	reviewer := fmt.Sprintf("ID %d", review.ReviewerID)
+	if review.ReviewerUsername != "" {
+		reviewer = fmt.Sprintf("@%s (ID %d)", review.ReviewerUsername, review.ReviewerID)
+	}
+	text := fmt.Sprintf("#%d · %s · %d/5 · объявление #%d\nО пользователе ID %d от %s · %s",
+		review.ID, review.CreatedAt.Format("02.01 15:04"), review.Rating, review.AdID, review.TargetID, reviewer, reviewStatusLabel(review))
+	if review.Comment != "" {
+		text += "\n«" + truncate(review.Comment, 300) + "»"
+	}
+	return text
+}
+
+// reviewButtons — кнопки модерации отзыва в зависимости от его статуса
+func reviewButtons(review models.Review, short bool) []tgbotapi.InlineKeyboardButton {
+	label := func(full string, icon string) string {
+		if short {
+			return fmt.Sprintf("%s #%d", icon, review.ID)
+		}
+		return full
+	}
+	switch review.Status {
+	case models.ReviewStatusPending:
+		return []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(label("✅ Опубликовать", "✅"), fmt.Sprintf("review_publish_%d", review.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(label("🤝 Сделка подтверждена", "🤝"), fmt.Sprintf("review_verify_%d", review.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(label("🙈 Скрыть", "🙈"), fmt.Sprintf("review_hide_%d", review.ID)),
+		}
+	case models.ReviewStatusHidden:
+		return []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(label("↩️ Вернуть", "↩️"), fmt.Sprintf("review_publish_%d", review.ID)),
+		}
+	default:
+		return []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(label("🙈 Скрыть", "🙈"), fmt.Sprintf("review_hide_%d", review.ID)),
+		}
+	}
+}
+
+// notifyManagersAboutReview присылает новый отзыв менеджерам, которые разбирают жалобы
+func notifyManagersAboutReview(review models.Review, ad models.Ad) {
+	managerBot.RLock()
+	bot := managerBot.api
+	managerBot.RUnlock()
+	if bot == nil {
+		return
+	}
+	managerIDs, err := managersWith(permManageBlacklist)
+	if err != nil {
+		log.Printf("Ошибка загрузки списка менеджеров: %v", err)
+		return
+	}
+
+	// Подсказка для проверки: добавлял ли автор отзыва объявление в избранное
+	var favorites int64
+	if err := db.DB.Model(&models.Favorite{}).Where("user_id = ? AND ad_id = ?", review.ReviewerID, ad.ID).Count(&favorites).Error; err != nil {
+		log.Printf("Ошибка проверки избранного для отзыва %d: %v", review.ID, err)
+	}
+	text := fmt.Sprintf("⭐ Новый отзыв\n\n%s\n\nОбъявление: «%s»", formatReview(review), ad.Title)
+	if favorites > 0 {
+		text += "\nОбъявление было в избранном у автора отзыва."
+	}
+
+	for _, managerID := range managerIDs {
+		msg := tgbotapi.NewMessage(managerID, text)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(reviewButtons(review, false))
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("failed to notify manager %d: %v", managerID, err)
+		}
+	}
+}
+
+// showReviewQueue показывает отзывы, ожидающие проверки, старые — первыми
+func showReviewQueue(bot *tgbotapi.BotAPI, chatID int64) {
+	var reviews []models.Review
+	if err := db.DB.Where("status = ?", models.ReviewStatusPending).
+		Order("id").
+		Limit(reviewQueueLimit).
+		Find(&reviews).Error; err != nil {
+		log.Printf("Ошибка загрузки отзывов: %v", err)
+		sendText(bot, chatID, "❌ Не удалось загрузить отзывы.")
+		return
+	}
+
+	var b strings.Builder
+	if len(reviews) == 0 {
+		b.WriteString("⭐ Новых отзывов нет")
+	} else {
+		b.WriteString("⭐ Отзывы на проверке\n")
+	}
+	sendReviewList(bot, chatID, &b, reviews)
+}
+
+// handleReviewsCommand обрабатывает /reviews <@username|ID> — все отзывы о пользователе
+func handleReviewsCommand(bot *tgbotapi.BotAPI, chatID int64, text string) {
+	target := parseBlacklistTarget(strings.TrimSpace(text[len(commandReviews):]))
+	if target.empty() {
+		sendText(bot, chatID, "Использование: /reviews <@username или Telegram ID>. Отзывы на проверке — в меню «⭐ Отзывы».")
+		return
+	}
+	userID := target.TelegramID
+	if userID == 0 {
+		id, err := profileUserID(target.Username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			sendText(bot, chatID, fmt.Sprintf("❌ Пользователь %s не найден.", target))
+			return
+		}
+		if err != nil {
+			log.Printf("Ошибка поиска пользователя %s: %v", target, err)
+			sendText(bot, chatID, "❌ Не удалось загрузить отзывы.")
+			return
+		}
+		userID = id
+	}
+
+	var reviews []models.Review
+	if err := db.DB.Where("target_id = ?", userID).Order("id DESC").Limit(reviewQueueLimit).Find(&reviews).Error; err != nil {
+		log.Printf("Ошибка загрузки отзывов о пользователе %d: %v", userID, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить отзывы.")
+		return
+	}
+	reputations, err := loadReputations([]int64{userID})
+	if err != nil {
+		log.Printf("Ошибка загрузки репутации пользователя %d: %v", userID, err)
+	}
+	reputation := reputations[userID]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "⭐ Отзывы о %s\nРейтинг: %.1f из 5, отзывов: %d, подтверждённых сделок: %d\n",
+		target, reputation.Average, reputation.Count, reputation.VerifiedDeals)
+	if len(reviews) == 0 {
+		b.WriteString("\nОтзывов нет.")
+	}
+	sendReviewList(bot, chatID, &b, reviews)
+}
+
+func sendReviewList(bot *tgbotapi.BotAPI, chatID int64, b *strings.Builder, reviews []models.Review) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, review := range reviews {
+		b.WriteString("\n" + formatReview(review) + "\n")
+		rows = append(rows, reviewButtons(review, true))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️ В меню", "menu_main"),
+	))
+
+	// Комментарии пользовательские, поэтому отправляем без Markdown
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sentMsg, err := bot.Send(msg)
+	if err == nil {
+		addBotMessage(chatID, sentMsg.MessageID)
+		if session := getSession(chatID); session != nil {
+			go scheduleDeletePreviousMessages(bot, chatID, session, sentMsg.MessageID)
+		}
+	}
+}
+
+// handleReviewModeration обрабатывает review_publish_<ID>, review_verify_<ID> и review_hide_<ID>
+func handleReviewModeration(bot *tgbotapi.BotAPI, chatID int64, managerID int64, data string) {
+	var (
+		prefix   string
+		status   string
+		verified bool
+	)
+	switch {
+	case strings.HasPrefix(data, "review_publish_"):
+		prefix, status = "review_publish_", models.ReviewStatusPublished
+	case strings.HasPrefix(data, "review_verify_"):
+		prefix, status, verified = "review_verify_", models.ReviewStatusPublished, true
+	case strings.HasPrefix(data, "review_hide_"):
+		prefix, status = "review_hide_", models.ReviewStatusHidden
+	default:
+		return
+	}
+	reviewID, err := strconv.ParseUint(strings.TrimPrefix(data, prefix), 10, 32)
+	if err != nil {
+		return
+	}
+
+	var review models.Review
+	var firstPublish bool
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, reviewID).Error; err != nil {
+			return err
+		}
+		if review.Status == status && (!verified || review.Verified) {
+			return errReviewUnchanged
+		}
+		before := review
+		firstPublish = review.Status == models.ReviewStatusPending && status == models.ReviewStatusPublished
+
+		now := time.Now()
+		review.Status = status
+		review.Verified = review.Verified || verified
+		review.ModeratedBy = managerID
+		review.ModeratedAt = &now
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		action := auditReviewPublish
+		if status == models.ReviewStatusHidden {
+			action = auditReviewHide
+		}
+		return recordAudit(tx, managerID, action, auditEntityReview, review.ID,
+			map[string]interface{}{"status": before.Status, "verified": before.Verified},
+			map[string]interface{}{"status": review.Status, "verified": review.Verified})
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendText(bot, chatID, "❌ Отзыв не найден.")
+		return
+	case errors.Is(err, errReviewUnchanged):
+		sendText(bot, chatID, fmt.Sprintf("ℹ️ Отзыв #%d уже обработан: %s.", review.ID, reviewStatusLabel(review)))
+		return
+	case err != nil:
+		log.Printf("Ошибка модерации отзыва %d: %v", reviewID, err)
+		sendText(bot, chatID, "❌ Не удалось обновить отзыв.")
+		return
+	}
+	log.Printf("Отзыв %d: статус %s (сделка подтверждена: %v), менеджер %d", review.ID, review.Status, review.Verified, managerID)
+
+	if firstPublish {
+		notifyUser(bot, review.TargetID, fmt.Sprintf("⭐ О вас оставили отзыв по объявлению #%d: %d из 5.", review.AdID, review.Rating))
+	}
+	sendText(bot, chatID, fmt.Sprintf("✅ Отзыв #%d: %s.", review.ID, reviewStatusLabel(review)))
+}
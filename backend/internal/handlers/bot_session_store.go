@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sessionStore — хранилище сессий бота, переживающее перезапуск сервера.
+// Живые сессии держатся в sessionRegistry, хранилище получает их снимки.
+type sessionStore interface {
+	Load(chatID int64) (*adSession, error)
+	Save(session *adSession) error
+	Delete(chatID int64) error
+	// Cleanup удаляет сессии, срок жизни которых истёк
+	Cleanup() error
+}
+
+// storedSession — сериализуемая часть adSession
+type storedSession struct {
+	Operation       adOperation
+	Stage           conversationStage
+	Ad              models.Ad
+	DurationDays    int
+	BotMessageIDs   []int
+	LastActivity    time.Time
+	ModerationPage  int
+	ModerationTotal int
+	Blacklist       blacklistDraft
+	Photos          []models.AdPhoto
+	PhotosEdited    bool
+	PhotoPromptID   int
+	PremiumQueued   bool
+}
+
+var (
+	activeSessionStore   sessionStore = newMemorySessionStore(sessionTimeoutDuration)
+	activeSessionStoreMu sync.RWMutex
+)
+
+func getSessionStore() sessionStore {
+	activeSessionStoreMu.RLock()
+	defer activeSessionStoreMu.RUnlock()
+	return activeSessionStore
+}
+
+// initSessionStore выбирает хранилище по SESSION_STORE (redis, postgres или memory).
+// Если переменная не задана, используется Redis при наличии подключения.
+// При недоступности выбранного хранилища остаёмся на памяти процесса.
+func initSessionStore() {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("SESSION_STORE")))
+	if kind == "" {
+		if middleware.RedisClient() != nil {
+			kind = "redis"
+		} else {
+			kind = "memory"
+		}
+	}
+
+	var store sessionStore
+	switch kind {
+	case "redis":
+		if client := middleware.RedisClient(); client != nil {
+			store = &redisSessionStore{client: client, ttl: sessionTimeoutDuration}
+		} else {
+			log.Printf("SESSION_STORE=redis, но Redis недоступен — сессии хранятся в памяти")
+		}
+	case "postgres":
+		if db.DB != nil {
+			store = &postgresSessionStore{db: db.DB, ttl: sessionTimeoutDuration}
+		} else {
+			log.Printf("SESSION_STORE=postgres, но база данных не инициализирована — сессии хранятся в памяти")
+		}
+	case "memory":
+	default:
+		log.Printf("Неизвестное значение SESSION_STORE=%q — сессии хранятся в памяти", kind)
+	}
+
+	if store == nil {
+		return
+	}
+
+	activeSessionStoreMu.Lock()
+	activeSessionStore = store
+	activeSessionStoreMu.Unlock()
+	log.Printf("Сессии бота хранятся в %s", kind)
+}
+
+func encodeSession(session *adSession) ([]byte, error) {
+	snapshot := storedSession{
+		Operation:       session.Operation,
+		Stage:           session.Stage,
+		Ad:              session.Ad,
+		DurationDays:    session.DurationDays,
+		BotMessageIDs:   session.BotMessageIDs,
+		LastActivity:    session.LastActivity,
+		ModerationPage:  session.ModerationPage,
+		ModerationTotal: session.ModerationTotal,
+		Blacklist:       session.Blacklist,
+		Photos:          session.Photos,
+		PhotosEdited:    session.PhotosEdited,
+		PhotoPromptID:   session.PhotoPromptID,
+		PremiumQueued:   session.PremiumQueued,
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSession(chatID int64, data []byte) (*adSession, error) {
+	var snapshot storedSession
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	return &adSession{
+		Operation:       snapshot.Operation,
+		Stage:           snapshot.Stage,
+		Ad:              snapshot.Ad,
+		DurationDays:    snapshot.DurationDays,
+		LastActivity:    snapshot.LastActivity,
+		ChatID:          chatID,
+		BotMessageIDs:   snapshot.BotMessageIDs,
+		ModerationPage:  snapshot.ModerationPage,
+		ModerationTotal: snapshot.ModerationTotal,
+		Blacklist:       snapshot.Blacklist,
+		Photos:          snapshot.Photos,
+		PhotosEdited:    snapshot.PhotosEdited,
+		PhotoPromptID:   snapshot.PhotoPromptID,
+		PremiumQueued:   snapshot.PremiumQueued,
+	}, nil
+}
+
+// memorySessionStore хранит снимки сессий в памяти процесса (поведение по умолчанию)
+type memorySessionStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	data map[int64]memorySessionEntry
+}
+
+type memorySessionEntry struct {
+	payload   []byte
+	expiresAt time.Time
+}
+
+func newMemorySessionStore(ttl time.Duration) *memorySessionStore {
+	return &memorySessionStore{ttl: ttl, data: make(map[int64]memorySessionEntry)}
+}
+
+func (s *memorySessionStore) Load(chatID int64) (*adSession, error) {
+	s.mu.Lock()
+	entry, ok := s.data[chatID]
+	s.mu.Unlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, nil
+	}
+	return decodeSession(chatID, entry.payload)
+}
+
+func (s *memorySessionStore) Save(session *adSession) error {
+	payload, err := encodeSession(session)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.data[session.ChatID] = memorySessionEntry{payload: payload, expiresAt: session.LastActivity.Add(s.ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Delete(chatID int64) error {
+	s.mu.Lock()
+	delete(s.data, chatID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Cleanup() error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for chatID, entry := range s.data {
+		if now.After(entry.expiresAt) {
+			delete(s.data, chatID)
+		}
+	}
+	return nil
+}
+
+// redisSessionStore хранит сессии в Redis, срок жизни задаётся TTL ключа
+type redisSessionStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func redisSessionKey(chatID int64) string {
+	return fmt.Sprintf("botsession:%d", chatID)
+}
+
+func (s *redisSessionStore) Load(chatID int64) (*adSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	payload, err := s.client.Get(ctx, redisSessionKey(chatID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSession(chatID, payload)
+}
+
+func (s *redisSessionStore) Save(session *adSession) error {
+	payload, err := encodeSession(session)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(session.LastActivity.Add(s.ttl))
+	if ttl <= 0 {
+		return s.Delete(session.ChatID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.client.Set(ctx, redisSessionKey(session.ChatID), payload, ttl).Err()
+}
+
+func (s *redisSessionStore) Delete(chatID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.client.Del(ctx, redisSessionKey(chatID)).Err()
+}
+
+// Cleanup не требуется: Redis сам удаляет ключи по истечении TTL
+func (s *redisSessionStore) Cleanup() error {
+	return nil
+}
+
+// postgresSessionStore хранит сессии в таблице bot_sessions
+type postgresSessionStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func (s *postgresSessionStore) Load(chatID int64) (*adSession, error) {
+	var row models.BotSession
+	err := s.db.Where("chat_id = ? AND expires_at > ?", chatID, time.Now()).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSession(chatID, row.Data)
+}
+
+func (s *postgresSessionStore) Save(session *adSession) error {
+	payload, err := encodeSession(session)
+	if err != nil {
+		return err
+	}
+	row := models.BotSession{
+		ChatID:    session.ChatID,
+		Data:      payload,
+		ExpiresAt: session.LastActivity.Add(s.ttl),
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "expires_at", "updated_at"}),
+	}).Create(&row).Error
+}
+
+func (s *postgresSessionStore) Delete(chatID int64) error {
+	return s.db.Where("chat_id = ?", chatID).Delete(&models.BotSession{}).Error
+}
+
+func (s *postgresSessionStore) Cleanup() error {
+	return s.db.Where("expires_at <= ?", time.Now()).Delete(&models.BotSession{}).Error
+}
+
+// persistSession сохраняет текущее состояние сессии чата в хранилище
+func persistSession(chatID int64) {
+	sessionRegistry.Lock()
+	session := sessionRegistry.data[chatID]
+	var payload *adSession
+	if session != nil {
+		// Копируем под блокировкой: BotMessageIDs меняются из фоновых горутин
+		snapshot := *session
+		snapshot.BotMessageIDs = append([]int(nil), session.BotMessageIDs...)
+		payload = &snapshot
+	}
+	sessionRegistry.Unlock()
+
+	if payload == nil {
+		return
+	}
+	if err := getSessionStore().Save(payload); err != nil {
+		log.Printf("failed to persist bot session %d: %v", chatID, err)
+	}
+}
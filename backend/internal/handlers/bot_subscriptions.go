@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// showSubscriptions обрабатывает /subscriptions — список сохранённых поисков с кнопками удаления
+func showSubscriptions(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	subs, err := userSubscriptions(userID)
+	if err != nil {
+		log.Printf("Ошибка загрузки подписок пользователя %d: %v", userID, err)
+		sendText(bot, chatID, "❌ Не удалось загрузить подписки. Попробуйте позже.")
+		return
+	}
+	if len(subs) == 0 {
+		sendText(bot, chatID, "У вас нет сохранённых поисков. Подписаться на новые объявления можно в Mini App: выберите фильтры в ленте и нажмите «🔔 Подписаться».")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("🔔 Ваши подписки:\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, sub := range subs {
+		fmt.Fprintf(&b, "\n#%d %s", sub.ID, subscriptionLabel(sub))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 Удалить #%d", sub.ID), fmt.Sprintf("sub_delete_%d", sub.ID)),
+		))
+	}
+	fmt.Fprintf(&b, "\n\nСразу приходит до %d оповещений в сутки, остальные — одним дайджестом.", subscriptionDailyAlertLimit)
+
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("failed to send subscriptions to %d: %v", chatID, err)
+	}
+}
+
+// handleSubscriptionDelete удаляет подписку по кнопке "sub_delete_<ID>"
+func handleSubscriptionDelete(bot *tgbotapi.BotAPI, chatID int64, userID int64, data string) {
+	subID, err := strconv.ParseUint(strings.TrimPrefix(data, "sub_delete_"), 10, 32)
+	if err != nil {
+		return
+	}
+
+	deleted, err := deleteSubscription(userID, uint(subID))
+	if err != nil {
+		log.Printf("Ошибка удаления подписки %d: %v", subID, err)
+		sendText(bot, chatID, "❌ Не удалось удалить подписку.")
+		return
+	}
+	if !deleted {
+		sendText(bot, chatID, "ℹ️ Подписка уже удалена.")
+		return
+	}
+	log.Printf("Пользователь %d удалил подписку %d", userID, subID)
+
+	showSubscriptions(bot, chatID, userID)
+}
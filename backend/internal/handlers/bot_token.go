@@ -1,6 +1,10 @@
 package handlers
 
-import "sync"
+import (
+	"os"
+	"strings"
+	"sync"
+)
 
 var (
 	botTokenMu sync.RWMutex
@@ -19,3 +23,11 @@ func getBotToken() string {
 	return botToken
 }
 
+// telegramAPIURL — адрес Bot API. TELEGRAM_API_URL позволяет указать локальный
+// Bot API сервер или фейковый сервер для проверки оплат.
+func telegramAPIURL() string {
+	if value := strings.TrimRight(os.Getenv("TELEGRAM_API_URL"), "/"); value != "" {
+		return value
+	}
+	return "https://api.telegram.org"
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	defaultWebhookPath    = "/telegram/webhook"
+	webhookSecretHeader   = "X-Telegram-Bot-Api-Secret-Token"
+	webhookEnqueueTimeout = 5 * time.Second
+)
+
+// webhookConfig описывает режим получения обновлений ботом.
+// Webhook включается переменной BOT_WEBHOOK_URL, иначе используется long polling.
+type webhookConfig struct {
+	URL    *url.URL
+	Path   string
+	Secret string
+}
+
+var (
+	webhookConfigOnce sync.Once
+	botWebhookConfig  *webhookConfig
+	webhookUpdates    = make(chan tgbotapi.Update, 100)
+	// webhookActive выставляется, когда бот запущен и читает webhookUpdates
+	webhookActive atomic.Bool
+)
+
+func loadWebhookConfig() *webhookConfig {
+	webhookConfigOnce.Do(func() {
+		raw := strings.TrimSpace(os.Getenv("BOT_WEBHOOK_URL"))
+		if raw == "" {
+			return
+		}
+
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			log.Printf("BOT_WEBHOOK_URL=%q некорректен (нужен https URL), бот работает через long polling", raw)
+			return
+		}
+
+		path := parsed.Path
+		if path == "" || path == "/" {
+			path = defaultWebhookPath
+			parsed.Path = path
+		}
+
+		secret := strings.TrimSpace(os.Getenv("BOT_WEBHOOK_SECRET"))
+		if secret == "" {
+			token := strings.TrimSpace(os.Getenv("BOT_TOKEN"))
+			if token == "" {
+				log.Printf("BOT_TOKEN не задан, webhook не включается")
+				return
+			}
+			secret = deriveWebhookSecret(token)
+		}
+
+		botWebhookConfig = &webhookConfig{URL: parsed, Path: path, Secret: secret}
+	})
+	return botWebhookConfig
+}
+
+// deriveWebhookSecret выводит секрет webhook из токена бота, чтобы все реплики
+// проверяли один и тот же секрет. Telegram допускает в нём только A-Z, a-z, 0-9, _ и -.
+func deriveWebhookSecret(botToken string) string {
+	mac := hmac.New(sha256.New, []byte(botToken))
+	mac.Write([]byte("telegram-webhook-secret"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RegisterBotWebhook добавляет маршрут для приёма обновлений Telegram, если включён режим webhook
+func RegisterBotWebhook(r *gin.Engine) {
+	cfg := loadWebhookConfig()
+	if cfg == nil {
+		return
+	}
+
+	// Telegram присылает обновления пачками, лимит по IP для него не применяем
+	middleware.ExcludeFromRateLimit(cfg.Path)
+	r.POST(cfg.Path, handleBotWebhook)
+	log.Printf("Telegram webhook route registered at %s", cfg.Path)
+}
+
+func handleBotWebhook(c *gin.Context) {
+	cfg := loadWebhookConfig()
+	if cfg == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if !webhookActive.Load() {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	token := c.GetHeader(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Secret)) != 1 {
+		metrics.APIRequestsTotal.WithLabelValues("bot_webhook", "401").Inc()
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := c.ShouldBindJSON(&update); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("bot_webhook", "400").Inc()
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case webhookUpdates <- update:
+	case <-time.After(webhookEnqueueTimeout):
+		// Telegram повторит доставку, если обработчик не успевает
+		log.Printf("bot webhook queue is full, update %d rejected", update.UpdateID)
+		metrics.APIRequestsTotal.WithLabelValues("bot_webhook", "503").Inc()
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("bot_webhook", "200").Inc()
+	c.Status(http.StatusOK)
+}
+
+// receiveUpdates настраивает выбранный режим на стороне Telegram и возвращает канал обновлений
+func receiveUpdates(bot *tgbotapi.BotAPI) tgbotapi.UpdatesChannel {
+	if cfg := loadWebhookConfig(); cfg != nil {
+		params := tgbotapi.Params{
+			"url":          cfg.URL.String(),
+			"secret_token": cfg.Secret,
+		}
+		if _, err := bot.MakeRequest("setWebhook", params); err != nil {
+			log.Fatal("setWebhook failed:", err)
+		}
+		webhookActive.Store(true)
+		log.Printf("Manager bot receives updates via webhook %s", cfg.URL.String())
+		return webhookUpdates
+	}
+
+	// Снимаем webhook, иначе getUpdates вернёт ошибку конфликта
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("deleteWebhook failed: %v", err)
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	log.Printf("Manager bot receives updates via long polling")
+	return bot.GetUpdatesChan(u)
+}
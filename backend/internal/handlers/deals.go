@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dealDefaultFeePercent — комиссия гаранта, если менеджер не указал её при открытии сделки
+const dealDefaultFeePercent = 5
+
+// dealListLimit — сколько сделок отдаёт GET /api/deals
+const dealListLimit = 50
+
+var (
+	errDealTransition = errors.New("transition is not allowed")
+	errDealExists     = errors.New("open deal already exists")
+	errDealSelf       = errors.New("buyer and seller are the same user")
+	errDealNoSeller   = errors.New("ad author is unknown")
+)
+
+// dealTransitions — из какого статуса в какие можно перевести сделку
+var dealTransitions = map[string][]string{
+	models.DealStatusCreated:   {models.DealStatusFunded, models.DealStatusRefunded},
+	models.DealStatusFunded:    {models.DealStatusDelivered, models.DealStatusDisputed, models.DealStatusRefunded},
+	models.DealStatusDelivered: {models.DealStatusReleased, models.DealStatusDisputed},
+	models.DealStatusDisputed:  {models.DealStatusReleased, models.DealStatusRefunded},
+}
+
+// dealOpenStatuses — сделки, которые ещё не закрыты
+var dealOpenStatuses = []string{models.DealStatusCreated, models.DealStatusFunded, models.DealStatusDelivered, models.DealStatusDisputed}
+
+var dealStatusLabels = map[string]string{
+	models.DealStatusCreated:   "🆕 Открыта, ждём оплату",
+	models.DealStatusFunded:    "💰 Оплата у гаранта",
+	models.DealStatusDelivered: "📦 Товар передан",
+	models.DealStatusReleased:  "✅ Завершена",
+	models.DealStatusDisputed:  "⚠️ Спор",
+	models.DealStatusRefunded:  "↩️ Возврат покупателю",
+}
+
+func dealTransitionAllowed(from, to string) bool {
+	for _, next := range dealTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func dealFee(amount int64) int64 {
+	return amount * dealDefaultFeePercent / 100
+}
+
+// createDeal открывает сделку по объявлению; продавец — автор объявления
+func createDeal(ad models.Ad, buyerID int64, buyerUsername string, amount, fee int64, managerID int64) (*models.Deal, error) {
+	if ad.UserID == 0 {
+		return nil, errDealNoSeller
+	}
+	if ownsAd(ad, buyerID) {
+		return nil, errDealSelf
+	}
+	deal := models.Deal{
+		AdID:           ad.ID,
+		SellerID:       ad.UserID,
+		SellerUsername: normalizeUsername(ad.Username),
+		BuyerID:        buyerID,
+		BuyerUsername:  normalizeUsername(buyerUsername),
+		Amount:         amount,
+		Fee:            fee,
+		Status:         models.DealStatusCreated,
+		ManagerID:      managerID,
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&models.Deal{}).
+			Where("ad_id = ? AND buyer_id = ? AND status IN ?", ad.ID, buyerID, dealOpenStatuses).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return errDealExists
+		}
+		if err := tx.Create(&deal).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditDealCreate, auditEntityDeal, deal.ID, nil, deal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deal, nil
+}
+
+// transitionDeal переводит сделку в статус to, если это разрешено из текущего
+func transitionDeal(dealID uint, to string, managerID int64) (models.Deal, error) {
+	var deal models.Deal
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&deal, dealID).Error; err != nil {
+			return err
+		}
+		if !dealTransitionAllowed(deal.Status, to) {
+			return errDealTransition
+		}
+		before := deal
+
+		now := time.Now()
+		deal.Status = to
+		switch to {
+		case models.DealStatusFunded:
+			deal.FundedAt = &now
+		case models.DealStatusDelivered:
+			deal.DeliveredAt = &now
+		case models.DealStatusReleased, models.DealStatusRefunded:
+			deal.ClosedAt = &now
+		}
+		if err := tx.Save(&deal).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, managerID, auditDealStatus, auditEntityDeal, deal.ID, before, deal)
+	})
+	return deal, err
+}
+
+// DealView — сделка в Mini App глазами участника
+type DealView struct {
+	models.Deal
+	AdTitle     string `json:"ad_title"`
+	Role        string `json:"role"`
+	StatusLabel string `json:"status_label"`
+}
+
+// GetDeals возвращает сделки, в которых пользователь — покупатель или продавец
+func GetDeals(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("deals", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	queryStart := time.Now()
+	var deals []models.Deal
+	err := db.DB.Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("created_at DESC, id DESC").
+		Limit(dealListLimit).
+		Find(&deals).Error
+	titles := make(map[uint]string)
+	if err == nil && len(deals) > 0 {
+		adIDs := make([]uint, 0, len(deals))
+		for _, deal := range deals {
+			adIDs = append(adIDs, deal.AdID)
+		}
+		// Снятые и удалённые объявления тоже подписываем: сделка по ним остаётся в истории
+		var ads []models.Ad
+		err = db.DB.Unscoped().Select("id", "title").Where("id IN ?", adIDs).Find(&ads).Error
+		for _, ad := range ads {
+			titles[ad.ID] = ad.Title
+		}
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetDeals"})
+		metrics.APIRequestsTotal.WithLabelValues("deals", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "deals").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load deals"})
+		return
+	}
+
+	response := make([]DealView, 0, len(deals))
+	for _, deal := range deals {
+		role := "buyer"
+		if deal.SellerID == userID {
+			role = "seller"
+		}
+		response = append(response, DealView{
+			Deal:        deal,
+			AdTitle:     titles[deal.AdID],
+			Role:        role,
+			StatusLabel: dealStatusLabels[deal.Status],
+		})
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("deals", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("deals").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusOK, response)
+}
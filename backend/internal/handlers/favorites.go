@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxFavoritesPerUser — сколько объявлений можно держать в избранном
+const maxFavoritesPerUser = 200
+
+var errFavoritesLimit = fmt.Errorf("no more than %d favorites allowed", maxFavoritesPerUser)
+
+// События по объявлению, о которых сообщается тем, у кого оно в избранном
+const (
+	favoriteEventRenewed  = "renewed"
+	favoriteEventEdited   = "edited"
+	favoriteEventExpiring = "expiring"
+)
+
+// FavoriteView — объявление из избранного
+type FavoriteView struct {
+	AdView
+	Notify      bool      `json:"notify"`
+	FavoritedAt time.Time `json:"favorited_at"`
+}
+
+// favoriteRequest — необязательное тело POST /api/ads/:id/favorite
+type favoriteRequest struct {
+	Notify *bool `json:"notify"`
+}
+
+func parseFavoriteAdID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// AddFavorite добавляет объявление в избранное или меняет настройку уведомлений
+func AddFavorite(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("add_favorite", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+	adID, ok := parseFavoriteAdID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("add_favorite", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "add_favorite").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ad id"})
+		return
+	}
+	var req favoriteRequest
+	// Тело необязательно: без него уведомления включены
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			metrics.APIRequestsTotal.WithLabelValues("add_favorite", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "add_favorite").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	notify := req.Notify == nil || *req.Notify
+
+	queryStart := time.Now()
+	var favorite models.Favorite
+	created := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var ad models.Ad
+		if err := tx.First(&ad, adID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("user_id = ? AND ad_id = ?", userID, adID).First(&favorite).Error
+		if err == nil {
+			if req.Notify == nil || favorite.Notify == notify {
+				return nil
+			}
+			favorite.Notify = notify
+			return tx.Model(&favorite).Update("notify", notify).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// В избранное добавляются только объявления, которые видны в ленте
+		if ad.Status != models.AdStatusActive {
+			return gorm.ErrRecordNotFound
+		}
+		var count int64
+		if err := tx.Model(&models.Favorite{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxFavoritesPerUser {
+			return errFavoritesLimit
+		}
+		favorite = models.Favorite{UserID: userID, AdID: adID, Notify: notify}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite)
+		created = result.RowsAffected > 0
+		return result.Error
+	})
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		metrics.APIRequestsTotal.WithLabelValues("add_favorite", "404").Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+		return
+	case errors.Is(err, errFavoritesLimit):
+		metrics.APIRequestsTotal.WithLabelValues("add_favorite", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "add_favorite").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		middleware.CaptureError(c, err, map[string]string{"handler": "AddFavorite"})
+		metrics.APIRequestsTotal.WithLabelValues("add_favorite", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "add_favorite").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save favorite"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	metrics.APIRequestsTotal.WithLabelValues("add_favorite", strconv.Itoa(status)).Inc()
+	metrics.APIReponseTime.WithLabelValues("add_favorite").Observe(time.Since(start).Seconds())
+	c.JSON(status, favorite)
+}
+
+// RemoveFavorite убирает объявление из избранного
+func RemoveFavorite(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("remove_favorite", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+	adID, ok := parseFavoriteAdID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("remove_favorite", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "remove_favorite").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ad id"})
+		return
+	}
+
+	queryStart := time.Now()
+	result := db.DB.Where("user_id = ? AND ad_id = ?", userID, adID).Delete(&models.Favorite{})
+	metrics.DatabaseQueryDuration.WithLabelValues("delete").Observe(time.Since(queryStart).Seconds())
+	if result.Error != nil {
+		middleware.CaptureError(c, result.Error, map[string]string{"handler": "RemoveFavorite"})
+		metrics.APIRequestsTotal.WithLabelValues("remove_favorite", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "remove_favorite").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove favorite"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		metrics.APIRequestsTotal.WithLabelValues("remove_favorite", "404").Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "favorite not found"})
+		return
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("remove_favorite", "204").Inc()
+	metrics.APIReponseTime.WithLabelValues("remove_favorite").Observe(time.Since(start).Seconds())
+	c.Status(http.StatusNoContent)
+}
+
+// GetFavorites возвращает избранное пользователя, последние добавленные — первыми.
+// Истёкшие и снятые объявления остаются в списке со своим статусом.
+func GetFavorites(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("favorites", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	queryStart := time.Now()
+	var favorites []models.Favorite
+	err := db.DB.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Find(&favorites).Error
+	var ads []models.Ad
+	if err == nil && len(favorites) > 0 {
+		adIDs := make([]uint, 0, len(favorites))
+		for _, favorite := range favorites {
+			adIDs = append(adIDs, favorite.AdID)
+		}
+		err = db.DB.Where("id IN ?", adIDs).Find(&ads).Error
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetFavorites"})
+		metrics.APIRequestsTotal.WithLabelValues("favorites", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "favorites").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load favorites"})
+		return
+	}
+
+	byID := make(map[uint]models.Ad, len(ads))
+	for _, ad := range ads {
+		byID[ad.ID] = ad
+	}
+	response := make([]FavoriteView, 0, len(favorites))
+	views := make([]AdView, 0, len(favorites))
+	ordered := make([]models.Ad, 0, len(favorites))
+	for _, favorite := range favorites {
+		// Удалённые объявления в выборку не попадают
+		ad, ok := byID[favorite.AdID]
+		if !ok {
+			continue
+		}
+		response = append(response, FavoriteView{Notify: favorite.Notify, FavoritedAt: favorite.CreatedAt})
+		views = append(views, buildAdView(ad))
+		ordered = append(ordered, ad)
+	}
+	attachReputations(views, ordered)
+	for i := range response {
+		response[i].AdView = views[i]
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("favorites", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("favorites").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusOK, response)
+}
+
+func favoriteEventText(ad models.Ad, event string) string {
+	switch event {
+	case favoriteEventRenewed:
+		return fmt.Sprintf("🔄 Объявление из избранного «%s» продлено до %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006"))
+	case favoriteEventExpiring:
+		text := fmt.Sprintf("⏳ Срок объявления из избранного «%s» истекает %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006 15:04"))
+		if ad.Username != "" {
+			text += " Успейте связаться с автором: @" + strings.TrimPrefix(ad.Username, "@")
+		}
+		return text
+	default:
+		return fmt.Sprintf("✏️ Объявление из избранного «%s» изменено.", ad.Title)
+	}
+}
+
+// notifyFavorites сообщает о событии по объявлению тем, у кого оно в избранном с включёнными уведомлениями.
+// Автору объявления уведомление не отправляется.
+func notifyFavorites(bot *tgbotapi.BotAPI, ad models.Ad, event string) {
+	if bot == nil || ad.Status != models.AdStatusActive {
+		return
+	}
+	var favorites []models.Favorite
+	if err := db.DB.Where("ad_id = ? AND notify = ?", ad.ID, true).Find(&favorites).Error; err != nil {
+		log.Printf("Ошибка загрузки избранного по объявлению %d: %v", ad.ID, err)
+		return
+	}
+
+	text := favoriteEventText(ad, event)
+	for _, favorite := range favorites {
+		if ownsAd(ad, favorite.UserID) {
+			continue
+		}
+		notifyUser(bot, favorite.UserID, text)
+	}
+}
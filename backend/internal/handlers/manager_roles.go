@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// permission — право на группу действий в боте
+type permission int
+
+const (
+	// permView — просмотр объявлений, очередей, чёрного списка и журнала
+	permView permission = iota
+	// permManageAds — создание, изменение, продление, снятие и модерация объявлений
+	permManageAds
+	// permManageBlacklist — чёрный список и разбор жалоб
+	permManageBlacklist
+	// permManageManagers — управление менеджерами и их ролями
+	permManageManagers
+	// permManagePayments — платежи в Stars и возвраты
+	permManagePayments
+	// permManagePremiumSlots — лимиты премиум-мест по категориям
+	permManagePremiumSlots
+	// permManageDeals — сделки через гаранта
+	permManageDeals
+)
+
+// managerRoles — роли в порядке убывания прав (в этом порядке они показываются в боте)
+var managerRoles = []string{
+	models.ManagerRoleOwner,
+	models.ManagerRoleModerator,
+	models.ManagerRoleAdEditor,
+	models.ManagerRoleViewer,
+}
+
+var managerRoleLabels = map[string]string{
+	models.ManagerRoleOwner:     "Владелец",
+	models.ManagerRoleModerator: "Модератор",
+	models.ManagerRoleAdEditor:  "Редактор объявлений",
+	models.ManagerRoleViewer:    "Наблюдатель",
+}
+
+var rolePermissions = map[string][]permission{
+	models.ManagerRoleOwner:     {permView, permManageAds, permManageBlacklist, permManageManagers, permManagePayments, permManagePremiumSlots, permManageDeals},
+	models.ManagerRoleModerator: {permView, permManageBlacklist, permManageDeals},
+	models.ManagerRoleAdEditor:  {permView, permManageAds},
+	models.ManagerRoleViewer:    {permView},
+}
+
+func roleAllows(role string, perm permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// callbackPermissions — право, нужное для callback с точным совпадением данных
+var callbackPermissions = map[string]permission{
+	"menu_main":               permView,
+	"menu_find_ad":            permView,
+	"menu_blacklist":          permView,
+	"blacklist_view":          permView,
+	"blacklist_history":       permView,
+	"menu_moderation":         permView,
+	"menu_reports":            permView,
+	"menu_client_requests":    permView,
+	"menu_reviews":            permView,
+	"menu_deals":              permView,
+	"blacklist_add":           permManageBlacklist,
+	"blacklist_remove":        permManageBlacklist,
+	"blacklist_evidence_done": permManageBlacklist,
+	"moderation_approve":      permManageAds,
+	"moderation_reject":       permManageAds,
+	"menu_new_ad":             permManageAds,
+	"save_from_settings":      permManageAds,
+	"confirm_yes":             permManageAds,
+	"confirm_no":              permManageAds,
+	"back":                    permManageAds,
+	"skip_photo":              permManageAds,
+	"photos_done":             permManageAds,
+	"photos_clear":            permManageAds,
+	"skip_user_id":            permManageAds,
+	"skip_username":           permManageAds,
+	"ad_edit":                 permManageAds,
+	"ad_renew":                permManageAds,
+	"ad_remove":               permManageAds,
+	"ad_publish":              permManageAds,
+	"edit_after_preview":      permManageAds,
+	"menu_managers":           permManageManagers,
+}
+
+// callbackPrefixPermissions — право для callback с параметром в данных.
+// Callback, не найденный ни в одном списке, доступен только владельцу.
+var callbackPrefixPermissions = []struct {
+	prefix string
+	perm   permission
+}{
+	{"moderation_page_", permView},
+	{"report_page_", permView},
+	{"select_ad_", permView},
+	{"ad_action_", permView},
+	{"ad_history_", permView},
+	{"report_accept_", permManageBlacklist},
+	{"report_dismiss_", permManageBlacklist},
+	{"review_", permManageBlacklist},
+	{"deal_view_", permView},
+	{"deal_new_", permManageDeals},
+	{"deal_to_", permManageDeals},
+	{"renew_duration_", permManageAds},
+	{"client_req_", permManageAds},
+	{"ad_restore_", permManageAds},
+	{"category_", permManageAds},
+	{"mode_", permManageAds},
+	{"tag_", permManageAds},
+	{"duration_", permManageAds},
+	{"premium_", permManageAds},
+	{"manager_", permManageManagers},
+}
+
+func callbackPermission(data string) permission {
+	if perm, ok := callbackPermissions[data]; ok {
+		return perm
+	}
+	for _, rule := range callbackPrefixPermissions {
+		if strings.HasPrefix(data, rule.prefix) {
+			return rule.perm
+		}
+	}
+	return permManageManagers
+}
+
+// stagePermission — право, нужное для ввода на этапе диалога
+func stagePermission(stage conversationStage) permission {
+	switch stage {
+	case stageNone, stageAwaitAction, stageAwaitFindAdID, stageAwaitSelectAd, stageAwaitBlacklistHistory:
+		return permView
+	case stageAwaitBlacklistAction, stageAwaitBlacklistAdd, stageAwaitBlacklistRemove,
+		stageAwaitBlacklistReason, stageAwaitBlacklistEvidence:
+		return permManageBlacklist
+	case stageAwaitManagerID:
+		return permManageManagers
+	case stageAwaitDealTerms:
+		return permManageDeals
+	default:
+		return permManageAds
+	}
+}
+
+// loadManager возвращает менеджера с известной ролью; ok=false, если пользователь не менеджер
+func loadManager(userID int64) (*models.Manager, bool) {
+	var manager models.Manager
+	err := db.DB.Where("telegram_id = ?", userID).First(&manager).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Ошибка загрузки роли менеджера %d: %v", userID, err)
+		}
+		return nil, false
+	}
+	if _, known := rolePermissions[manager.Role]; !known {
+		return nil, false
+	}
+	return &manager, true
+}
+
+// IsActiveManager проверяет, что пользователь — менеджер с известной ролью.
+// Используется в middleware.RequireManager для маршрутов /api/manager.
+func IsActiveManager(userID int64) bool {
+	_, ok := loadManager(userID)
+	return ok
+}
+
+// CanManageAds проверяет право на чужие объявления. Используется в middleware.RequireOwner:
+// наблюдателям и модераторам объявления других пользователей через API не отдаются.
+func CanManageAds(userID int64) bool {
+	return managerCan(userID, permManageAds)
+}
+
+// managerCan проверяет, есть ли у пользователя право perm
+func managerCan(userID int64, perm permission) bool {
+	manager, ok := loadManager(userID)
+	return ok && roleAllows(manager.Role, perm)
+}
+
+// touchManagerUsername запоминает текущий username менеджера для списка в боте
+func touchManagerUsername(manager *models.Manager, username string) {
+	if manager.Username == username {
+		return
+	}
+	if err := db.DB.Model(manager).Update("username", username).Error; err != nil {
+		log.Printf("Ошибка обновления username менеджера %d: %v", manager.TelegramID, err)
+	}
+}
+
+// managersWith возвращает Telegram ID менеджеров с правом perm
+func managersWith(perm permission) ([]int64, error) {
+	var roles []string
+	for _, role := range managerRoles {
+		if roleAllows(role, perm) {
+			roles = append(roles, role)
+		}
+	}
+
+	var ids []int64
+	err := db.DB.Model(&models.Manager{}).Where("role IN ?", roles).Order("telegram_id").Pluck("telegram_id", &ids).Error
+	return ids, err
+}
+
+// bootstrapOwners назначает владельцами пользователей из MANAGER_ID
+func bootstrapOwners(ownerIDs []int64) error {
+	now := time.Now()
+	for _, id := range ownerIDs {
+		err := db.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "telegram_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"role": models.ManagerRoleOwner, "updated_at": now}),
+		}).Create(&models.Manager{TelegramID: id, Role: models.ManagerRoleOwner}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setManagerRole назначает пользователю роль (добавляя его в менеджеры при необходимости)
+func setManagerRole(actorID, telegramID int64, role string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var manager models.Manager
+		err := tx.Where("telegram_id = ?", telegramID).First(&manager).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		before := map[string]interface{}{}
+		if err == nil {
+			before["role"] = manager.Role
+		} else {
+			manager = models.Manager{TelegramID: telegramID, AddedBy: actorID}
+		}
+
+		manager.Role = role
+		if err := tx.Save(&manager).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actorID, auditManagerRole, auditEntityManager, telegramID,
+			before, map[string]interface{}{"role": role})
+	})
+}
+
+// removeManager лишает пользователя доступа к боту. Возвращает false, если он не был менеджером.
+func removeManager(actorID, telegramID int64) (bool, error) {
+	removed := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var manager models.Manager
+		if err := tx.Where("telegram_id = ?", telegramID).First(&manager).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Delete(&manager).Error; err != nil {
+			return err
+		}
+		removed = true
+		return recordAudit(tx, actorID, auditManagerRemove, auditEntityManager, telegramID,
+			map[string]interface{}{"role": manager.Role}, nil)
+	})
+	return removed, err
+}
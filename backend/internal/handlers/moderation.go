@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetModerationQueue отдаёт объявления, ожидающие модерации, — самые старые первыми, как в боте.
+// Доступ проверяет middleware.RequireManager.
+func GetModerationQueue(c *gin.Context) {
+	start := time.Now()
+
+	limit := defaultAdsPageSize
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			metrics.APIRequestsTotal.WithLabelValues("moderation", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "moderation").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if parsed > maxAdsPageSize {
+			parsed = maxAdsPageSize
+		}
+		limit = parsed
+	}
+
+	var ads []models.Ad
+	queryStart := time.Now()
+	err := db.DB.Where("status = ?", models.AdStatusPending).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&ads).Error
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		log.Printf("GetModerationQueue: ошибка БД: %v", err)
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetModerationQueue"})
+		metrics.APIRequestsTotal.WithLabelValues("moderation", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "moderation").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch moderation queue"})
+		return
+	}
+
+	response := make([]AdView, 0, len(ads))
+	for _, ad := range ads {
+		response = append(response, buildAdView(ad))
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("moderation", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("moderation").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusOK, response)
+}
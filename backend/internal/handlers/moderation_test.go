@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/manager/moderation закрыт RequireManager, как в main.go
+func TestModerationQueueRoute(t *testing.T) {
+	managers := map[int64]string{3003: models.ManagerRoleViewer}
+	pending := models.Ad{ID: 7, UserID: 1001, ClientID: "1001", Title: "На модерации", Status: models.AdStatusPending, ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name       string
+		caller     int64
+		wantStatus int
+	}{
+		{"regular user", 1001, http.StatusForbidden},
+		{"manager", 3003, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeDB(t, func(query string, args []driver.Value) (fakeResult, error) {
+				switch {
+				case strings.Contains(query, `FROM "managers"`):
+					return managerRows(managers, args), nil
+				case strings.Contains(query, `FROM "ads"`):
+					return adRows(pending), nil
+				default:
+					return fakeResult{}, nil
+				}
+			})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("user_id", tt.caller)
+				c.Next()
+			})
+			r.GET("/api/manager/moderation", middleware.RequireManager(IsActiveManager), GetModerationQueue)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/manager/moderation", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var views []AdView
+			if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(views) != 1 || views[0].ID != pending.ID {
+				t.Fatalf("queue = %+v, want the pending ad", views)
+			}
+		})
+	}
+}
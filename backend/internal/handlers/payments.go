@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// paymentCurrency — Telegram Stars; для них provider_token не передаётся
+const paymentCurrency = "XTR"
+
+const (
+	defaultPremiumPriceStars = 250
+	defaultDayPriceStars     = 10
+)
+
+// Ошибки проверки покупки; текст показывается пользователю
+var (
+	errPaymentAdUnavailable = errors.New("объявление недоступно для оплаты")
+	errPaymentNotOwner      = errors.New("оплатить можно только своё объявление")
+	errPaymentAlreadyDone   = errors.New("этот счёт уже оплачен или отменён")
+	errPaymentMismatch      = errors.New("счёт устарел, запросите новый")
+	errPaymentPremiumActive = errors.New("объявление уже в премиуме")
+	errPaymentPremiumLimit  = errors.New("все премиум-места заняты, попробуйте позже")
+)
+
+// starsPrice читает цену в звёздах из переменной окружения
+func starsPrice(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// paymentPrice возвращает стоимость покупки в звёздах
+func paymentPrice(product string, days int) int {
+	if product == models.PaymentProductPremium {
+		return starsPrice("STARS_PRICE_PREMIUM", defaultPremiumPriceStars)
+	}
+	return starsPrice("STARS_PRICE_PER_DAY", defaultDayPriceStars) * days
+}
+
+func paymentTitle(payment models.Payment) string {
+	if payment.Product == models.PaymentProductPremium {
+		return "Премиум для объявления"
+	}
+	return fmt.Sprintf("Продление на %d дн.", payment.Days)
+}
+
+func paymentPayload(paymentID uint) string {
+	return fmt.Sprintf("payment:%d", paymentID)
+}
+
+func parsePaymentPayload(payload string) (uint, bool) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(payload, "payment:"), 10, 32)
+	if err != nil || !strings.HasPrefix(payload, "payment:") {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func ownsAd(ad models.Ad, userID int64) bool {
+	return ad.UserID == userID || ad.ClientID == strconv.FormatInt(userID, 10)
+}
+
+// checkPaymentAllowed проверяет, что покупку ещё можно применить к объявлению.
+// Премиум-место считается в tx: при применении оплаты это её транзакция с блокировкой категории.
+func checkPaymentAllowed(tx *gorm.DB, ad models.Ad, payment models.Payment) error {
+	if !ownsAd(ad, payment.UserID) {
+		return errPaymentNotOwner
+	}
+	switch payment.Product {
+	case models.PaymentProductPremium:
+		if ad.Status != models.AdStatusActive || !ad.ExpiresAt.After(time.Now()) {
+			return errPaymentAdUnavailable
+		}
+		if ad.IsPremium {
+			return errPaymentPremiumActive
+		}
+		free, _, err := premiumSlotFree(tx, ad.Category, &ad.ID)
+		if err != nil {
+			return err
+		}
+		if !free {
+			return errPaymentPremiumLimit
+		}
+	case models.PaymentProductExtend:
+		// Снятые менеджером и ждущие модерации объявления продлевать нельзя
+		if ad.Status != models.AdStatusActive && ad.Status != models.AdStatusExpired {
+			return errPaymentAdUnavailable
+		}
+	default:
+		return errPaymentMismatch
+	}
+	return nil
+}
+
+// applyPayment выдаёт оплаченное: премиум или продление от текущего срока (или от сейчас, если он истёк)
+func applyPayment(ad *models.Ad, payment models.Payment) {
+	switch payment.Product {
+	case models.PaymentProductPremium:
+		ad.IsPremium = true
+	case models.PaymentProductExtend:
+		base := ad.ExpiresAt
+		if base.Before(time.Now()) {
+			base = time.Now()
+		}
+		ad.ExpiresAt = base.Add(time.Duration(payment.Days) * 24 * time.Hour)
+		ad.Status = models.AdStatusActive
+		ad.PreExpiryNotified = false
+	}
+}
+
+// revertPayment забирает выданное при возврате
+func revertPayment(ad *models.Ad, payment models.Payment) {
+	switch payment.Product {
+	case models.PaymentProductPremium:
+		ad.IsPremium = false
+	case models.PaymentProductExtend:
+		ad.ExpiresAt = ad.ExpiresAt.Add(-time.Duration(payment.Days) * 24 * time.Hour)
+	}
+}
+
+// sendStarsInvoice отправляет пользователю счёт в Stars. Запрос собираем сами:
+// InvoiceConfig из tgbotapi всегда передаёт provider_token и suggested_tip_amounts,
+// которые для XTR не нужны.
+func sendStarsInvoice(bot *tgbotapi.BotAPI, payment models.Payment, ad models.Ad) error {
+	params := tgbotapi.Params{
+		"chat_id":     strconv.FormatInt(payment.UserID, 10),
+		"title":       paymentTitle(payment),
+		"description": truncate(fmt.Sprintf("Объявление #%d «%s»", ad.ID, ad.Title), 255),
+		"payload":     paymentPayload(payment.ID),
+		"currency":    paymentCurrency,
+	}
+	prices := []tgbotapi.LabeledPrice{{Label: paymentTitle(payment), Amount: payment.Amount}}
+	if err := params.AddInterface("prices", prices); err != nil {
+		return err
+	}
+	_, err := bot.MakeRequest("sendInvoice", params)
+	return err
+}
+
+// refundPayment возвращает звёзды и забирает выданное, если покупка была применена.
+// Запрос к Telegram выполняется под блокировкой строки платежа, чтобы не вернуть деньги дважды.
+func refundPayment(bot *tgbotapi.BotAPI, paymentID uint, actorID int64) (*models.Payment, error) {
+	var payment models.Payment
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error; err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPaid && payment.Status != models.PaymentStatusFailed {
+			return errPaymentAlreadyDone
+		}
+
+		if payment.Status == models.PaymentStatusPaid {
+			var ad models.Ad
+			err := tx.First(&ad, payment.AdID).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				before := ad
+				revertPayment(&ad, payment)
+				if err := tx.Save(&ad).Error; err != nil {
+					return err
+				}
+				if err := saveAdRevision(tx, ad, actorID, nil); err != nil {
+					return err
+				}
+				if err := recordAudit(tx, actorID, auditAdRefund, auditEntityAd, ad.ID, before, ad); err != nil {
+					return err
+				}
+			}
+		}
+
+		now := time.Now()
+		payment.Status = models.PaymentStatusRefunded
+		payment.RefundedAt = &now
+		payment.RefundedBy = actorID
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+
+		return refundStarCharge(bot, payment.UserID, payment.TelegramChargeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if payment.Product == models.PaymentProductPremium {
+		promotePremiumWaitlist(bot)
+	}
+	return &payment, nil
+}
+
+// refundStarCharge возвращает звёзды по charge id из successful_payment
+func refundStarCharge(bot *tgbotapi.BotAPI, userID int64, chargeID string) error {
+	_, err := bot.MakeRequest("refundStarPayment", tgbotapi.Params{
+		"user_id":                    strconv.FormatInt(userID, 10),
+		"telegram_payment_charge_id": chargeID,
+	})
+	return err
+}
+
+// invoiceRequest — тело запроса на счёт из Mini App
+type invoiceRequest struct {
+	Product string `json:"product"`
+	Days    int    `json:"days"`
+}
+
+// CreateInvoice выставляет владельцу объявления счёт в Telegram Stars. Счёт приходит
+// в чат с ботом, покупка применяется после successful_payment.
+func CreateInvoice(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	managerBot.RLock()
+	bot := managerBot.api
+	managerBot.RUnlock()
+	if bot == nil {
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "503").Inc()
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "оплата временно недоступна"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_invoice").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ad id"})
+		return
+	}
+
+	var req invoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_invoice").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	switch req.Product {
+	case models.PaymentProductPremium:
+		req.Days = 0
+	case models.PaymentProductExtend:
+		if !isValidDuration(req.Days) {
+			metrics.APIRequestsTotal.WithLabelValues("create_invoice", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "create_invoice").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidDuration.Error()})
+			return
+		}
+	default:
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_invoice").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product must be premium or extend"})
+		return
+	}
+
+	var ad models.Ad
+	if err := db.DB.First(&ad, uint(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.APIRequestsTotal.WithLabelValues("create_invoice", "404").Inc()
+			c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+			return
+		}
+		middleware.CaptureError(c, err, map[string]string{
+			"handler": "CreateInvoice",
+			"ad_id":   strconv.FormatUint(id, 10),
+		})
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_invoice").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch ad"})
+		return
+	}
+
+	payment := models.Payment{
+		AdID:     ad.ID,
+		UserID:   userID,
+		Product:  req.Product,
+		Days:     req.Days,
+		Amount:   paymentPrice(req.Product, req.Days),
+		Currency: paymentCurrency,
+		Status:   models.PaymentStatusPending,
+	}
+	if err := checkPaymentAllowed(db.DB, ad, payment); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errPaymentNotOwner) {
+			status = http.StatusForbidden
+		} else if !isPaymentCheckError(err) {
+			middleware.CaptureError(c, err, map[string]string{"handler": "CreateInvoice"})
+			metrics.ErrorsTotal.WithLabelValues("database", "create_invoice").Inc()
+			status = http.StatusInternalServerError
+		}
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", strconv.Itoa(status)).Inc()
+		c.JSON(status, gin.H{"error": paymentErrorMessage(err)})
+		return
+	}
+
+	queryStart := time.Now()
+	if err := db.DB.Create(&payment).Error; err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateInvoice"})
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_invoice").Inc()
+		metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось выставить счёт. Попробуйте позже."})
+		return
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+
+	if err := sendStarsInvoice(bot, payment, ad); err != nil {
+		log.Printf("CreateInvoice: не удалось отправить счёт %d пользователю %d: %v", payment.ID, userID, err)
+		middleware.CaptureError(c, err, map[string]string{
+			"handler":    "CreateInvoice",
+			"payment_id": strconv.FormatUint(uint64(payment.ID), 10),
+		})
+		metrics.APIRequestsTotal.WithLabelValues("create_invoice", "502").Inc()
+		c.JSON(http.StatusBadGateway, gin.H{"error": "не удалось отправить счёт. Напишите боту /start и попробуйте снова."})
+		return
+	}
+
+	log.Printf("CreateInvoice: счёт %d на %d XTR (%s) по объявлению %d отправлен пользователю %d", payment.ID, payment.Amount, payment.Product, ad.ID, userID)
+
+	metrics.APIRequestsTotal.WithLabelValues("create_invoice", "201").Inc()
+	metrics.APIReponseTime.WithLabelValues("create_invoice").Observe(time.Since(start).Seconds())
+
+	c.JSON(http.StatusCreated, gin.H{
+		"payment_id": payment.ID,
+		"amount":     payment.Amount,
+		"currency":   payment.Currency,
+	})
+}
+
+func isPaymentCheckError(err error) bool {
+	for _, known := range []error{
+		errPaymentAdUnavailable, errPaymentNotOwner, errPaymentAlreadyDone,
+		errPaymentMismatch, errPaymentPremiumActive, errPaymentPremiumLimit,
+	} {
+		if errors.Is(err, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentErrorMessage скрывает от пользователя внутренние ошибки
+func paymentErrorMessage(err error) string {
+	if isPaymentCheckError(err) {
+		return err.Error()
+	}
+	return "не удалось проверить объявление, попробуйте позже"
+}
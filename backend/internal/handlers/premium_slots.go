@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultPremiumSlotLimit — лимит премиум-мест для категорий без своей записи в premium_slot_limits
+const defaultPremiumSlotLimit = 3
+
+// maxPremiumSlotLimit ограничивает значение, которое можно задать командой /slots
+const maxPremiumSlotLimit = 50
+
+var (
+	errPremiumLimit  = errors.New("premium limit reached")
+	errPremiumQueued = errors.New("ad already queued for premium")
+	// errPremiumNotEligible — в очередь ставятся только активные объявления без премиума
+	errPremiumNotEligible = errors.New("ad is not eligible for premium queue")
+)
+
+// lockPremiumCategory берёт advisory lock категории до конца транзакции tx: пока он держится,
+// ни одна реплика не выдаст в этой категории ещё одно премиум-место. Вызывается перед premiumSlotFree
+// во всех транзакциях, которые ставят премиум.
+func lockPremiumCategory(tx *gorm.DB, category string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "premium_slots:"+category).Error
+}
+
+// premiumSlotLimit возвращает лимит премиум-мест категории
+func premiumSlotLimit(tx *gorm.DB, category string) (int, error) {
+	var limit models.PremiumSlotLimit
+	err := tx.Where("category = ?", category).Take(&limit).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultPremiumSlotLimit, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return limit.MaxActive, nil
+}
+
+func activePremiumCount(tx *gorm.DB, category string, excludeID *uint) (int64, error) {
+	query := tx.Model(&models.Ad{}).Where("status = ? AND is_premium = ? AND expires_at > ? AND category = ?",
+		models.AdStatusActive, true, time.Now(), category)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// premiumSlotFree проверяет, есть ли в категории свободное премиум-место.
+// excludeID — объявление, которое уже может занимать место и не должно считаться дважды.
+func premiumSlotFree(tx *gorm.DB, category string, excludeID *uint) (bool, int, error) {
+	limit, err := premiumSlotLimit(tx, category)
+	if err != nil {
+		return false, 0, err
+	}
+	count, err := activePremiumCount(tx, category, excludeID)
+	if err != nil {
+		return false, limit, err
+	}
+	return count < int64(limit), limit, nil
+}
+
+// premiumLimitError — в категории не осталось премиум-мест
+type premiumLimitError struct {
+	category string
+	limit    int
+}
+
+func (e premiumLimitError) Error() string {
+	return fmt.Sprintf("все премиум-места в категории «%s» заняты (%d)", categoryLabel(e.category), e.limit)
+}
+
+func (e premiumLimitError) Is(target error) bool {
+	return target == errPremiumLimit
+}
+
+// checkPremiumSlot возвращает premiumLimitError, если объявлению не хватает премиум-места.
+// tx должен быть транзакцией, в которой объявление станет премиум.
+func checkPremiumSlot(tx *gorm.DB, ad models.Ad) error {
+	var exclude *uint
+	if ad.ID != 0 {
+		exclude = &ad.ID
+	}
+	if err := lockPremiumCategory(tx, ad.Category); err != nil {
+		return err
+	}
+	free, limit, err := premiumSlotFree(tx, ad.Category, exclude)
+	if err != nil {
+		return err
+	}
+	if !free {
+		return premiumLimitError{category: ad.Category, limit: limit}
+	}
+	return nil
+}
+
+func premiumLimitText(category string, limit int) string {
+	return fmt.Sprintf("⚠️ Все премиум-места в категории «%s» заняты (лимит %d).", categoryLabel(category), limit)
+}
+
+func categoryLabel(category string) string {
+	if label, ok := categoryLabels[category]; ok {
+		return label
+	}
+	return category
+}
+
+func setPremiumSlotLimit(category string, maxActive int, actorID int64) error {
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_active", "updated_by", "updated_at"}),
+	}).Create(&models.PremiumSlotLimit{
+		Category:  category,
+		MaxActive: maxActive,
+		UpdatedBy: actorID,
+		UpdatedAt: time.Now(),
+	}).Error
+}
+
+// premiumBookingEligible — объявление может ждать премиум, пока оно активно и ещё не в премиуме
+func premiumBookingEligible(ad models.Ad) bool {
+	return ad.Status == models.AdStatusActive && ad.ExpiresAt.After(time.Now()) && !ad.IsPremium
+}
+
+// enqueuePremium ставит объявление в очередь на премиум своей категории
+func enqueuePremium(adID uint, requestedBy int64) (*models.PremiumBooking, error) {
+	var booking models.PremiumBooking
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var ad models.Ad
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ad, adID).Error; err != nil {
+			return err
+		}
+		if !premiumBookingEligible(ad) {
+			return errPremiumNotEligible
+		}
+
+		var open int64
+		if err := tx.Model(&models.PremiumBooking{}).
+			Where("ad_id = ? AND status IN ?", adID, []string{models.PremiumBookingQueued, models.PremiumBookingActive}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return errPremiumQueued
+		}
+
+		booking = models.PremiumBooking{AdID: adID, RequestedBy: requestedBy, Status: models.PremiumBookingQueued}
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, requestedBy, auditAdPremiumQueue, auditEntityAd, adID, nil, booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// cancelPremiumBooking снимает объявление из очереди на премиум
+func cancelPremiumBooking(adID uint, actorID int64) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var booking models.PremiumBooking
+		if err := tx.Where("ad_id = ? AND status = ?", adID, models.PremiumBookingQueued).Take(&booking).Error; err != nil {
+			return err
+		}
+		before := booking
+		booking.Status = models.PremiumBookingCancelled
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actorID, auditAdPremiumUnqueue, auditEntityAd, adID, before, booking)
+	})
+}
+
+// premiumQueueEntry — заявка из очереди вместе с объявлением
+type premiumQueueEntry struct {
+	Booking models.PremiumBooking
+	Ad      models.Ad
+}
+
+// premiumQueue возвращает очередь категории в порядке подачи заявок
+func premiumQueue(category string) ([]premiumQueueEntry, error) {
+	var bookings []models.PremiumBooking
+	if err := db.DB.Joins("JOIN ads ON ads.id = premium_bookings.ad_id AND ads.deleted_at IS NULL").
+		Where("premium_bookings.status = ? AND ads.category = ?", models.PremiumBookingQueued, category).
+		Order("premium_bookings.id").
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	if len(bookings) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(bookings))
+	for _, booking := range bookings {
+		ids = append(ids, booking.AdID)
+	}
+	var ads []models.Ad
+	if err := db.DB.Where("id IN ?", ids).Find(&ads).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Ad, len(ads))
+	for _, ad := range ads {
+		byID[ad.ID] = ad
+	}
+
+	entries := make([]premiumQueueEntry, 0, len(bookings))
+	for _, booking := range bookings {
+		if ad, ok := byID[booking.AdID]; ok {
+			entries = append(entries, premiumQueueEntry{Booking: booking, Ad: ad})
+		}
+	}
+	return entries, nil
+}
+
+// premiumQueueStatus — место объявления в очереди (с единицы) и ориентировочное начало окна
+type premiumQueueStatus struct {
+	Position int
+	StartsAt *time.Time
+}
+
+// adPremiumQueueStatus возвращает место объявления в очереди; ok == false, если его там нет
+func adPremiumQueueStatus(ad models.Ad) (premiumQueueStatus, bool, error) {
+	entries, err := premiumQueue(ad.Category)
+	if err != nil {
+		return premiumQueueStatus{}, false, err
+	}
+	for i, entry := range entries {
+		if entry.Ad.ID == ad.ID {
+			startsAt, err := estimatePremiumStart(ad.Category, i)
+			if err != nil {
+				return premiumQueueStatus{}, false, err
+			}
+			return premiumQueueStatus{Position: i + 1, StartsAt: startsAt}, true, nil
+		}
+	}
+	return premiumQueueStatus{}, false, nil
+}
+
+// estimatePremiumStart оценивает, когда освободится место для index-й заявки очереди (с нуля):
+// места освобождаются по мере окончания сроков текущих премиум-объявлений.
+// nil — место свободно уже сейчас или оценить нельзя, потому что лимит равен нулю.
+func estimatePremiumStart(category string, index int) (*time.Time, error) {
+	limit, err := premiumSlotLimit(db.DB, category)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var active []models.Ad
+	if err := db.DB.Where("status = ? AND is_premium = ? AND expires_at > ? AND category = ?",
+		models.AdStatusActive, true, time.Now(), category).
+		Find(&active).Error; err != nil {
+		return nil, err
+	}
+
+	// Моделируем очередь: каждое освободившееся место занимает следующая заявка
+	// и держит его до конца срока своего объявления
+	ends := make([]time.Time, 0, len(active))
+	for _, ad := range active {
+		ends = append(ends, ad.ExpiresAt)
+	}
+	entries, err := premiumQueue(category)
+	if err != nil {
+		return nil, err
+	}
+	free := limit - len(ends)
+	for i := 0; i <= index && i < len(entries); i++ {
+		if free > 0 {
+			free--
+			if i == index {
+				return nil, nil
+			}
+		} else {
+			sort.Slice(ends, func(a, b int) bool { return ends[a].Before(ends[b]) })
+			startsAt := ends[0]
+			ends = ends[1:]
+			if i == index {
+				return &startsAt, nil
+			}
+		}
+		ends = append(ends, entries[i].Ad.ExpiresAt)
+	}
+	return nil, nil
+}
+
+// promotePremiumWaitlist закрывает окна закончившихся премиум-объявлений и поднимает из очереди
+// столько объявлений, сколько в каждой категории свободно мест. Вызывается после истечения срока,
+// снятия объявления или премиума и по расписанию.
+func promotePremiumWaitlist(bot *tgbotapi.BotAPI) {
+	now := time.Now()
+	stillPremium := db.DB.Model(&models.Ad{}).Select("id").
+		Where("status = ? AND is_premium = ? AND expires_at > ?", models.AdStatusActive, true, now)
+	if err := db.DB.Model(&models.PremiumBooking{}).
+		Where("status = ? AND ad_id NOT IN (?)", models.PremiumBookingActive, stillPremium).
+		Updates(map[string]interface{}{"status": models.PremiumBookingFinished, "ends_at": now}).Error; err != nil {
+		log.Printf("Ошибка закрытия премиум-окон: %v", err)
+	}
+
+	var bookings []models.PremiumBooking
+	if err := db.DB.Where("status = ?", models.PremiumBookingQueued).Order("id").Find(&bookings).Error; err != nil {
+		log.Printf("Ошибка загрузки очереди на премиум: %v", err)
+		return
+	}
+
+	// Категории, где мест уже нет: дальше по очереди в них не смотрим
+	full := make(map[string]bool)
+	for _, booking := range bookings {
+		ad, promoted, err := promotePremiumBooking(booking, full)
+		if err != nil {
+			log.Printf("Ошибка подъёма заявки %d на премиум: %v", booking.ID, err)
+			continue
+		}
+		if !promoted {
+			continue
+		}
+
+		log.Printf("Объявление %d поднято из очереди в премиум (заявка %d)", ad.ID, booking.ID)
+		if bot == nil {
+			continue
+		}
+		notifyUser(bot, ad.UserID, fmt.Sprintf("⭐ Освободилось премиум-место: ваше объявление «%s» теперь в премиуме до %s.", ad.Title, ad.ExpiresAt.Format("02.01.2006")))
+		if booking.RequestedBy != 0 && booking.RequestedBy != ad.UserID {
+			notifyUser(bot, booking.RequestedBy, fmt.Sprintf("⭐ Объявление #%d «%s» поднято из очереди в премиум.", ad.ID, ad.Title))
+		}
+	}
+}
+
+// promotePremiumBooking поднимает одну заявку, если для неё есть место. Заявки объявлений,
+// которые уже не могут стать премиум (сняты, истекли, удалены), отменяются.
+func promotePremiumBooking(booking models.PremiumBooking, full map[string]bool) (models.Ad, bool, error) {
+	var ad models.Ad
+	promoted := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Очередь могла обработать другая реплика: работаем только с ещё ждущей заявкой
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, booking.ID).Error; err != nil {
+			return err
+		}
+		if booking.Status != models.PremiumBookingQueued {
+			return nil
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ad, booking.AdID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err != nil || !premiumBookingEligible(ad) {
+			log.Printf("Заявка %d на премиум отменена: объявление %d больше не активно или уже в премиуме", booking.ID, booking.AdID)
+			return tx.Model(&booking).Update("status", models.PremiumBookingCancelled).Error
+		}
+		if full[ad.Category] {
+			return nil
+		}
+
+		if err := lockPremiumCategory(tx, ad.Category); err != nil {
+			return err
+		}
+		free, _, err := premiumSlotFree(tx, ad.Category, &ad.ID)
+		if err != nil {
+			return err
+		}
+		if !free {
+			full[ad.Category] = true
+			return nil
+		}
+
+		before := ad
+		ad.IsPremium = true
+		if err := tx.Save(&ad).Error; err != nil {
+			return err
+		}
+		if err := saveAdRevision(tx, ad, 0, nil); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, 0, auditAdPremiumPromote, auditEntityAd, ad.ID, before, ad); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		endsAt := ad.ExpiresAt
+		booking.Status = models.PremiumBookingActive
+		booking.StartsAt = &now
+		booking.EndsAt = &endsAt
+		promoted = true
+		return tx.Save(&booking).Error
+	})
+	return ad, promoted, err
+}
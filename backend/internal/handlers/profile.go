@@ -1,56 +1,299 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"youtube-market/internal/db"
 	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
 	"youtube-market/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func GetProfileAds(c *gin.Context) {
+// profilePremiumHistoryLimit — сколько последних периодов премиума отдаёт профиль
+const profilePremiumHistoryLimit = 20
+
+// Значки профиля, которые выдаются по решениям менеджеров
+const (
+	profileBadgeManager       = "manager"
+	profileBadgeVerifiedDeals = "verified_deals"
+)
+
+var profileBadgeLabels = map[string]string{
+	profileBadgeManager:       "Менеджер биржи",
+	profileBadgeVerifiedDeals: "Подтверждённые сделки",
+}
+
+// ProfileStats — сколько объявлений у пользователя
+type ProfileStats struct {
+	Active  int64 `json:"active"`
+	Expired int64 `json:"expired"`
+	Total   int64 `json:"total"`
+}
+
+// PremiumPeriod — период, когда объявление пользователя было в премиуме
+type PremiumPeriod struct {
+	AdID     uint       `json:"ad_id"`
+	Source   string     `json:"source"` // payment — оплата в Stars, queue — место из очереди
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// ProfileBlacklist — активная запись чёрного списка
+type ProfileBlacklist struct {
+	Reason   string    `json:"reason"`
+	ListedAt time.Time `json:"listed_at"`
+}
+
+// ProfileBadge — значок, подтверждённый менеджером
+type ProfileBadge struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// Profile — ответ GET /api/profile/:username и GET /api/profile
+type Profile struct {
+	Username       string            `json:"username"`
+	TelegramID     int64             `json:"telegram_id,omitempty"`
+	FirstSeenAt    *time.Time        `json:"first_seen_at,omitempty"`
+	Stats          ProfileStats      `json:"stats"`
+	PremiumHistory []PremiumPeriod   `json:"premium_history"`
+	Blacklisted    bool              `json:"blacklisted"`
+	Blacklist      *ProfileBlacklist `json:"blacklist,omitempty"`
+	Badges         []ProfileBadge    `json:"badges"`
+	Reputation     *Reputation       `json:"reputation,omitempty"`
+	Ads            []AdView          `json:"ads"`
+	Page           int               `json:"page"`
+	Limit          int               `json:"limit"`
+	HasMore        bool              `json:"has_more"`
+}
+
+// GetProfile возвращает профиль пользователя по username, а без username — профиль вызывающего.
+// Объявления отдаются страницами: ?page= (с единицы) и ?limit=.
+func GetProfile(c *gin.Context) {
 	start := time.Now()
-	username := strings.TrimSpace(c.Param("username"))
-	if username == "" {
-		metrics.APIRequestsTotal.WithLabelValues("profile", "400").Inc()
+	reject := func(status int, message string) {
+		metrics.APIRequestsTotal.WithLabelValues("profile", strconv.Itoa(status)).Inc()
 		metrics.ErrorsTotal.WithLabelValues("validation", "profile").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "username parameter is required"})
-		return
+		c.JSON(status, gin.H{"error": message})
 	}
 
-	username = strings.TrimPrefix(username, "@")
+	page, limit := 1, defaultAdsPageSize
+	if raw := strings.TrimSpace(c.Query("page")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			reject(http.StatusBadRequest, "page must be a positive integer")
+			return
+		}
+		page = parsed
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			reject(http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if parsed > maxAdsPageSize {
+			parsed = maxAdsPageSize
+		}
+		limit = parsed
+	}
 
+	profile := Profile{Page: page, Limit: limit}
+	// Свой профиль: пользователь берётся из initData, видны и объявления на модерации
+	own := false
 	queryStart := time.Now()
+	if raw := strings.TrimSpace(c.Param("username")); raw != "" {
+		profile.Username = normalizeUsername(raw)
+		if profile.Username == "" {
+			reject(http.StatusBadRequest, "username parameter is required")
+			return
+		}
+		id, err := profileUserID(profile.Username)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			profileFailed(c, err, "user")
+			return
+		}
+		profile.TelegramID = id
+	} else {
+		userID, ok := middleware.AuthUserID(c)
+		if !ok {
+			metrics.APIRequestsTotal.WithLabelValues("profile", "401").Inc()
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			return
+		}
+		profile.TelegramID = userID
+		profile.Username = normalizeUsername(middleware.AuthUsername(c))
+	}
+	if authID, ok := middleware.AuthUserID(c); ok && profile.TelegramID != 0 && authID == profile.TelegramID {
+		own = true
+	}
+
+	adsQuery := func() *gorm.DB {
+		query := db.DB.Model(&models.Ad{})
+		switch {
+		case profile.TelegramID != 0 && profile.Username != "":
+			query = query.Where("(LOWER(username) = LOWER(?) OR user_id = ?)", profile.Username, profile.TelegramID)
+		case profile.TelegramID != 0:
+			query = query.Where("user_id = ?", profile.TelegramID)
+		default:
+			query = query.Where("LOWER(username) = LOWER(?)", profile.Username)
+		}
+		// Объявления на модерации ещё никто не видел
+		if !own {
+			query = query.Where("status <> ?", models.AdStatusPending)
+		}
+		return query
+	}
+
+	now := time.Now()
+	if err := adsQuery().
+		Select(`COUNT(*) FILTER (WHERE status = ? AND expires_at > ?) AS active,
+			COUNT(*) FILTER (WHERE status = ? OR (status = ? AND expires_at <= ?)) AS expired,
+			COUNT(*) AS total`,
+			models.AdStatusActive, now, models.AdStatusExpired, models.AdStatusActive, now).
+		Scan(&profile.Stats).Error; err != nil {
+		profileFailed(c, err, "stats")
+		return
+	}
+
 	var ads []models.Ad
-	if err := db.DB.
-		Where("LOWER(username) = LOWER(?)", username).
-		Order(gorm.Expr("CASE WHEN status = ? THEN 0 WHEN status = ? THEN 1 ELSE 2 END, updated_at DESC", models.AdStatusActive, models.AdStatusExpired)).
+	if err := adsQuery().
+		Order(gorm.Expr("CASE WHEN status = ? THEN 0 WHEN status = ? THEN 1 ELSE 2 END, updated_at DESC, id DESC", models.AdStatusActive, models.AdStatusExpired)).
+		Offset((page - 1) * limit).
+		Limit(limit + 1).
 		Find(&ads).Error; err != nil {
-		metrics.APIRequestsTotal.WithLabelValues("profile", "500").Inc()
-		metrics.ErrorsTotal.WithLabelValues("database", "profile").Inc()
-		metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch profile ads"})
+		profileFailed(c, err, "ads")
 		return
 	}
+	if len(ads) > limit {
+		ads = ads[:limit]
+		profile.HasMore = true
+	}
+
+	history, err := profilePremiumHistory(adsQuery)
+	if err != nil {
+		profileFailed(c, err, "premium")
+		return
+	}
+	profile.PremiumHistory = history
+
+	user, err := findBlacklistUser(db.DB, blacklistTarget{Username: profile.Username, TelegramID: profile.TelegramID}, true)
+	switch {
+	case err == nil:
+		entries, err := activeBlacklistEntries([]int64{user.ID})
+		if err != nil {
+			profileFailed(c, err, "blacklist")
+			return
+		}
+		entry, ok := entries[user.ID]
+		profile.Blacklisted = true
+		profile.Blacklist = &ProfileBlacklist{Reason: entry.Reason, ListedAt: blacklistListedAt(*user, entry, ok)}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		profileFailed(c, err, "blacklist")
+		return
+	}
+
+	profile.Badges = []ProfileBadge{}
+	if profile.TelegramID != 0 {
+		firstSeen, err := userFirstSeen(profile.TelegramID)
+		if err != nil {
+			profileFailed(c, err, "first_seen")
+			return
+		}
+		if !firstSeen.IsZero() {
+			profile.FirstSeenAt = &firstSeen
+		}
+
+		reputations, err := loadReputations([]int64{profile.TelegramID})
+		if err != nil {
+			profileFailed(c, err, "reputation")
+			return
+		}
+		if reputation, ok := reputations[profile.TelegramID]; ok {
+			profile.Reputation = &reputation
+			if reputation.VerifiedDeals > 0 {
+				profile.Badges = append(profile.Badges, ProfileBadge{Code: profileBadgeVerifiedDeals, Label: profileBadgeLabels[profileBadgeVerifiedDeals]})
+			}
+		}
+		if _, ok := loadManager(profile.TelegramID); ok {
+			profile.Badges = append(profile.Badges, ProfileBadge{Code: profileBadgeManager, Label: profileBadgeLabels[profileBadgeManager]})
+		}
+	}
 	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
 
-	now := time.Now()
-	response := make([]AdView, 0, len(ads))
+	profile.Ads = make([]AdView, 0, len(ads))
 	for _, ad := range ads {
 		// Ensure status reflects current expiration
 		if ad.Status == models.AdStatusActive && ad.ExpiresAt.Before(now) {
 			ad.Status = models.AdStatusExpired
 		}
-		response = append(response, buildAdView(ad))
+		profile.Ads = append(profile.Ads, buildAdView(ad))
 	}
+	attachReputations(profile.Ads, ads)
 
 	// Собираем метрики
 	metrics.APIRequestsTotal.WithLabelValues("profile", "200").Inc()
 	metrics.APIReponseTime.WithLabelValues("profile").Observe(time.Since(start).Seconds())
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, profile)
+}
+
+// profilePremiumHistory собирает периоды премиума объявлений профиля: оплаты в Stars и места из очереди
+func profilePremiumHistory(adsQuery func() *gorm.DB) ([]PremiumPeriod, error) {
+	history := []PremiumPeriod{}
+
+	var payments []models.Payment
+	if err := db.DB.
+		Where("ad_id IN (?) AND product = ? AND paid_at IS NOT NULL AND status IN ?",
+			adsQuery().Select("id"), models.PaymentProductPremium, []string{models.PaymentStatusPaid, models.PaymentStatusRefunded}).
+		Order("paid_at DESC").
+		Limit(profilePremiumHistoryLimit).
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		period := PremiumPeriod{AdID: payment.AdID, Source: "payment", StartsAt: *payment.PaidAt}
+		switch {
+		case payment.RefundedAt != nil:
+			period.EndsAt = payment.RefundedAt
+		case payment.Days > 0:
+			endsAt := payment.PaidAt.AddDate(0, 0, payment.Days)
+			period.EndsAt = &endsAt
+		}
+		history = append(history, period)
+	}
+
+	var bookings []models.PremiumBooking
+	if err := db.DB.
+		Where("ad_id IN (?) AND starts_at IS NOT NULL AND status IN ?",
+			adsQuery().Select("id"), []string{models.PremiumBookingActive, models.PremiumBookingFinished}).
+		Order("starts_at DESC").
+		Limit(profilePremiumHistoryLimit).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		history = append(history, PremiumPeriod{AdID: booking.AdID, Source: "queue", StartsAt: *booking.StartsAt, EndsAt: booking.EndsAt})
+	}
+
+	// Свежие периоды — первыми
+	sort.Slice(history, func(i, j int) bool { return history[i].StartsAt.After(history[j].StartsAt) })
+	if len(history) > profilePremiumHistoryLimit {
+		history = history[:profilePremiumHistoryLimit]
+	}
+	return history, nil
+}
+
+func profileFailed(c *gin.Context, err error, query string) {
+	middleware.CaptureError(c, err, map[string]string{"handler": "GetProfile", "query": query})
+	metrics.APIRequestsTotal.WithLabelValues("profile", "500").Inc()
+	metrics.ErrorsTotal.WithLabelValues("database", "profile").Inc()
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch profile"})
 }
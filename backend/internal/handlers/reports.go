@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxReportScreenshots    = 5
+	maxReportScreenshotSize = 5 << 20 // 5 МБ
+	maxReportTextLen        = 2048
+)
+
+// reportCategoryLabels — допустимые категории жалоб
+var reportCategoryLabels = map[string]string{
+	"non_payment":    "Не оплатил",
+	"fake_channel":   "Фейковый канал",
+	"stolen_content": "Кража контента",
+	"fake_ad":        "Мошенническое объявление",
+	"other":          "Другое",
+}
+
+var reportScreenshotTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// CreateReport принимает жалобу на мошенника от пользователя Mini App.
+// Тело — multipart/form-data: target_username или ad_id, category, text и до
+// maxReportScreenshots файлов в поле screenshots.
+func CreateReport(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("create_report", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	badRequest := func(message string) {
+		metrics.APIRequestsTotal.WithLabelValues("create_report", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_report").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	}
+
+	report := models.ScamReport{
+		ReporterID:       userID,
+		ReporterUsername: middleware.AuthUsername(c),
+		TargetUsername:   normalizeUsername(c.PostForm("target_username")),
+		Category:         strings.TrimSpace(c.PostForm("category")),
+		Text:             truncate(strings.TrimSpace(c.PostForm("text")), maxReportTextLen),
+		Status:           models.ReportStatusPending,
+	}
+
+	if _, ok := reportCategoryLabels[report.Category]; !ok {
+		badRequest("unknown report category")
+		return
+	}
+	if report.Text == "" {
+		badRequest("text is required")
+		return
+	}
+
+	if rawAdID := strings.TrimSpace(c.PostForm("ad_id")); rawAdID != "" {
+		adID, err := strconv.ParseUint(rawAdID, 10, 32)
+		if err != nil {
+			badRequest("invalid ad_id")
+			return
+		}
+		var ad models.Ad
+		if err := db.DB.First(&ad, uint(adID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				metrics.APIRequestsTotal.WithLabelValues("create_report", "404").Inc()
+				c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+				return
+			}
+			middleware.CaptureError(c, err, map[string]string{
+				"handler": "CreateReport",
+				"ad_id":   rawAdID,
+			})
+			metrics.APIRequestsTotal.WithLabelValues("create_report", "500").Inc()
+			metrics.ErrorsTotal.WithLabelValues("database", "create_report").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch ad"})
+			return
+		}
+		id := ad.ID
+		report.AdID = &id
+		report.TargetTelegramID = ad.UserID
+		if report.TargetUsername == "" {
+			report.TargetUsername = normalizeUsername(ad.Username)
+		}
+	}
+
+	if report.TargetUsername == "" && report.TargetTelegramID == 0 {
+		badRequest("target_username or ad_id is required")
+		return
+	}
+	if report.TargetTelegramID == userID ||
+		(report.ReporterUsername != "" && strings.EqualFold(report.TargetUsername, report.ReporterUsername)) {
+		badRequest("you cannot report yourself")
+		return
+	}
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil && form != nil {
+		files = form.File["screenshots"]
+	}
+	if len(files) > maxReportScreenshots {
+		badRequest(fmt.Sprintf("no more than %d screenshots allowed", maxReportScreenshots))
+		return
+	}
+	for _, header := range files {
+		screenshot, err := readReportScreenshot(header)
+		if err != nil {
+			badRequest(err.Error())
+			return
+		}
+		report.Screenshots = append(report.Screenshots, screenshot)
+	}
+
+	queryStart := time.Now()
+	if err := db.DB.Create(&report).Error; err != nil {
+		log.Printf("CreateReport: ошибка сохранения жалобы: %v", err)
+		middleware.CaptureError(c, err, map[string]string{
+			"handler": "CreateReport",
+		})
+		metrics.APIRequestsTotal.WithLabelValues("create_report", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_report").Inc()
+		metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось сохранить жалобу. Попробуйте позже."})
+		return
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+
+	log.Printf("CreateReport: жалоба ID=%d от пользователя %d на %s", report.ID, userID, reportTargetLabel(report))
+	notifyManagers(permManageBlacklist, fmt.Sprintf("📣 Новая жалоба #%d на %s (%s). Откройте «Жалобы» в меню.", report.ID, reportTargetLabel(report), reportCategoryLabels[report.Category]))
+
+	metrics.APIRequestsTotal.WithLabelValues("create_report", "201").Inc()
+	metrics.APIReponseTime.WithLabelValues("create_report").Observe(time.Since(start).Seconds())
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":     report.ID,
+		"status": report.Status,
+	})
+}
+
+func readReportScreenshot(header *multipart.FileHeader) (models.ReportScreenshot, error) {
+	if header.Size > maxReportScreenshotSize {
+		return models.ReportScreenshot{}, fmt.Errorf("screenshot %q is larger than %d MB", header.Filename, maxReportScreenshotSize>>20)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return models.ReportScreenshot{}, fmt.Errorf("failed to read screenshot %q", header.Filename)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxReportScreenshotSize+1))
+	if err != nil || len(data) == 0 {
+		return models.ReportScreenshot{}, fmt.Errorf("failed to read screenshot %q", header.Filename)
+	}
+	if len(data) > maxReportScreenshotSize {
+		return models.ReportScreenshot{}, fmt.Errorf("screenshot %q is larger than %d MB", header.Filename, maxReportScreenshotSize>>20)
+	}
+
+	contentType := http.DetectContentType(data)
+	if !reportScreenshotTypes[contentType] {
+		return models.ReportScreenshot{}, fmt.Errorf("screenshot %q must be JPEG, PNG or WebP", header.Filename)
+	}
+	return models.ReportScreenshot{ContentType: contentType, Data: data}, nil
+}
+
+// reportTargetLabel — на кого подана жалоба, для сообщений менеджерам и автору
+func reportTargetLabel(report models.ScamReport) string {
+	target := blacklistTarget{Username: report.TargetUsername, TelegramID: report.TargetTelegramID}
+	label := target.String()
+	if report.AdID != nil {
+		label += fmt.Sprintf(", объявление #%d", *report.AdID)
+	}
+	return label
+}
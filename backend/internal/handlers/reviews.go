@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// reviewMinAccountAge — сколько пользователь должен быть известен бирже, чтобы оставлять отзывы
+	reviewMinAccountAge = 7 * 24 * time.Hour
+	maxReviewCommentLen = 1000
+	// reviewListLimit — сколько последних отзывов отдаёт GET /api/reviews
+	reviewListLimit = 50
+)
+
+// Reputation — сводка опубликованных отзывов о пользователе
+type Reputation struct {
+	Average       float64 `json:"average"`
+	Count         int64   `json:"count"`
+	VerifiedDeals int64   `json:"verified_deals"`
+}
+
+// ReviewsPage — ответ GET /api/reviews
+type ReviewsPage struct {
+	UserID     int64           `json:"user_id"`
+	Reputation Reputation      `json:"reputation"`
+	Items      []models.Review `json:"items"`
+}
+
+type reviewRequest struct {
+	AdID uint `json:"ad_id" binding:"required"`
+	// TargetID — о ком отзыв; нужен автору объявления, чтобы оценить покупателя
+	TargetID int64  `json:"target_id"`
+	Rating   int    `json:"rating" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// loadReputations считает репутацию пользователей по опубликованным отзывам и завершённым сделкам.
+// Подтверждённые сделки — сделки через гаранта, где пользователь был продавцом,
+// плюс отзывы, которые менеджер подтвердил вручную без сделки в боте.
+func loadReputations(userIDs []int64) (map[int64]Reputation, error) {
+	result := make(map[int64]Reputation)
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		TargetID      int64
+		Average       float64
+		Count         int64
+		VerifiedDeals int64
+	}
+	err := db.DB.Model(&models.Review{}).
+		Select(`target_id, ROUND(AVG(rating)::numeric, 1) AS average, COUNT(*) AS count,
+			COUNT(*) FILTER (WHERE verified AND NOT EXISTS (
+				SELECT 1 FROM deals WHERE deals.ad_id = reviews.ad_id AND deals.buyer_id = reviews.reviewer_id
+					AND deals.seller_id = reviews.target_id AND deals.status = ?)) AS verified_deals`, models.DealStatusReleased).
+		Where("status = ? AND target_id IN ?", models.ReviewStatusPublished, userIDs).
+		Group("target_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.TargetID] = Reputation{Average: row.Average, Count: row.Count, VerifiedDeals: row.VerifiedDeals}
+	}
+
+	var deals []struct {
+		SellerID int64
+		Count    int64
+	}
+	if err := db.DB.Model(&models.Deal{}).
+		Select("seller_id, COUNT(*) AS count").
+		Where("status = ? AND seller_id IN ?", models.DealStatusReleased, userIDs).
+		Group("seller_id").
+		Scan(&deals).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range deals {
+		reputation := result[row.SellerID]
+		reputation.VerifiedDeals += row.Count
+		result[row.SellerID] = reputation
+	}
+	return result, nil
+}
+
+// attachReputations добавляет к объявлениям репутацию их авторов; views и ads идут в одном порядке.
+// Репутация — дополнительная информация, поэтому ошибка только пишется в лог.
+func attachReputations(views []AdView, ads []models.Ad) {
+	seen := make(map[int64]bool)
+	var userIDs []int64
+	for _, ad := range ads {
+		if ad.UserID != 0 && !seen[ad.UserID] {
+			seen[ad.UserID] = true
+			userIDs = append(userIDs, ad.UserID)
+		}
+	}
+	reputations, err := loadReputations(userIDs)
+	if err != nil {
+		log.Printf("Ошибка загрузки репутации авторов: %v", err)
+		return
+	}
+	for i, ad := range ads {
+		if reputation, ok := reputations[ad.UserID]; ok {
+			views[i].SellerReputation = &reputation
+		}
+	}
+}
+
+// profileUserID находит Telegram ID по username: по объявлениям, затем по визитам в Mini App
+func profileUserID(username string) (int64, error) {
+	var ids []int64
+	if err := db.DB.Model(&models.Ad{}).
+		Where("LOWER(username) = LOWER(?) AND user_id <> 0", username).
+		Order("created_at DESC").
+		Limit(1).
+		Pluck("user_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		if err := db.DB.Model(&models.TelegramUser{}).
+			Where("LOWER(username) = LOWER(?)", username).
+			Order("last_seen_at DESC").
+			Limit(1).
+			Pluck("telegram_id", &ids).Error; err != nil {
+			return 0, err
+		}
+	}
+	if len(ids) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return ids[0], nil
+}
+
+// reviewInteraction проверяет, что автор отзыва и тот, о ком отзыв, взаимодействовали по объявлению:
+// участвовали в сделке через гаранта в любом статусе. Жалобы взаимодействием не считаются — подать
+// её может любой пользователь. released — сделка между ними по объявлению завершена.
+func reviewInteraction(ad models.Ad, reviewerID, targetID int64) (interacted, released bool, err error) {
+	var deals []models.Deal
+	if err := db.DB.Select("id", "status").
+		Where("ad_id = ? AND ((buyer_id = ? AND seller_id = ?) OR (buyer_id = ? AND seller_id = ?))",
+			ad.ID, reviewerID, targetID, targetID, reviewerID).
+		Find(&deals).Error; err != nil {
+		return false, false, err
+	}
+	for _, deal := range deals {
+		if deal.Status == models.DealStatusReleased {
+			released = true
+		}
+	}
+	return len(deals) > 0, released, nil
+}
+
+// CreateReview принимает отзыв об участнике сделки по объявлению: покупатель оценивает автора,
+// автор — покупателя. Отзыв виден после проверки менеджером.
+func CreateReview(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("create_review", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	reject := func(status int, message string) {
+		metrics.APIRequestsTotal.WithLabelValues("create_review", strconv.Itoa(status)).Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_review").Inc()
+		c.JSON(status, gin.H{"error": message})
+	}
+	failed := func(err error, query string) {
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateReview", "query": query})
+		metrics.APIRequestsTotal.WithLabelValues("create_review", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_review").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save review"})
+	}
+
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reject(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		reject(http.StatusBadRequest, "rating must be between 1 and 5")
+		return
+	}
+
+	queryStart := time.Now()
+	var ad models.Ad
+	if err := db.DB.First(&ad, req.AdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.APIRequestsTotal.WithLabelValues("create_review", "404").Inc()
+			c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+			return
+		}
+		failed(err, "ad")
+		return
+	}
+	// Объявление на модерации ещё никто не видел
+	if ad.Status == models.AdStatusPending {
+		metrics.APIRequestsTotal.WithLabelValues("create_review", "404").Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "ad not found"})
+		return
+	}
+	if ad.UserID == 0 {
+		reject(http.StatusBadRequest, "ad author is unknown")
+		return
+	}
+	// Покупатель оценивает автора объявления, автор — покупателя, указанного в target_id
+	targetID := ad.UserID
+	if ownsAd(ad, userID) {
+		if req.TargetID == 0 || req.TargetID == userID {
+			reject(http.StatusBadRequest, "you cannot review yourself")
+			return
+		}
+		targetID = req.TargetID
+	} else if req.TargetID != 0 && req.TargetID != ad.UserID {
+		reject(http.StatusBadRequest, "target_id must be the ad author")
+		return
+	}
+
+	firstSeen, err := userFirstSeen(userID)
+	if err != nil {
+		failed(err, "first_seen")
+		return
+	}
+	if firstSeen.IsZero() || time.Since(firstSeen) < reviewMinAccountAge {
+		reject(http.StatusForbidden, "account is too new to leave reviews")
+		return
+	}
+	_, err = findBlacklistUser(db.DB, blacklistTarget{TelegramID: userID}, true)
+	switch {
+	case err == nil:
+		reject(http.StatusForbidden, "reviews are not allowed for this account")
+		return
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		failed(err, "blacklist")
+		return
+	}
+
+	// Отзыв по сделке, завершённой через гаранта, подтверждён без участия менеджера
+	interacted, releasedDeal, err := reviewInteraction(ad, userID, targetID)
+	if err != nil {
+		failed(err, "interaction")
+		return
+	}
+	if !interacted {
+		reject(http.StatusForbidden, "no interaction over this ad")
+		return
+	}
+
+	review := models.Review{
+		AdID:             ad.ID,
+		ReviewerID:       userID,
+		ReviewerUsername: middleware.AuthUsername(c),
+		TargetID:         targetID,
+		Rating:           req.Rating,
+		Comment:          truncate(strings.TrimSpace(req.Comment), maxReviewCommentLen),
+		Status:           models.ReviewStatusPending,
+		Verified:         releasedDeal,
+	}
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+	if result.Error != nil {
+		failed(result.Error, "insert")
+		return
+	}
+	if result.RowsAffected == 0 {
+		reject(http.StatusConflict, "review for this ad already exists")
+		return
+	}
+	log.Printf("Отзыв %d от пользователя %d о пользователе %d по объявлению %d: %d/5", review.ID, userID, review.TargetID, ad.ID, review.Rating)
+
+	go notifyManagersAboutReview(review, ad)
+
+	metrics.APIRequestsTotal.WithLabelValues("create_review", "201").Inc()
+	metrics.APIReponseTime.WithLabelValues("create_review").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetReviews возвращает репутацию и опубликованные отзывы о пользователе (?user_id= или ?username=)
+func GetReviews(c *gin.Context) {
+	start := time.Now()
+
+	var targetID int64
+	if raw := strings.TrimSpace(c.Query("user_id")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			metrics.APIRequestsTotal.WithLabelValues("reviews", "400").Inc()
+			metrics.ErrorsTotal.WithLabelValues("validation", "reviews").Inc()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		targetID = id
+	} else if username := normalizeUsername(c.Query("username")); username != "" {
+		id, err := profileUserID(username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.APIRequestsTotal.WithLabelValues("reviews", "200").Inc()
+			c.JSON(http.StatusOK, ReviewsPage{Items: []models.Review{}})
+			return
+		}
+		if err != nil {
+			middleware.CaptureError(c, err, map[string]string{"handler": "GetReviews", "query": "user"})
+			metrics.APIRequestsTotal.WithLabelValues("reviews", "500").Inc()
+			metrics.ErrorsTotal.WithLabelValues("database", "reviews").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+			return
+		}
+		targetID = id
+	} else {
+		metrics.APIRequestsTotal.WithLabelValues("reviews", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "reviews").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id or username is required"})
+		return
+	}
+
+	queryStart := time.Now()
+	response := ReviewsPage{UserID: targetID, Items: []models.Review{}}
+	err := db.DB.Where("target_id = ? AND status = ?", targetID, models.ReviewStatusPublished).
+		Order("created_at DESC").
+		Limit(reviewListLimit).
+		Find(&response.Items).Error
+	var reputations map[int64]Reputation
+	if err == nil {
+		reputations, err = loadReputations([]int64{targetID})
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetReviews"})
+		metrics.APIRequestsTotal.WithLabelValues("reviews", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "reviews").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+		return
+	}
+	response.Reputation = reputations[targetID]
+
+	metrics.APIRequestsTotal.WithLabelValues("reviews", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("reviews").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/metrics"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxSubscriptionsPerUser — сколько сохранённых поисков может быть у пользователя
+	maxSubscriptionsPerUser = 10
+	// subscriptionDailyAlertLimit — сколько оповещений в сутки приходит сразу, остальные копятся для дайджеста
+	subscriptionDailyAlertLimit = 5
+	// subscriptionDigestInterval — дайджест приходит не чаще раза в этот период
+	subscriptionDigestInterval = 24 * time.Hour
+	// subscriptionDigestDelay — сколько копятся оповещения, прежде чем уйти дайджестом
+	subscriptionDigestDelay = 3 * time.Hour
+	// subscriptionDigestLimit — сколько объявлений перечисляется в дайджесте
+	subscriptionDigestLimit = 20
+)
+
+// subscriptionRequest — тело POST /api/subscriptions
+type subscriptionRequest struct {
+	Category string `json:"category"`
+	Mode     string `json:"mode"`
+	Tag      string `json:"tag"`
+	Query    string `json:"query"`
+}
+
+// subscriptionView — подписка с человекочитаемым описанием фильтра
+type subscriptionView struct {
+	models.Subscription
+	Label string `json:"label"`
+}
+
+// normalizeSubscription проверяет фильтр подписки и приводит его к виду, в котором он хранится
+func normalizeSubscription(req subscriptionRequest) (models.Subscription, error) {
+	sub := models.Subscription{
+		Category: strings.TrimSpace(req.Category),
+		Mode:     strings.TrimSpace(req.Mode),
+		Tag:      strings.TrimSpace(req.Tag),
+		Query:    truncate(strings.TrimSpace(req.Query), maxSearchQueryLen),
+	}
+	// Тег "all" означает отсутствие фильтра, как и в GET /api/ads
+	if strings.EqualFold(sub.Tag, "all") {
+		sub.Tag = ""
+	}
+	if sub.Category == "other" {
+		sub.Mode = ""
+	}
+
+	if sub.Category == "" {
+		if sub.Mode != "" || sub.Tag != "" {
+			return sub, errors.New("mode and tag require category")
+		}
+		if sub.Query == "" {
+			return sub, errors.New("category or query is required")
+		}
+		return sub, nil
+	}
+	if _, ok := categoryLabels[sub.Category]; !ok {
+		return sub, errors.New("unknown category")
+	}
+	if _, ok := modeLabels[sub.Category][sub.Mode]; sub.Mode != "" && !ok {
+		return sub, errors.New("unknown mode for category")
+	}
+	if _, ok := tagLabels[sub.Category][sub.Tag]; sub.Tag != "" && !ok {
+		return sub, errors.New("unknown tag for category")
+	}
+	return sub, nil
+}
+
+// subscriptionLabel описывает фильтр подписки, например «Купля/Продажа · Продаю · «канал»»
+func subscriptionLabel(sub models.Subscription) string {
+	var parts []string
+	if sub.Category != "" {
+		parts = append(parts, categoryLabel(sub.Category))
+	}
+	if label := modeLabels[sub.Category][sub.Mode]; label != "" {
+		parts = append(parts, label)
+	}
+	if label := tagLabels[sub.Category][sub.Tag]; label != "" {
+		parts = append(parts, label)
+	}
+	if sub.Query != "" {
+		parts = append(parts, "«"+sub.Query+"»")
+	}
+	if len(parts) == 0 {
+		return "все объявления"
+	}
+	return strings.Join(parts, " · ")
+}
+
+func userSubscriptions(userID int64) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	err := db.DB.Where("user_id = ?", userID).Order("id").Find(&subs).Error
+	return subs, err
+}
+
+// GetSubscriptions возвращает сохранённые поиски пользователя
+func GetSubscriptions(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("subscriptions", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	queryStart := time.Now()
+	subs, err := userSubscriptions(userID)
+	metrics.DatabaseQueryDuration.WithLabelValues("select").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetSubscriptions"})
+		metrics.APIRequestsTotal.WithLabelValues("subscriptions", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "subscriptions").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load subscriptions"})
+		return
+	}
+
+	views := make([]subscriptionView, 0, len(subs))
+	for _, sub := range subs {
+		views = append(views, subscriptionView{Subscription: sub, Label: subscriptionLabel(sub)})
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("subscriptions", "200").Inc()
+	metrics.APIReponseTime.WithLabelValues("subscriptions").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusOK, views)
+}
+
+// CreateSubscription сохраняет поиск: категория, режим, тег и необязательный текстовый запрос
+func CreateSubscription(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("create_subscription", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+
+	badRequest := func(status int, message string) {
+		metrics.APIRequestsTotal.WithLabelValues("create_subscription", strconv.Itoa(status)).Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "create_subscription").Inc()
+		c.JSON(status, gin.H{"error": message})
+	}
+
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	sub, err := normalizeSubscription(req)
+	if err != nil {
+		badRequest(http.StatusBadRequest, err.Error())
+		return
+	}
+	sub.UserID = userID
+
+	queryStart := time.Now()
+	existing, err := userSubscriptions(userID)
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateSubscription", "query": "select"})
+		metrics.APIRequestsTotal.WithLabelValues("create_subscription", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_subscription").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save subscription"})
+		return
+	}
+	for _, other := range existing {
+		if other.Category == sub.Category && other.Mode == sub.Mode && other.Tag == sub.Tag && strings.EqualFold(other.Query, sub.Query) {
+			badRequest(http.StatusConflict, "subscription already exists")
+			return
+		}
+	}
+	if len(existing) >= maxSubscriptionsPerUser {
+		badRequest(http.StatusBadRequest, fmt.Sprintf("no more than %d subscriptions allowed", maxSubscriptionsPerUser))
+		return
+	}
+
+	if err := db.DB.Create(&sub).Error; err != nil {
+		metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateSubscription", "query": "insert"})
+		metrics.APIRequestsTotal.WithLabelValues("create_subscription", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "create_subscription").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save subscription"})
+		return
+	}
+	metrics.DatabaseQueryDuration.WithLabelValues("insert").Observe(time.Since(queryStart).Seconds())
+	log.Printf("Пользователь %d подписался на %s (подписка %d)", userID, subscriptionLabel(sub), sub.ID)
+
+	metrics.APIRequestsTotal.WithLabelValues("create_subscription", "201").Inc()
+	metrics.APIReponseTime.WithLabelValues("create_subscription").Observe(time.Since(start).Seconds())
+	c.JSON(http.StatusCreated, subscriptionView{Subscription: sub, Label: subscriptionLabel(sub)})
+}
+
+// deleteSubscription удаляет подписку пользователя; false — такой подписки у него нет
+func deleteSubscription(userID int64, subID uint) (bool, error) {
+	result := db.DB.Where("id = ? AND user_id = ?", subID, userID).Delete(&models.Subscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteSubscription удаляет сохранённый поиск
+func DeleteSubscription(c *gin.Context) {
+	start := time.Now()
+	userID, ok := middleware.AuthUserID(c)
+	if !ok {
+		metrics.APIRequestsTotal.WithLabelValues("delete_subscription", "401").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+		return
+	}
+	subID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		metrics.APIRequestsTotal.WithLabelValues("delete_subscription", "400").Inc()
+		metrics.ErrorsTotal.WithLabelValues("validation", "delete_subscription").Inc()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	queryStart := time.Now()
+	deleted, err := deleteSubscription(userID, uint(subID))
+	metrics.DatabaseQueryDuration.WithLabelValues("delete").Observe(time.Since(queryStart).Seconds())
+	if err != nil {
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteSubscription"})
+		metrics.APIRequestsTotal.WithLabelValues("delete_subscription", "500").Inc()
+		metrics.ErrorsTotal.WithLabelValues("database", "delete_subscription").Inc()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete subscription"})
+		return
+	}
+	if !deleted {
+		metrics.APIRequestsTotal.WithLabelValues("delete_subscription", "404").Inc()
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+
+	metrics.APIRequestsTotal.WithLabelValues("delete_subscription", "204").Inc()
+	metrics.APIReponseTime.WithLabelValues("delete_subscription").Observe(time.Since(start).Seconds())
+	c.Status(http.StatusNoContent)
+}
+
+// matchingSubscriptions находит подписки чужих пользователей, под которые подходит объявление.
+// На каждого пользователя берётся первая подходящая подписка.
+func matchingSubscriptions(ad models.Ad) ([]models.Subscription, error) {
+	var candidates []models.Subscription
+	if err := db.DB.Where("user_id <> ?", ad.UserID).
+		Where("category = '' OR category = ?", ad.Category).
+		Where("mode = '' OR mode = ?", ad.Mode).
+		Where("tag = '' OR tag = ?", ad.Tag).
+		Order("id").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var matched []models.Subscription
+	for _, sub := range candidates {
+		if seen[sub.UserID] {
+			continue
+		}
+		if sub.Query != "" {
+			var count int64
+			if err := applyAdSearch(db.DB.Model(&models.Ad{}).Where("ads.id = ?", ad.ID), sub.Query).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				continue
+			}
+		}
+		seen[sub.UserID] = true
+		matched = append(matched, sub)
+	}
+	return matched, nil
+}
+
+// notifySubscribers оповещает подписчиков о новом объявлении. Сверх суточного лимита
+// оповещения копятся и приходят дайджестом (processSubscriptionDigests).
+func notifySubscribers(bot *tgbotapi.BotAPI, ad models.Ad) {
+	if bot == nil || ad.Status != models.AdStatusActive {
+		return
+	}
+	subs, err := matchingSubscriptions(ad)
+	if err != nil {
+		log.Printf("Ошибка поиска подписок для объявления %d: %v", ad.ID, err)
+		return
+	}
+
+	for _, sub := range subs {
+		alert := models.SubscriptionAlert{SubscriptionID: sub.ID, UserID: sub.UserID, AdID: ad.ID}
+		result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			log.Printf("Ошибка сохранения оповещения для подписки %d: %v", sub.ID, result.Error)
+			continue
+		}
+		// Объявление уже приходило этому пользователю (например, при повторной публикации)
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var sentToday int64
+		if err := db.DB.Model(&models.SubscriptionAlert{}).
+			Where("user_id = ? AND digest = ? AND sent_at > ?", sub.UserID, false, time.Now().Add(-24*time.Hour)).
+			Count(&sentToday).Error; err != nil {
+			log.Printf("Ошибка подсчёта оповещений пользователя %d: %v", sub.UserID, err)
+			continue
+		}
+		if sentToday >= subscriptionDailyAlertLimit {
+			continue
+		}
+
+		msg := tgbotapi.NewMessage(sub.UserID, formatSubscriptionAlert(sub, ad))
+		if _, err := bot.Send(msg); err != nil {
+			log.Printf("failed to notify subscriber %d: %v", sub.UserID, err)
+			// Временную ошибку доставит дайджест, а недоступному пользователю слать нечего
+			if !isPermanentSendError(err) {
+				continue
+			}
+		}
+		if err := db.DB.Model(&alert).Update("sent_at", time.Now()).Error; err != nil {
+			log.Printf("Ошибка отметки оповещения %d: %v", alert.ID, err)
+		}
+	}
+}
+
+func formatSubscriptionAlert(sub models.Subscription, ad models.Ad) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔔 Новое объявление по подписке «%s»\n\n%s", subscriptionLabel(sub), ad.Title)
+	if ad.Desc != "" {
+		b.WriteString("\n\n" + truncate(ad.Desc, 300))
+	}
+	if ad.Username != "" {
+		b.WriteString("\n\nКонтакт: @" + strings.TrimPrefix(ad.Username, "@"))
+	}
+	b.WriteString("\n\nУправление подписками: /subscriptions")
+	return b.String()
+}
+
+// processSubscriptionDigests отправляет накопившиеся сверх лимита оповещения одним сообщением
+func processSubscriptionDigests(bot *tgbotapi.BotAPI) {
+	now := time.Now()
+	var userIDs []int64
+	if err := db.DB.Model(&models.SubscriptionAlert{}).
+		Where("sent_at IS NULL").
+		Group("user_id").
+		Having("MIN(created_at) <= ?", now.Add(-subscriptionDigestDelay)).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		log.Printf("subscription digest scan failed: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		var lastDigest int64
+		if err := db.DB.Model(&models.SubscriptionAlert{}).
+			Where("user_id = ? AND digest = ? AND sent_at > ?", userID, true, now.Add(-subscriptionDigestInterval)).
+			Count(&lastDigest).Error; err != nil {
+			log.Printf("subscription digest check failed for %d: %v", userID, err)
+			continue
+		}
+		if lastDigest > 0 {
+			continue
+		}
+		if err := sendSubscriptionDigest(bot, userID); err != nil {
+			log.Printf("subscription digest failed for %d: %v", userID, err)
+		}
+	}
+}
+
+func sendSubscriptionDigest(bot *tgbotapi.BotAPI, userID int64) error {
+	var alerts []models.SubscriptionAlert
+	if err := db.DB.Where("user_id = ? AND sent_at IS NULL", userID).Order("id").Find(&alerts).Error; err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	alertIDs := make([]uint, 0, len(alerts))
+	adIDs := make([]uint, 0, len(alerts))
+	for _, alert := range alerts {
+		alertIDs = append(alertIDs, alert.ID)
+		adIDs = append(adIDs, alert.AdID)
+	}
+
+	// Снятые и истёкшие за это время объявления в дайджест не попадают
+	var ads []models.Ad
+	if err := db.DB.Where("id IN ? AND status = ? AND expires_at > ?", adIDs, models.AdStatusActive, time.Now()).
+		Order("id").
+		Find(&ads).Error; err != nil {
+		return err
+	}
+
+	if len(ads) > 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "🔔 Новые объявления по вашим подпискам: %d\n", len(ads))
+		for i, ad := range ads {
+			if i == subscriptionDigestLimit {
+				fmt.Fprintf(&b, "\n… и ещё %d", len(ads)-i)
+				break
+			}
+			fmt.Fprintf(&b, "\n• %s — %s", truncate(ad.Title, 60), categoryLabel(ad.Category))
+			if ad.Username != "" {
+				b.WriteString(", @" + strings.TrimPrefix(ad.Username, "@"))
+			}
+		}
+		b.WriteString("\n\nУправление подписками: /subscriptions")
+		if _, err := bot.Send(tgbotapi.NewMessage(userID, b.String())); err != nil {
+			if !isPermanentSendError(err) {
+				return err
+			}
+			// Пользователь заблокировал бота или не начинал с ним диалог: повторять бесполезно,
+			// поэтому оповещения закрываются как отправленные и больше не выбираются
+			log.Printf("Дайджест подписок для %d не доставлен, оповещения закрыты: %v", userID, err)
+		}
+	}
+
+	return db.DB.Model(&models.SubscriptionAlert{}).
+		Where("id IN ?", alertIDs).
+		Updates(map[string]interface{}{"sent_at": time.Now(), "digest": true}).Error
+}
+
+// isPermanentSendError — Telegram отказал навсегда: бот заблокирован или чат не найден
+func isPermanentSendError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusForbidden ||
+		(apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "chat not found"))
+}
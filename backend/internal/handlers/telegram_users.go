@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"youtube-market/internal/db"
+	"youtube-market/internal/middleware"
+	"youtube-market/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// telegramUserTouchInterval — как часто обновляется дата последнего визита пользователя
+const telegramUserTouchInterval = time.Hour
+
+var telegramUserTouches = struct {
+	sync.Mutex
+	seen map[int64]time.Time
+}{seen: make(map[int64]time.Time)}
+
+// TelegramUserMiddleware запоминает, когда пользователь Mini App впервые и в последний раз заходил.
+// Дата первого визита нужна для проверки возраста аккаунта. Должен стоять после TMAuthMiddleware.
+func TelegramUserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := middleware.AuthUserID(c); ok && shouldTouchTelegramUser(userID) {
+			go touchTelegramUser(userID, middleware.AuthUsername(c))
+		}
+		c.Next()
+	}
+}
+
+func shouldTouchTelegramUser(userID int64) bool {
+	now := time.Now()
+	telegramUserTouches.Lock()
+	defer telegramUserTouches.Unlock()
+
+	if last, ok := telegramUserTouches.seen[userID]; ok && now.Sub(last) < telegramUserTouchInterval {
+		return false
+	}
+	telegramUserTouches.seen[userID] = now
+
+	// Не даём карте расти бесконечно
+	if len(telegramUserTouches.seen) > 10000 {
+		for id, last := range telegramUserTouches.seen {
+			if now.Sub(last) >= telegramUserTouchInterval {
+				delete(telegramUserTouches.seen, id)
+			}
+		}
+	}
+	return true
+}
+
+func touchTelegramUser(userID int64, username string) {
+	if db.DB == nil {
+		return
+	}
+	now := time.Now()
+	user := models.TelegramUser{TelegramID: userID, Username: username, FirstSeenAt: now, LastSeenAt: now}
+	if err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"username", "last_seen_at"}),
+	}).Create(&user).Error; err != nil {
+		log.Printf("Не удалось сохранить визит пользователя %d: %v", userID, err)
+	}
+}
+
+// userFirstSeen возвращает, с какого момента пользователь известен бирже: первый визит
+// в Mini App или первое объявление, если оно раньше. Нулевое время — пользователь не встречался.
+func userFirstSeen(userID int64) (time.Time, error) {
+	var firstSeen *time.Time
+	err := db.DB.Raw(`SELECT LEAST(
+		(SELECT first_seen_at FROM telegram_users WHERE telegram_id = ?),
+		(SELECT MIN(created_at) FROM ads WHERE user_id = ? AND deleted_at IS NULL)
+	)`, userID, userID).Scan(&firstSeen).Error
+	if err != nil || firstSeen == nil {
+		return time.Time{}, err
+	}
+	return *firstSeen, nil
+}
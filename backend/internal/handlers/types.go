@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -19,8 +21,63 @@ type AdView struct {
 	Status     string    `json:"status"`
 	ExpiresAt  time.Time `json:"expires_at"`
 	PhotoURL   string    `json:"photo_url,omitempty"`
+	PhotoURLs  []string  `json:"photo_urls,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	// Репутация автора по опубликованным отзывам; нет, если отзывов ещё не было
+	SellerReputation *Reputation `json:"seller_reputation,omitempty"`
+	// Заполняются только при поиске (q): HTML-фрагменты с подсветкой совпадений в <mark>
+	TitleHighlight string `json:"title_highlight,omitempty"`
+	Snippet        string `json:"snippet,omitempty"`
+}
+
+// AdsPage — страница ленты объявлений с курсором на следующую страницу
+type AdsPage struct {
+	Items      []AdView `json:"items"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Total      int64    `json:"total"`
+}
+
+// adsCursor — позиция последнего объявления страницы, кодируется в непрозрачную строку
+type adsCursor struct {
+	Sort      string    `json:"s"`
+	Premium   bool      `json:"p"`
+	UpdatedAt time.Time `json:"u"`
+	ExpiresAt time.Time `json:"e"`
+	Rank      float64   `json:"r,omitempty"`
+	ID        uint      `json:"i"`
+}
+
+func encodeAdsCursor(sort string, row adSearchRow) string {
+	raw, _ := json.Marshal(adsCursor{
+		Sort:      sort,
+		Premium:   row.IsPremium,
+		UpdatedAt: row.UpdatedAt,
+		ExpiresAt: row.ExpiresAt,
+		Rank:      row.SearchRank,
+		ID:        row.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeAdsCursor разбирает курсор; пустая строка означает первую страницу.
+// Курсор от другой сортировки считается некорректным.
+func decodeAdsCursor(value, sort string) (*adsCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor adsCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort || cursor.ID == 0 {
+		return nil, fmt.Errorf("cursor does not match sort %q", sort)
+	}
+	return &cursor, nil
 }
 
 func buildAdView(ad models.Ad) AdView {
@@ -39,8 +96,11 @@ func buildAdView(ad models.Ad) AdView {
 		UpdatedAt: ad.UpdatedAt,
 	}
 
-	if ad.PhotoPath != "" {
-		view.PhotoURL = fmt.Sprintf("/api/ads/%d/photo", ad.ID)
+	for i := 0; i < ad.PhotoCount; i++ {
+		view.PhotoURLs = append(view.PhotoURLs, fmt.Sprintf("/api/ads/%d/photos/%d", ad.ID, i))
+	}
+	if len(view.PhotoURLs) > 0 {
+		view.PhotoURL = view.PhotoURLs[0]
 	}
 
 	return view
@@ -56,3 +56,25 @@ func TMAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// AuthUserID возвращает Telegram ID пользователя, проверенный TMAuthMiddleware
+func AuthUserID(c *gin.Context) (int64, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(int64)
+	if !ok || userID == 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// AuthUsername возвращает username пользователя из init_data (может быть пустым)
+func AuthUsername(c *gin.Context) string {
+	value, exists := c.Get("username")
+	if !exists {
+		return ""
+	}
+	username, _ := value.(string)
+	return username
+}
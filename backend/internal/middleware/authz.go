@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ManagerChecker проверяет, есть ли у пользователя права менеджера (для RequireOwner — на чтение чужих данных)
+type ManagerChecker func(userID int64) bool
+
+// RequireManager пропускает только менеджеров. Ставится после TMAuthMiddleware.
+func RequireManager(isManager ManagerChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := AuthUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			c.Abort()
+			return
+		}
+		if !isManager(userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "manager access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOwner проверяет доступ к данным пользователя из query-параметра param.
+// Без параметра запрос относится к самому пользователю из init_data; чужой ID можно
+// передать только тем, кого пропускает canReadOthers. Итоговый владелец доступен обработчику через OwnerID.
+func RequireOwner(param string, canReadOthers ManagerChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := AuthUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			c.Abort()
+			return
+		}
+
+		ownerID := userID
+		if raw := strings.TrimSpace(c.Query(param)); raw != "" {
+			requested, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || requested <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a positive integer"})
+				c.Abort()
+				return
+			}
+			if requested != userID && !canReadOthers(userID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "access to another user's data is not allowed"})
+				c.Abort()
+				return
+			}
+			ownerID = requested
+		}
+
+		c.Set("owner_id", ownerID)
+		c.Next()
+	}
+}
+
+// OwnerID возвращает владельца данных, проверенного RequireOwner
+func OwnerID(c *gin.Context) (int64, bool) {
+	value, exists := c.Get("owner_id")
+	if !exists {
+		return 0, false
+	}
+	ownerID, ok := value.(int64)
+	if !ok || ownerID == 0 {
+		return 0, false
+	}
+	return ownerID, true
+}
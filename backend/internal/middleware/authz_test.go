@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testManagerID int64 = 3003
+
+func testManagers(userID int64) bool {
+	return userID == testManagerID
+}
+
+// newAuthzRouter эмулирует TMAuthMiddleware: user_id берётся из заголовка X-Test-User
+func newAuthzRouter(guard gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if raw := c.GetHeader("X-Test-User"); raw != "" {
+			id, _ := strconv.ParseInt(raw, 10, 64)
+			c.Set("user_id", id)
+		}
+		c.Next()
+	})
+	r.GET("/check", guard, func(c *gin.Context) {
+		ownerID, _ := OwnerID(c)
+		c.JSON(http.StatusOK, gin.H{"owner_id": ownerID})
+	})
+	return r
+}
+
+func doAuthzRequest(r *gin.Engine, userHeader, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/check"+query, nil)
+	if userHeader != "" {
+		req.Header.Set("X-Test-User", userHeader)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireOwner(t *testing.T) {
+	r := newAuthzRouter(RequireOwner("user_id", testManagers))
+
+	tests := []struct {
+		name       string
+		user       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"unauthenticated", "", "", http.StatusUnauthorized, ""},
+		{"unauthenticated with user_id", "", "?user_id=1001", http.StatusUnauthorized, ""},
+		{"own data without param", "1001", "", http.StatusOK, `{"owner_id":1001}`},
+		{"own data with own user_id", "1001", "?user_id=1001", http.StatusOK, `{"owner_id":1001}`},
+		{"another user's data", "1001", "?user_id=2002", http.StatusForbidden, ""},
+		{"second user reads the first one", "2002", "?user_id=1001", http.StatusForbidden, ""},
+		{"invalid user_id", "1001", "?user_id=abc", http.StatusBadRequest, ""},
+		{"negative user_id", "1001", "?user_id=-5", http.StatusBadRequest, ""},
+		{"manager reads another user", "3003", "?user_id=2002", http.StatusOK, `{"owner_id":2002}`},
+		{"manager without param", "3003", "", http.StatusOK, `{"owner_id":3003}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAuthzRequest(r, tt.user, tt.query)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRequireManager(t *testing.T) {
+	r := newAuthzRouter(RequireManager(testManagers))
+
+	tests := []struct {
+		name       string
+		user       string
+		wantStatus int
+	}{
+		{"unauthenticated", "", http.StatusUnauthorized},
+		{"regular user", "1001", http.StatusForbidden},
+		{"manager", "3003", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doAuthzRequest(r, tt.user, ""); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireOwnerDoesNotCheckManagersForOwnData(t *testing.T) {
+	calls := 0
+	r := newAuthzRouter(RequireOwner("user_id", func(int64) bool {
+		calls++
+		return false
+	}))
+	if w := doAuthzRequest(r, "1001", "?user_id=1001"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if calls != 0 {
+		t.Fatalf("manager lookup called %d times for own data", calls)
+	}
+}
+
+func TestOwnerIDWithoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if _, ok := OwnerID(c); ok {
+		t.Fatal("OwnerID must be empty without RequireOwner")
+	}
+}
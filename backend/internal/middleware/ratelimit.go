@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 	"youtube-market/internal/metrics"
 
@@ -12,7 +13,13 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-var rdb *redis.Client
+var (
+	rdb        *redis.Client
+	redisReady bool
+
+	rateLimitExcluded   = make(map[string]struct{})
+	rateLimitExcludedMu sync.RWMutex
+)
 
 // InitRedis инициализирует подключение к Redis
 func InitRedis() error {
@@ -40,9 +47,33 @@ func InitRedis() error {
 		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
+	redisReady = true
 	return nil
 }
 
+// RedisClient возвращает клиент Redis или nil, если подключение не установлено
+func RedisClient() *redis.Client {
+	if !redisReady {
+		return nil
+	}
+	return rdb
+}
+
+// ExcludeFromRateLimit исключает путь из ограничения частоты запросов
+// (например, для входящих webhook-запросов Telegram)
+func ExcludeFromRateLimit(path string) {
+	rateLimitExcludedMu.Lock()
+	rateLimitExcluded[path] = struct{}{}
+	rateLimitExcludedMu.Unlock()
+}
+
+func isRateLimitExcluded(path string) bool {
+	rateLimitExcludedMu.RLock()
+	defer rateLimitExcludedMu.RUnlock()
+	_, ok := rateLimitExcluded[path]
+	return ok
+}
+
 // RateLimitMiddleware ограничивает количество запросов: 60 запросов в минуту на IP
 // Исключает статические ресурсы и метрики из лимита
 func RateLimitMiddleware() gin.HandlerFunc {
@@ -53,7 +84,8 @@ func RateLimitMiddleware() gin.HandlerFunc {
 		   path == "/terms" || path == "/privacy" ||
 		   path == "/" || 
 		   len(path) > 7 && path[:7] == "/static" ||
-		   len(path) > 7 && path[:7] == "/assets" {
+		   len(path) > 7 && path[:7] == "/assets" ||
+		   isRateLimitExcluded(path) {
 			c.Next()
 			return
 		}
@@ -97,3 +129,87 @@ func RateLimitMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// localUserLimits — счётчики UserRateLimit на случай, когда Redis недоступен
+var localUserLimits = struct {
+	sync.Mutex
+	counters map[string]localUserCounter
+}{counters: make(map[string]localUserCounter)}
+
+type localUserCounter struct {
+	count   int64
+	resetAt time.Time
+}
+
+func incrementLocalUserLimit(key string, window time.Duration) (int64, time.Duration) {
+	now := time.Now()
+	localUserLimits.Lock()
+	defer localUserLimits.Unlock()
+
+	counter, ok := localUserLimits.counters[key]
+	if !ok || now.After(counter.resetAt) {
+		counter = localUserCounter{resetAt: now.Add(window)}
+	}
+	counter.count++
+	localUserLimits.counters[key] = counter
+
+	// Удаляем истёкшие счётчики, чтобы карта не росла
+	if len(localUserLimits.counters) > 10000 {
+		for k, c := range localUserLimits.counters {
+			if now.After(c.resetAt) {
+				delete(localUserLimits.counters, k)
+			}
+		}
+	}
+	return counter.count, counter.resetAt.Sub(now)
+}
+
+// UserRateLimit ограничивает число запросов авторизованного пользователя к группе маршрутов scope:
+// не более limit запросов за window. Должен стоять после TMAuthMiddleware.
+// Без Redis счётчики ведутся в памяти процесса.
+func UserRateLimit(scope string, limit int64, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := AuthUserID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := fmt.Sprintf("ratelimit:%s:%d", scope, userID)
+		var (
+			count int64
+			ttl   time.Duration
+		)
+		if client := RedisClient(); client != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			var err error
+			count, err = client.Incr(ctx, key).Result()
+			if err != nil {
+				count, ttl = incrementLocalUserLimit(key, window)
+			} else {
+				if count == 1 {
+					client.Expire(ctx, key, window)
+				}
+				ttl = client.TTL(ctx, key).Val()
+			}
+		} else {
+			count, ttl = incrementLocalUserLimit(key, window)
+		}
+
+		if count > limit {
+			metrics.RateLimitHits.WithLabelValues(c.ClientIP()).Inc()
+			if ttl > 0 {
+				c.Header("Retry-After", fmt.Sprintf("%d", int(ttl.Seconds())+1))
+			}
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "rate limit exceeded",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -6,37 +6,366 @@ import (
 )
 
 type User struct {
-	ID        int64          `gorm:"primaryKey" json:"id"`
-	Username  string         `gorm:"uniqueIndex;size:64" json:"username"`
-	IsScammer bool           `json:"is_scammer"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID         int64          `gorm:"primaryKey" json:"id"`
+	TelegramID *int64         `gorm:"uniqueIndex" json:"telegram_id,omitempty"`
+	Username   string         `gorm:"uniqueIndex;size:64" json:"username"`
+	IsScammer  bool           `json:"is_scammer"`
+	Aliases    []UserAlias    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"aliases,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// UserAlias — прежний username пользователя (сохраняется при смене username)
+type UserAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    int64     `gorm:"index" json:"user_id"`
+	Username  string    `gorm:"size:64;index" json:"username"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Ad struct {
-	ID                uint           `gorm:"primaryKey" json:"id"`
+	ID                uint           `gorm:"primaryKey;index:idx_ads_feed,priority:3" json:"id"`
 	UserID            int64          `json:"user_id"`
 	ClientID          string         `gorm:"size:64;index" json:"client_id"`
 	Username          string         `gorm:"size:64;index" json:"username"`
 	Title             string         `gorm:"size:128" json:"title"`
 	Desc              string         `gorm:"size:2048" json:"desc"`
-	PhotoID           string         `gorm:"size:256" json:"-"`
-	PhotoPath         string         `gorm:"size:512" json:"-"`
+	PhotoCount        int            `json:"photo_count"`
 	Category          string         `gorm:"size:32;index" json:"category"`
 	Mode              string         `gorm:"size:16;index" json:"mode"`
 	Tag               string         `gorm:"size:64;index" json:"tag"`
-	IsPremium         bool           `json:"is_premium"`
+	IsPremium         bool           `gorm:"index:idx_ads_feed,priority:1" json:"is_premium"`
 	Status            string         `gorm:"size:16;index" json:"status"`
 	ExpiresAt         time.Time      `gorm:"index" json:"expires_at"`
 	PreExpiryNotified bool           `json:"-"`
+	RequestedDays     int            `json:"requested_days"`
+	RejectReason      string         `gorm:"size:512" json:"reject_reason,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
+	UpdatedAt         time.Time      `gorm:"index:idx_ads_feed,priority:2" json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// AdPhoto — фото из галереи объявления. Position задаёт порядок, фото с Position 0 — обложка.
+type AdPhoto struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AdID      uint      `gorm:"uniqueIndex:idx_ad_photos_ad_position,priority:1" json:"ad_id"`
+	Position  int       `gorm:"uniqueIndex:idx_ad_photos_ad_position,priority:2" json:"position"`
+	FileID    string    `gorm:"size:256" json:"-"`
+	Key       string    `gorm:"size:256" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 const (
 	AdStatusActive   = "active"
 	AdStatusExpired  = "expired"
 	AdStatusInactive = "inactive"
+	AdStatusPending  = "pending"
+)
+
+// BotSession хранит сериализованную сессию диалога менеджера с ботом
+type BotSession struct {
+	ChatID    int64     `gorm:"primaryKey;autoIncrement:false"`
+	Data      []byte    `gorm:"type:bytea"`
+	ExpiresAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// BlacklistEntry — запись о внесении пользователя в чёрный список.
+// Снятые записи не удаляются: RemovedAt и RemovedBy сохраняют историю.
+type BlacklistEntry struct {
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	UserID     int64               `gorm:"index" json:"user_id"`
+	TelegramID int64               `gorm:"index" json:"telegram_id,omitempty"`
+	Username   string              `gorm:"size:64;index" json:"username"`
+	Reason     string              `gorm:"size:1024" json:"reason"`
+	AddedBy    int64               `json:"added_by"`
+	RemovedAt  *time.Time          `gorm:"index" json:"removed_at,omitempty"`
+	RemovedBy  int64               `json:"removed_by,omitempty"`
+	Evidence   []BlacklistEvidence `gorm:"foreignKey:EntryID;constraint:OnDelete:CASCADE" json:"evidence,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// BlacklistEvidence — доказательство к записи чёрного списка (file_id скриншота в Telegram)
+type BlacklistEvidence struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EntryID   uint      `gorm:"index" json:"entry_id"`
+	FileID    string    `gorm:"size:256" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScamReport — жалоба пользователя Mini App на мошенника
+type ScamReport struct {
+	ID               uint               `gorm:"primaryKey" json:"id"`
+	ReporterID       int64              `gorm:"index" json:"reporter_id"`
+	ReporterUsername string             `gorm:"size:64" json:"reporter_username"`
+	TargetUsername   string             `gorm:"size:64;index" json:"target_username"`
+	TargetTelegramID int64              `json:"target_telegram_id,omitempty"`
+	AdID             *uint              `gorm:"index" json:"ad_id,omitempty"`
+	Category         string             `gorm:"size:32" json:"category"`
+	Text             string             `gorm:"size:2048" json:"text"`
+	Status           string             `gorm:"size:16;index" json:"status"`
+	ReviewedBy       int64              `json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time         `json:"reviewed_at,omitempty"`
+	BlacklistEntryID *uint              `json:"blacklist_entry_id,omitempty"`
+	Screenshots      []ReportScreenshot `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+const (
+	ReportStatusPending   = "pending"
+	ReportStatusAccepted  = "accepted"
+	ReportStatusDismissed = "dismissed"
+)
+
+// ReportScreenshot — скриншот к жалобе. FileID заполняется после первой отправки в Telegram,
+// дальше скриншот пересылается по нему и используется как доказательство в чёрном списке.
+type ReportScreenshot struct {
+	ID          uint   `gorm:"primaryKey"`
+	ReportID    uint   `gorm:"index"`
+	ContentType string `gorm:"size:64"`
+	Data        []byte `gorm:"type:bytea"`
+	FileID      string `gorm:"size:256"`
+	CreatedAt   time.Time
+}
+
+// AuditEvent — действие менеджера в боте. Changes хранит JSON вида
+// {"поле": {"from": старое, "to": новое}} только по изменившимся полям.
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    int64     `gorm:"index" json:"actor_id"`
+	Action     string    `gorm:"size:32;index" json:"action"`
+	EntityType string    `gorm:"size:16;index:idx_audit_events_entity,priority:1" json:"entity_type"`
+	EntityID   string    `gorm:"size:64;index:idx_audit_events_entity,priority:2" json:"entity_id"`
+	Changes    string    `gorm:"type:jsonb" json:"changes"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// Manager — пользователь бота с ролью. Владельцы из MANAGER_ID создаются при старте бота.
+type Manager struct {
+	TelegramID int64     `gorm:"primaryKey;autoIncrement:false" json:"telegram_id"`
+	Username   string    `gorm:"size:64" json:"username,omitempty"`
+	Role       string    `gorm:"size:16;index" json:"role"`
+	AddedBy    int64     `json:"added_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const (
+	ManagerRoleOwner     = "owner"
+	ManagerRoleModerator = "moderator"
+	ManagerRoleAdEditor  = "ad_editor"
+	ManagerRoleViewer    = "viewer"
+)
+
+// AdRevision — снимок объявления после очередного сохранения. Восстановление старой
+// версии создаёт новую ревизию с RestoredFrom, прежние ревизии не меняются.
+type AdRevision struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	AdID         uint      `gorm:"index" json:"ad_id"`
+	EditorID     int64     `json:"editor_id"`
+	Username     string    `gorm:"size:64" json:"username"`
+	Title        string    `gorm:"size:128" json:"title"`
+	Desc         string    `gorm:"size:2048" json:"desc"`
+	Category     string    `gorm:"size:32" json:"category"`
+	Mode         string    `gorm:"size:16" json:"mode"`
+	Tag          string    `gorm:"size:64" json:"tag"`
+	IsPremium    bool      `json:"is_premium"`
+	Status       string    `gorm:"size:16" json:"status"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RestoredFrom *uint     `json:"restored_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Payment — покупка в Telegram Stars: премиум или продление объявления.
+// TelegramChargeID нужен для возврата через refundStarPayment.
+type Payment struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	AdID             uint       `gorm:"index" json:"ad_id"`
+	UserID           int64      `gorm:"index" json:"user_id"`
+	Product          string     `gorm:"size:16" json:"product"`
+	Days             int        `json:"days,omitempty"`
+	Amount           int        `json:"amount"`
+	Currency         string     `gorm:"size:8" json:"currency"`
+	Status           string     `gorm:"size:16;index" json:"status"`
+	TelegramChargeID string     `gorm:"size:128;index" json:"-"`
+	ProviderChargeID string     `gorm:"size:128" json:"-"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+	RefundedAt       *time.Time `json:"refunded_at,omitempty"`
+	RefundedBy       int64      `json:"refunded_by,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+const (
+	PaymentProductPremium = "premium"
+	PaymentProductExtend  = "extend"
+)
+
+const (
+	// PaymentStatusPending — счёт выставлен, оплаты ещё не было
+	PaymentStatusPending = "pending"
+	// PaymentStatusPaid — оплачено и применено к объявлению
+	PaymentStatusPaid = "paid"
+	// PaymentStatusFailed — оплачено, но применить не удалось; ждёт возврата
+	PaymentStatusFailed   = "failed"
+	PaymentStatusRefunded = "refunded"
+)
+
+// PremiumSlotLimit — сколько премиум-объявлений одновременно может быть в категории.
+// Для категорий без записи действует лимит по умолчанию.
+type PremiumSlotLimit struct {
+	Category  string    `gorm:"primaryKey;size:32" json:"category"`
+	MaxActive int       `json:"max_active"`
+	UpdatedBy int64     `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PremiumBooking — заявка на премиум-место. Пока мест нет, заявка ждёт в очереди категории;
+// когда место освобождается, она получает окно StartsAt–EndsAt до конца срока объявления.
+type PremiumBooking struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	AdID        uint       `gorm:"index" json:"ad_id"`
+	RequestedBy int64      `json:"requested_by"`
+	Status      string     `gorm:"size:16;index" json:"status"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+const (
+	// PremiumBookingQueued — ждёт свободного места
+	PremiumBookingQueued = "queued"
+	// PremiumBookingActive — объявление поднято из очереди и занимает место
+	PremiumBookingActive = "active"
+	// PremiumBookingFinished — премиум закончился вместе со сроком или был снят
+	PremiumBookingFinished = "finished"
+	// PremiumBookingCancelled — заявка снята до того, как подошла очередь
+	PremiumBookingCancelled = "cancelled"
+)
+
+// ClientRequest — заявка владельца объявления из бота: продлить или снять объявление.
+// Заявки разбирают менеджеры в разделе «📥 Заявки клиентов».
+type ClientRequest struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	AdID      uint       `gorm:"index" json:"ad_id"`
+	UserID    int64      `gorm:"index" json:"user_id"`
+	Kind      string     `gorm:"size:16" json:"kind"`
+	Days      int        `json:"days,omitempty"`
+	Status    string     `gorm:"size:16;index" json:"status"`
+	HandledBy int64      `json:"handled_by,omitempty"`
+	HandledAt *time.Time `json:"handled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+const (
+	ClientRequestRenew  = "renew"
+	ClientRequestRemove = "remove"
+)
+
+const (
+	ClientRequestPending  = "pending"
+	ClientRequestDone     = "done"
+	ClientRequestRejected = "rejected"
+)
+
+// Subscription — сохранённый поиск пользователя Mini App. Пустое поле фильтра подходит под любое значение.
+type Subscription struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    int64     `gorm:"index" json:"user_id"`
+	Category  string    `gorm:"size:32" json:"category"`
+	Mode      string    `gorm:"size:16" json:"mode"`
+	Tag       string    `gorm:"size:64" json:"tag"`
+	Query     string    `gorm:"size:256" json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubscriptionAlert — объявление, найденное по подписке. SentAt пуст, пока оповещение
+// ждёт дайджеста; Digest — оповещение ушло в составе дайджеста.
+type SubscriptionAlert struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"index" json:"subscription_id"`
+	UserID         int64      `gorm:"uniqueIndex:idx_subscription_alerts_user_ad,priority:1" json:"user_id"`
+	AdID           uint       `gorm:"uniqueIndex:idx_subscription_alerts_user_ad,priority:2" json:"ad_id"`
+	Digest         bool       `json:"digest"`
+	SentAt         *time.Time `gorm:"index" json:"sent_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Favorite — объявление в избранном у пользователя Mini App. Notify — присылать ли в бот
+// уведомления о продлении, изменении и скором окончании срока.
+type Favorite struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    int64     `gorm:"uniqueIndex:idx_favorites_user_ad,priority:1" json:"user_id"`
+	AdID      uint      `gorm:"uniqueIndex:idx_favorites_user_ad,priority:2;index" json:"ad_id"`
+	Notify    bool      `json:"notify"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TelegramUser — пользователь Mini App: когда впервые и в последний раз заходил
+type TelegramUser struct {
+	TelegramID  int64     `gorm:"primaryKey;autoIncrement:false" json:"telegram_id"`
+	Username    string    `gorm:"size:64;index" json:"username"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Review — отзыв пользователя об авторе объявления. Один отзыв от пары пользователей по объявлению.
+type Review struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	AdID             uint       `gorm:"uniqueIndex:idx_reviews_pair_ad,priority:3;index" json:"ad_id"`
+	ReviewerID       int64      `gorm:"uniqueIndex:idx_reviews_pair_ad,priority:1" json:"reviewer_id"`
+	ReviewerUsername string     `gorm:"size:64" json:"reviewer_username,omitempty"`
+	TargetID         int64      `gorm:"uniqueIndex:idx_reviews_pair_ad,priority:2;index" json:"target_id"`
+	Rating           int        `json:"rating"`
+	Comment          string     `gorm:"size:1024" json:"comment"`
+	Status           string     `gorm:"size:16;index" json:"status"`
+	Verified         bool       `json:"verified"`
+	ModeratedBy      int64      `json:"-"`
+	ModeratedAt      *time.Time `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Статусы отзыва: новый отзыв виден только после проверки менеджером
+const (
+	ReviewStatusPending   = "pending"
+	ReviewStatusPublished = "published"
+	ReviewStatusHidden    = "hidden"
+)
+
+// Deal — сделка через гаранта по объявлению: покупатель переводит деньги менеджеру,
+// тот передаёт их продавцу после получения товара. Суммы — в рублях.
+type Deal struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	AdID           uint       `gorm:"index" json:"ad_id"`
+	SellerID       int64      `gorm:"index" json:"seller_id"`
+	SellerUsername string     `gorm:"size:64" json:"seller_username,omitempty"`
+	BuyerID        int64      `gorm:"index" json:"buyer_id"`
+	BuyerUsername  string     `gorm:"size:64" json:"buyer_username,omitempty"`
+	Amount         int64      `json:"amount"`
+	Fee            int64      `json:"fee"`
+	Status         string     `gorm:"size:16;index" json:"status"`
+	ManagerID      int64      `json:"-"`
+	FundedAt       *time.Time `json:"funded_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Статусы сделки: created → funded → delivered → released; из funded и delivered — спор (disputed),
+// который закрывается выплатой продавцу (released) или возвратом покупателю (refunded)
+const (
+	DealStatusCreated   = "created"
+	DealStatusFunded    = "funded"
+	DealStatusDelivered = "delivered"
+	DealStatusReleased  = "released"
+	DealStatusDisputed  = "disputed"
+	DealStatusRefunded  = "refunded"
 )
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound — объекта с таким ключом нет в хранилище
+var ErrNotFound = errors.New("blob not found")
+
+// Object — содержимое и метаданные сохранённого объекта
+type Object struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore — хранилище файлов (фото объявлений и т.п.) по строковому ключу вида "ads/abc.jpg"
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Photos — хранилище фото объявлений, выбирается в Init
+var Photos BlobStore
+
+// Init выбирает хранилище по PHOTO_STORAGE: local (по умолчанию, каталог PHOTO_STORAGE_DIR)
+// или s3 (любое S3-совместимое хранилище, например MinIO).
+func Init() error {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("PHOTO_STORAGE")))
+	switch kind {
+	case "", "local":
+		dir := strings.TrimSpace(os.Getenv("PHOTO_STORAGE_DIR"))
+		if dir == "" {
+			dir = "data/photos"
+		}
+		store, err := NewLocalStore(dir)
+		if err != nil {
+			return err
+		}
+		Photos = store
+		log.Printf("Фото объявлений хранятся в каталоге %s", dir)
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+		if err != nil {
+			return err
+		}
+		Photos = store
+		log.Printf("Фото объявлений хранятся в S3: %s/%s", store.endpoint, store.bucket)
+	default:
+		return fmt.Errorf("unknown PHOTO_STORAGE %q", kind)
+	}
+	return nil
+}
+
+// validKey отсекает ключи, которые могут выйти за пределы хранилища
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
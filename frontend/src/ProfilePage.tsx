@@ -1,4 +1,7 @@
 // В ProfilePage.tsx
-const username = webApp.initDataUnsafe.user?.username;
-const res = await fetch(`/api/profile/${username}`);
-const ads = await res.json();
\ No newline at end of file
+import { apiFetch } from './utils/telegram';
+
+// Без username API возвращает профиль текущего пользователя Mini App
+const res = await apiFetch('/api/profile');
+const profile = await res.json();
+const ads = profile.ads;
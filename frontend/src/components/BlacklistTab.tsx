@@ -4,9 +4,13 @@ import { Input } from './ui/input';
 import { Button } from './ui/button';
 import { ScrollArea } from './ui/scroll-area';
 import { apiFetch } from '../utils/telegram';
+import { ReportForm } from './ReportForm';
 
 interface BlacklistEntry {
   username: string;
+  reason?: string;
+  previous_usernames?: string[] | null;
+  listed_at: string;
   created_at: string;
   updated_at: string;
 }
@@ -87,7 +91,7 @@ export function BlacklistTab() {
         <div className="relative">
           <Input
             type="text"
-            placeholder="@username"
+            placeholder="@username или Telegram ID"
             value={username}
             onChange={(e) => setUsername(e.target.value)}
             onKeyPress={handleKeyPress}
@@ -149,6 +153,9 @@ export function BlacklistTab() {
         </div>
       )}
 
+      {/* Report Form */}
+      <ReportForm />
+
       {/* Blacklist Table */}
       <div className="space-y-3">
         <div className="flex items-center justify-between">
@@ -169,11 +176,21 @@ export function BlacklistTab() {
             ) : (
               <div className="divide-y divide-border">
                 {entries.map((entry) => (
-                  <div key={entry.username} className="px-4 py-3 flex items-center justify-between">
-                    <span className="font-medium">@{entry.username}</span>
-                    <span className="text-xs text-muted-foreground">
-                      обновлён {new Date(entry.updated_at).toLocaleDateString('ru-RU')}
-                    </span>
+                  <div key={entry.username} className="px-4 py-3 space-y-1">
+                    <div className="flex items-center justify-between">
+                      <span className="font-medium">@{entry.username}</span>
+                      <span className="text-xs text-muted-foreground">
+                        внесён {new Date(entry.listed_at).toLocaleDateString('ru-RU')}
+                      </span>
+                    </div>
+                    {entry.previous_usernames && entry.previous_usernames.length > 0 && (
+                      <p className="text-xs text-muted-foreground">
+                        ранее: {entry.previous_usernames.map((name) => `@${name}`).join(', ')}
+                      </p>
+                    )}
+                    {entry.reason && (
+                      <p className="text-xs text-muted-foreground">{entry.reason}</p>
+                    )}
                   </div>
                 ))}
               </div>
@@ -0,0 +1,99 @@
+import { useEffect, useState } from 'react';
+import { Handshake } from 'lucide-react';
+import { ReviewForm } from './ReviewForm';
+import { apiFetch } from '../utils/telegram';
+
+interface Deal {
+  id: number;
+  adId: number;
+  adTitle: string;
+  role: 'buyer' | 'seller';
+  status: string;
+  statusLabel: string;
+  amount: number;
+  fee: number;
+  counterparty: string;
+  counterpartyId: number;
+  createdAt: string;
+}
+
+// Сделки через гаранта, где пользователь — покупатель или продавец. Статусы меняет менеджер в боте.
+export function DealsList() {
+  const [deals, setDeals] = useState<Deal[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    fetchDeals();
+  }, []);
+
+  const fetchDeals = async () => {
+    setLoading(true);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/deals');
+      if (!response.ok) {
+        throw new Error('Ошибка загрузки сделок');
+      }
+      const data = await response.json();
+      setDeals(
+        data.map((deal: any) => {
+          const counterpartyUsername = deal.role === 'buyer' ? deal.seller_username : deal.buyer_username;
+          const counterpartyId = deal.role === 'buyer' ? deal.seller_id : deal.buyer_id;
+          return {
+            id: deal.id,
+            adId: deal.ad_id,
+            adTitle: deal.ad_title,
+            role: deal.role,
+            status: deal.status,
+            statusLabel: deal.status_label,
+            amount: deal.amount,
+            fee: deal.fee,
+            counterparty: counterpartyUsername ? `@${counterpartyUsername}` : `ID ${counterpartyId}`,
+            counterpartyId,
+            createdAt: deal.created_at,
+          };
+        })
+      );
+    } catch (err) {
+      console.error('Failed to fetch deals:', err);
+      setError('Не удалось загрузить сделки.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // Раздел нужен только участникам сделок
+  if (loading || (deals.length === 0 && !error)) {
+    return null;
+  }
+
+  return (
+    <div className="space-y-4">
+      <h2 className="text-lg">Сделки через гаранта</h2>
+      {error && <p className="text-sm text-destructive">{error}</p>}
+      {deals.map((deal) => (
+        <div key={deal.id} className="rounded-2xl border border-border p-4 space-y-1">
+          <div className="flex items-center gap-2">
+            <Handshake size={16} className="text-[#FF0000]" />
+            <span className="font-medium">Сделка #{deal.id}</span>
+            <span className="text-sm text-muted-foreground">{deal.statusLabel}</span>
+          </div>
+          <p className="text-sm">
+            {deal.adTitle ? `«${deal.adTitle}»` : `Объявление #${deal.adId}`}
+          </p>
+          <p className="text-sm text-muted-foreground">
+            {deal.role === 'buyer' ? 'Вы покупатель, продавец' : 'Вы продавец, покупатель'} {deal.counterparty}
+          </p>
+          <p className="text-sm">
+            {deal.amount.toLocaleString('ru-RU')} ₽ · комиссия гаранта {deal.fee.toLocaleString('ru-RU')} ₽
+          </p>
+          <p className="text-xs text-muted-foreground">
+            Открыта {new Date(deal.createdAt).toLocaleDateString('ru-RU')}
+          </p>
+          <ReviewForm adId={deal.adId} targetId={deal.role === 'seller' ? deal.counterpartyId : undefined} />
+        </div>
+      ))}
+    </div>
+  );
+}
@@ -0,0 +1,137 @@
+import { useEffect, useState } from 'react';
+import { Heart } from 'lucide-react';
+import { Button } from './ui/button';
+import { ListingCard, parseSellerReputation, type ListingCardData } from './ListingCard';
+import { apiFetch } from '../utils/telegram';
+
+// Загружает id объявлений из избранного, чтобы подсветить их в ленте
+export async function fetchFavoriteIds(): Promise<Set<number>> {
+  const response = await apiFetch('/api/favorites');
+  if (!response.ok) {
+    throw new Error('Ошибка загрузки избранного');
+  }
+  const data = await response.json();
+  return new Set<number>(data.map((ad: any) => ad.id));
+}
+
+interface FavoriteButtonProps {
+  adId: number;
+  active: boolean;
+  onChange: (active: boolean) => void;
+}
+
+export function FavoriteButton({ adId, active, onChange }: FavoriteButtonProps) {
+  const [sending, setSending] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const toggle = async () => {
+    setSending(true);
+    setError(null);
+    try {
+      const response = await apiFetch(`/api/ads/${adId}/favorite`, { method: active ? 'DELETE' : 'POST' });
+      // 404 при удалении — объявления в избранном уже нет
+      if (!response.ok && !(active && response.status === 404)) {
+        const data = await response.json().catch(() => ({}));
+        throw new Error(data.error || 'Не удалось обновить избранное');
+      }
+      onChange(!active);
+    } catch (err) {
+      console.error('Failed to toggle favorite:', err);
+      setError('Не удалось обновить избранное');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  return (
+    <div className="flex flex-col gap-1">
+      <Button
+        onClick={toggle}
+        disabled={sending}
+        variant="outline"
+        size="sm"
+        className={`rounded-xl self-start ${active ? 'border-[#FF0000] text-[#FF0000]' : 'border-border'}`}
+      >
+        <Heart size={16} fill={active ? 'currentColor' : 'none'} />
+        {active ? 'В избранном' : 'В избранное'}
+      </Button>
+      {error && <p className="text-sm text-destructive">{error}</p>}
+    </div>
+  );
+}
+
+// Избранные объявления пользователя; уведомления об их продлении и изменении приходят в бот
+export function FavoritesList() {
+  const [listings, setListings] = useState<ListingCardData[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    fetchFavorites();
+  }, []);
+
+  const fetchFavorites = async () => {
+    setLoading(true);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/favorites');
+      if (!response.ok) {
+        throw new Error('Ошибка загрузки избранного');
+      }
+      const data = await response.json();
+      setListings(
+        data.map((ad: any) => ({
+          id: ad.id,
+          title: ad.title,
+          description: ad.desc,
+          username: `@${ad.username}`,
+          isPremium: ad.is_premium,
+          category: ad.category,
+          mode: ad.mode,
+          tag: ad.tag,
+          status: ad.status,
+          expiresAt: ad.expires_at,
+          photoUrl: ad.photo_url ?? null,
+          photoUrls: ad.photo_urls ?? [],
+          sellerReputation: parseSellerReputation(ad.seller_reputation),
+        }))
+      );
+    } catch (err) {
+      console.error('Failed to fetch favorites:', err);
+      setError('Не удалось загрузить избранное.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (loading) {
+    return null;
+  }
+
+  return (
+    <div className="space-y-4">
+      <h2 className="text-lg">Избранное</h2>
+      {error && <p className="text-sm text-destructive">{error}</p>}
+      {listings.length === 0 ? (
+        <p className="text-sm text-muted-foreground">
+          Добавляйте объявления в избранное из ленты — бот напомнит, если их продлят, изменят или срок подойдёт к концу.
+        </p>
+      ) : (
+        listings.map((listing) => (
+          <ListingCard
+            key={listing.id}
+            listing={listing}
+            showExpiryDate={true}
+            footer={
+              <FavoriteButton
+                adId={listing.id}
+                active={true}
+                onChange={() => setListings((prev) => prev.filter((item) => item.id !== listing.id))}
+              />
+            }
+          />
+        ))
+      )}
+    </div>
+  );
+}
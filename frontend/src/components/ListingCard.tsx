@@ -1,5 +1,5 @@
-import { useState, useEffect, useRef, type ReactNode } from 'react';
-import { Flame, Clock, ChevronDown, ChevronUp } from 'lucide-react';
+import { useState, useEffect, useRef, type ReactNode, type UIEvent } from 'react';
+import { Flame, Clock, ChevronDown, ChevronUp, Star } from 'lucide-react';
 import { ImageWithFallback } from './figma/ImageWithFallback';
 import { Button } from './ui/button';
 import { Linkify } from '../utils/linkify';
@@ -40,6 +40,20 @@ const tagLabels: Record<string, string> = {
   boost: 'Накрутка',
 };
 
+export interface SellerReputation {
+  average: number;
+  count: number;
+  verifiedDeals: number;
+}
+
+// Репутация автора из ответа API (seller_reputation)
+export function parseSellerReputation(value: any): SellerReputation | null {
+  if (!value) {
+    return null;
+  }
+  return { average: value.average, count: value.count, verifiedDeals: value.verified_deals };
+}
+
 export interface ListingCardData {
   id: number;
   title: string;
@@ -52,6 +66,8 @@ export interface ListingCardData {
   status?: 'active' | 'expired' | 'inactive';
   expiresAt?: string;
   photoUrl?: string | null;
+  photoUrls?: string[];
+  sellerReputation?: SellerReputation | null;
 }
 
 interface ListingCardProps {
@@ -72,7 +88,14 @@ export function ListingCard({ listing, footer, showExpiryDate = false, showFullD
   const isExpired = listing.status === 'expired';
   const isInactive = listing.status === 'inactive';
   const isPremium = listing.isPremium;
-  const hasPhoto = listing.photoUrl && listing.photoUrl.trim() !== '';
+  const photos = listing.photoUrls?.length ? listing.photoUrls : listing.photoUrl ? [listing.photoUrl] : [];
+  const hasPhoto = photos.length > 0;
+  const [photoIndex, setPhotoIndex] = useState(0);
+
+  const handleGalleryScroll = (e: UIEvent<HTMLDivElement>) => {
+    const el = e.currentTarget;
+    setPhotoIndex(Math.round(el.scrollLeft / el.clientWidth));
+  };
 
   // Проверяем, нужно ли показывать кнопку разворачивания
   // Проверка должна происходить после рендеринга, когда применён line-clamp
@@ -183,7 +206,24 @@ export function ListingCard({ listing, footer, showExpiryDate = false, showFullD
     <div ref={cardRef} className={`bg-card rounded-2xl shadow-md overflow-hidden transition-all hover:shadow-lg ${borderClass}`}>
       {hasPhoto && (
         <div className="relative aspect-video overflow-hidden bg-muted">
-          <ImageWithFallback src={listing.photoUrl!} alt={listing.title} className="w-full h-full object-cover" />
+          <div className="flex h-full overflow-x-auto snap-x snap-mandatory" onScroll={handleGalleryScroll}>
+            {photos.map((url, index) => (
+              <ImageWithFallback
+                key={url}
+                src={`${url}?size=medium`}
+                srcSet={`${url}?size=thumb 320w, ${url}?size=medium 800w, ${url}?size=full 2048w`}
+                sizes="(max-width: 640px) 100vw, 640px"
+                alt={`${listing.title} — фото ${index + 1}`}
+                loading={index === 0 ? undefined : 'lazy'}
+                className="w-full h-full flex-none snap-center object-cover"
+              />
+            ))}
+          </div>
+          {photos.length > 1 && (
+            <div className="absolute bottom-3 right-3 bg-black/60 text-white px-2 py-0.5 rounded-full text-xs">
+              {photoIndex + 1}/{photos.length}
+            </div>
+          )}
           {isPremium && !isExpired && !isInactive && (
             <div className="absolute top-3 right-3 bg-[#FF0000] text-white px-3 py-1 rounded-full flex items-center gap-1 shadow-lg">
               <Flame size={16} />
@@ -287,6 +327,15 @@ export function ListingCard({ listing, footer, showExpiryDate = false, showFullD
         >
           {listing.username}
         </a>
+        {listing.sellerReputation && (
+          <span className="ml-2 inline-flex items-center gap-1 text-xs text-muted-foreground">
+            <Star size={12} className="text-yellow-500" fill="currentColor" />
+            {listing.sellerReputation.count > 0 &&
+              `${listing.sellerReputation.average.toFixed(1)} · отзывов: ${listing.sellerReputation.count}`}
+            {listing.sellerReputation.count > 0 && listing.sellerReputation.verifiedDeals > 0 && ' · '}
+            {listing.sellerReputation.verifiedDeals > 0 && `сделок через гаранта: ${listing.sellerReputation.verifiedDeals}`}
+          </span>
+        )}
 
         {footer && (
           <div className="pt-3 border-t border-border">
@@ -1,8 +1,11 @@
 import { useState, useEffect } from 'react';
-import { ListingCard, type ListingCardData } from './ListingCard';
+import { ListingCard, parseSellerReputation, type ListingCardData } from './ListingCard';
 import { Tabs, TabsList, TabsTrigger } from './ui/tabs';
 import { Button } from './ui/button';
 import { FilterScroll } from './FilterScroll';
+import { SubscribeButton } from './Subscriptions';
+import { FavoriteButton, fetchFavoriteIds } from './Favorites';
+import { ReviewForm } from './ReviewForm';
 import { apiFetch } from '../utils/telegram';
 
 type MainCategory = 'services' | 'buysell' | 'other';
@@ -15,19 +18,47 @@ export function ListingsTab() {
   const [buysellType, setBuysellType] = useState<string>('all');
   const [otherType, setOtherType] = useState<string>('all');
   const [listings, setListings] = useState<ListingCardData[]>([]);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
   const [loading, setLoading] = useState(true);
+  const [loadingMore, setLoadingMore] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [favoriteIds, setFavoriteIds] = useState<Set<number>>(new Set());
+
+  useEffect(() => {
+    fetchFavoriteIds()
+      .then(setFavoriteIds)
+      .catch((error) => console.error('Failed to fetch favorites:', error));
+  }, []);
+
+  const setFavorite = (adId: number, active: boolean) => {
+    setFavoriteIds((prev) => {
+      const next = new Set(prev);
+      if (active) {
+        next.add(adId);
+      } else {
+        next.delete(adId);
+      }
+      return next;
+    });
+  };
 
   useEffect(() => {
     fetchListings();
   }, [mainCategory, serviceFilter, serviceType, buysellFilter, buysellType, otherType]);
 
-  const fetchListings = async () => {
-    setLoading(true);
+  const fetchListings = async (cursor?: string) => {
+    if (cursor) {
+      setLoadingMore(true);
+    } else {
+      setLoading(true);
+    }
     setError(null);
     try {
       const params = new URLSearchParams();
       params.set('cat', mainCategory);
+      if (cursor) {
+        params.set('cursor', cursor);
+      }
 
       if (mainCategory === 'services' && serviceType !== 'all') {
         params.set('tag', serviceType);
@@ -50,7 +81,7 @@ export function ListingsTab() {
       }
       const data = await response.json();
 
-      const transformedListings: ListingCardData[] = data.map((ad: any) => ({
+      const transformedListings: ListingCardData[] = data.items.map((ad: any) => ({
         id: ad.id,
         title: ad.title,
         description: ad.desc,
@@ -62,17 +93,29 @@ export function ListingsTab() {
         status: ad.status,
         expiresAt: ad.expires_at,
         photoUrl: ad.photo_url ?? null,
+        photoUrls: ad.photo_urls ?? [],
+        sellerReputation: parseSellerReputation(ad.seller_reputation),
       }));
-      setListings(transformedListings);
+      setListings((prev) => (cursor ? [...prev, ...transformedListings] : transformedListings));
+      setNextCursor(data.next_cursor ?? null);
     } catch (error) {
       console.error('Failed to fetch listings:', error);
       setError('Не удалось загрузить объявления. Попробуйте обновить позже.');
-      setListings([]);
+      if (!cursor) {
+        setListings([]);
+        setNextCursor(null);
+      }
     } finally {
       setLoading(false);
+      setLoadingMore(false);
     }
   };
 
+  const currentMode =
+    mainCategory === 'services' ? serviceFilter : mainCategory === 'buysell' ? buysellFilter : '';
+  const currentTag =
+    mainCategory === 'services' ? serviceType : mainCategory === 'buysell' ? buysellType : otherType;
+
   return (
     <div className="pb-4">
       {/* Header */}
@@ -415,6 +458,7 @@ export function ListingsTab() {
             </Button>
           </FilterScroll>
         )}
+        <SubscribeButton category={mainCategory} mode={currentMode} tag={currentTag} />
       </div>
 
       {/* Listings Grid */}
@@ -432,9 +476,34 @@ export function ListingsTab() {
             <p>Объявления не найдены</p>
           </div>
         ) : (
-          listings.map((listing) => (
-            <ListingCard key={listing.id} listing={listing} />
-          ))
+          <>
+            {listings.map((listing) => (
+              <ListingCard
+                key={listing.id}
+                listing={listing}
+                footer={
+                  <div className="flex flex-col gap-2">
+                    <FavoriteButton
+                      adId={listing.id}
+                      active={favoriteIds.has(listing.id)}
+                      onChange={(active) => setFavorite(listing.id, active)}
+                    />
+                    <ReviewForm adId={listing.id} />
+                  </div>
+                }
+              />
+            ))}
+            {nextCursor && (
+              <Button
+                onClick={() => fetchListings(nextCursor)}
+                disabled={loadingMore}
+                variant="outline"
+                className="w-full rounded-xl"
+              >
+                {loadingMore ? 'Загрузка...' : 'Показать ещё'}
+              </Button>
+            )}
+          </>
         )}
       </div>
     </div>
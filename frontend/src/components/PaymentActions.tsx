@@ -0,0 +1,68 @@
+import { useState } from 'react';
+import { Star } from 'lucide-react';
+import { Button } from './ui/button';
+import { apiFetch } from '../utils/telegram';
+
+const EXTEND_DAYS = 7;
+
+interface PaymentActionsProps {
+  adId: number;
+  canBuyPremium: boolean;
+}
+
+export function PaymentActions({ adId, canBuyPremium }: PaymentActionsProps) {
+  const [sending, setSending] = useState(false);
+  const [message, setMessage] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const requestInvoice = async (product: 'premium' | 'extend') => {
+    setSending(true);
+    setMessage(null);
+    setError(null);
+    try {
+      const response = await apiFetch(`/api/ads/${adId}/invoice`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ product, days: product === 'extend' ? EXTEND_DAYS : 0 }),
+      });
+      const data = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(data.error || 'Не удалось выставить счёт');
+      }
+      setMessage(`Счёт на ${data.amount} ⭐ отправлен в чат с ботом.`);
+    } catch (err) {
+      console.error('Failed to request invoice:', err);
+      setError(err instanceof Error ? err.message : 'Не удалось выставить счёт');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  return (
+    <div className="flex flex-col gap-2">
+      <div className="flex gap-2">
+        <Button
+          onClick={() => requestInvoice('extend')}
+          disabled={sending}
+          variant="outline"
+          className="flex-1 rounded-xl"
+        >
+          <Star size={16} />
+          Продлить на {EXTEND_DAYS} дн.
+        </Button>
+        {canBuyPremium && (
+          <Button
+            onClick={() => requestInvoice('premium')}
+            disabled={sending}
+            className="flex-1 bg-[#FF0000] hover:bg-[#CC0000] text-white rounded-xl"
+          >
+            <Star size={16} />
+            Премиум
+          </Button>
+        )}
+      </div>
+      {message && <p className="text-sm text-green-700">{message}</p>}
+      {error && <p className="text-sm text-destructive">{error}</p>}
+    </div>
+  );
+}
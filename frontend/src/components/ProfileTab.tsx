@@ -3,6 +3,10 @@ import { ListingCard, MANAGER_LINK, type ListingCardData } from './ListingCard';
 import { Button } from './ui/button';
 import { User, Moon, Sun } from 'lucide-react';
 import { apiFetch } from '../utils/telegram';
+import { PaymentActions } from './PaymentActions';
+import { SubscriptionsList } from './Subscriptions';
+import { FavoritesList } from './Favorites';
+import { DealsList } from './Deals';
 
 interface ProfileTabProps {
   isDark: boolean;
@@ -51,7 +55,8 @@ export function ProfileTab({ isDark, toggleTheme }: ProfileTabProps) {
     console.log('ProfileTab: запрос объявлений для user_id=', userId);
     setLoading(true);
     try {
-      const response = await apiFetch(`/api/myads?user_id=${userId}`);
+      // Владельца сервер берёт из init_data, user_id в запросе доступен только менеджерам
+      const response = await apiFetch('/api/myads');
       console.log('ProfileTab: получен ответ', response.status, response.statusText);
       const data = await response.json();
       console.log('ProfileTab: получено объявлений', data.length);
@@ -68,6 +73,7 @@ export function ProfileTab({ isDark, toggleTheme }: ProfileTabProps) {
         status: (ad.status ?? 'active') as 'active' | 'expired' | 'inactive',
         expiresAt: ad.expires_at,
         photoUrl: ad.photo_url ?? null,
+        photoUrls: ad.photo_urls ?? [],
       }));
       
       setListings(transformedListings);
@@ -154,11 +160,14 @@ export function ProfileTab({ isDark, toggleTheme }: ProfileTabProps) {
                 listing={listing}
                 showExpiryDate={true}
                 footer={
-                  (isExpired || isInactive) && (
+                  isExpired || isInactive ? (
                     <div className="flex flex-col gap-2">
                       <p className="text-sm text-muted-foreground">
-                        Объявление не показывается на бирже. Свяжитесь с менеджером, чтобы поднять его вновь.
+                        {isExpired
+                          ? 'Объявление не показывается на бирже. Продлите его за звёзды или свяжитесь с менеджером.'
+                          : 'Объявление не показывается на бирже. Свяжитесь с менеджером, чтобы поднять его вновь.'}
                       </p>
+                      {isExpired && <PaymentActions adId={listing.id} canBuyPremium={false} />}
                       <Button
                         asChild
                         className="bg-[#FF0000] hover:bg-[#CC0000] text-white"
@@ -168,13 +177,24 @@ export function ProfileTab({ isDark, toggleTheme }: ProfileTabProps) {
                         </a>
                       </Button>
                     </div>
-                  )
+                  ) : listing.status === 'active' ? (
+                    <PaymentActions adId={listing.id} canBuyPremium={!listing.isPremium} />
+                  ) : null
                 }
               />
             );
           })}
         </div>
       ) : null}
+
+      {/* Escrow deals */}
+      <DealsList />
+
+      {/* Favorites */}
+      <FavoritesList />
+
+      {/* Saved searches */}
+      <SubscriptionsList />
     </div>
   );
 }
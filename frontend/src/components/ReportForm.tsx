@@ -0,0 +1,134 @@
+import { useState } from 'react';
+import { Flag } from 'lucide-react';
+import { Input } from './ui/input';
+import { Textarea } from './ui/textarea';
+import { Button } from './ui/button';
+import { apiFetch } from '../utils/telegram';
+
+const MAX_SCREENSHOTS = 5;
+
+const CATEGORIES: { value: string; label: string }[] = [
+  { value: 'non_payment', label: 'Не оплатил' },
+  { value: 'fake_channel', label: 'Фейковый канал' },
+  { value: 'stolen_content', label: 'Кража контента' },
+  { value: 'fake_ad', label: 'Мошенническое объявление' },
+  { value: 'other', label: 'Другое' },
+];
+
+export function ReportForm() {
+  const [target, setTarget] = useState('');
+  const [category, setCategory] = useState(CATEGORIES[0].value);
+  const [text, setText] = useState('');
+  const [files, setFiles] = useState<File[]>([]);
+  const [sending, setSending] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [sentId, setSentId] = useState<number | null>(null);
+
+  const handleFiles = (list: FileList | null) => {
+    const selected = Array.from(list ?? []);
+    if (selected.length > MAX_SCREENSHOTS) {
+      setError(`Можно приложить не более ${MAX_SCREENSHOTS} скриншотов`);
+      setFiles(selected.slice(0, MAX_SCREENSHOTS));
+      return;
+    }
+    setError(null);
+    setFiles(selected);
+  };
+
+  const handleSubmit = async () => {
+    const value = target.trim().replace('@', '');
+    if (!value || !text.trim()) {
+      setError('Укажите username или ID объявления и опишите ситуацию');
+      return;
+    }
+
+    const form = new FormData();
+    if (/^#?\d+$/.test(value)) {
+      form.append('ad_id', value.replace('#', ''));
+    } else {
+      form.append('target_username', value);
+    }
+    form.append('category', category);
+    form.append('text', text.trim());
+    files.forEach((file) => form.append('screenshots', file));
+
+    setSending(true);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/reports', { method: 'POST', body: form });
+      const data = await response.json().catch(() => ({}));
+      if (response.status === 429) {
+        throw new Error('Слишком много жалоб. Попробуйте позже.');
+      }
+      if (!response.ok) {
+        throw new Error(data.error || 'Не удалось отправить жалобу');
+      }
+      setSentId(data.id);
+      setTarget('');
+      setText('');
+      setFiles([]);
+    } catch (err) {
+      console.error('Failed to send report:', err);
+      setError(err instanceof Error ? err.message : 'Не удалось отправить жалобу');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  return (
+    <div className="space-y-3 rounded-2xl border border-border p-4">
+      <div className="flex items-center gap-2">
+        <Flag size={18} className="text-[#FF0000]" />
+        <h2 className="text-lg font-semibold">Пожаловаться на мошенника</h2>
+      </div>
+      <Input
+        type="text"
+        placeholder="@username или номер объявления"
+        value={target}
+        onChange={(e) => setTarget(e.target.value)}
+        className="h-11 rounded-xl"
+      />
+      <select
+        value={category}
+        onChange={(e) => setCategory(e.target.value)}
+        className="h-11 w-full rounded-xl border border-border bg-input-background px-3 text-sm"
+      >
+        {CATEGORIES.map((item) => (
+          <option key={item.value} value={item.value}>
+            {item.label}
+          </option>
+        ))}
+      </select>
+      <Textarea
+        placeholder="Что произошло?"
+        value={text}
+        onChange={(e) => setText(e.target.value)}
+        maxLength={2048}
+        className="min-h-24 rounded-xl"
+      />
+      <label className="block text-sm text-muted-foreground">
+        Скриншоты (до {MAX_SCREENSHOTS} шт.)
+        <input
+          type="file"
+          accept="image/jpeg,image/png,image/webp"
+          multiple
+          onChange={(e) => handleFiles(e.target.files)}
+          className="mt-1 block w-full text-sm"
+        />
+      </label>
+      {error && <p className="text-sm text-destructive">{error}</p>}
+      {sentId !== null && !error && (
+        <p className="text-sm text-green-700">
+          Жалоба #{sentId} отправлена. Мы сообщим о решении в Telegram.
+        </p>
+      )}
+      <Button
+        onClick={handleSubmit}
+        disabled={sending}
+        className="w-full bg-[#FF0000] hover:bg-[#CC0000] rounded-xl"
+      >
+        {sending ? 'Отправка...' : 'Отправить жалобу'}
+      </Button>
+    </div>
+  );
+}
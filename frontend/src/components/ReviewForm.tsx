@@ -0,0 +1,118 @@
+import { useState } from 'react';
+import { Star } from 'lucide-react';
+import { Textarea } from './ui/textarea';
+import { Button } from './ui/button';
+import { apiFetch } from '../utils/telegram';
+
+const MAX_COMMENT_LENGTH = 1000;
+
+interface ReviewFormProps {
+  adId: number;
+  // Кого оценивает автор объявления; для отзыва об авторе не нужен
+  targetId?: number;
+}
+
+// Отзыв об участнике сделки: оценка 1–5 и комментарий. Публикуется после проверки менеджером.
+export function ReviewForm({ adId, targetId }: ReviewFormProps) {
+  const [open, setOpen] = useState(false);
+  const [rating, setRating] = useState(0);
+  const [comment, setComment] = useState('');
+  const [sending, setSending] = useState(false);
+  const [sent, setSent] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const errorMessage = (status: number, fallback: string) => {
+    switch (status) {
+      case 403:
+        if (fallback === 'no interaction over this ad') {
+          return 'Отзыв можно оставить только после сделки через гаранта по этому объявлению.';
+        }
+        if (fallback === 'account is too new to leave reviews') {
+          return 'Отзывы можно оставлять через неделю после первого визита на биржу.';
+        }
+        return 'Вы не можете оставлять отзывы.';
+      case 409:
+        return 'Вы уже оставили отзыв по этому объявлению.';
+      case 429:
+        return 'Слишком много отзывов. Попробуйте завтра.';
+      default:
+        return fallback;
+    }
+  };
+
+  const handleSubmit = async () => {
+    if (rating < 1) {
+      setError('Поставьте оценку от 1 до 5');
+      return;
+    }
+    setSending(true);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/reviews', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ad_id: adId, target_id: targetId, rating, comment: comment.trim() }),
+      });
+      const data = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        throw new Error(errorMessage(response.status, data.error || 'Не удалось отправить отзыв'));
+      }
+      setSent(true);
+    } catch (err) {
+      console.error('Failed to send review:', err);
+      setError(err instanceof Error ? err.message : 'Не удалось отправить отзыв');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  if (sent) {
+    return <p className="text-sm text-green-700">Спасибо! Отзыв появится после проверки менеджером.</p>;
+  }
+
+  if (!open) {
+    return (
+      <Button onClick={() => setOpen(true)} variant="outline" size="sm" className="rounded-xl self-start border-border">
+        <Star size={16} />
+        Оставить отзыв
+      </Button>
+    );
+  }
+
+  return (
+    <div className="flex flex-col gap-2">
+      <div className="flex gap-1">
+        {[1, 2, 3, 4, 5].map((value) => (
+          <button
+            key={value}
+            type="button"
+            onClick={() => setRating(value)}
+            aria-label={`Оценка ${value}`}
+            className="text-yellow-500"
+          >
+            <Star size={24} fill={value <= rating ? 'currentColor' : 'none'} />
+          </button>
+        ))}
+      </div>
+      <Textarea
+        value={comment}
+        onChange={(e) => setComment(e.target.value.slice(0, MAX_COMMENT_LENGTH))}
+        placeholder="Как прошла сделка?"
+        rows={3}
+      />
+      {error && <p className="text-sm text-destructive">{error}</p>}
+      <div className="flex gap-2">
+        <Button
+          onClick={handleSubmit}
+          disabled={sending}
+          className="flex-1 bg-[#FF0000] hover:bg-[#CC0000] text-white rounded-xl"
+        >
+          {sending ? 'Отправка...' : 'Отправить'}
+        </Button>
+        <Button onClick={() => setOpen(false)} variant="outline" className="rounded-xl">
+          Отмена
+        </Button>
+      </div>
+    </div>
+  );
+}
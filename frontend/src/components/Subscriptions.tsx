@@ -0,0 +1,146 @@
+import { useEffect, useState } from 'react';
+import { Bell, Trash2 } from 'lucide-react';
+import { Button } from './ui/button';
+import { apiFetch } from '../utils/telegram';
+
+interface Subscription {
+  id: number;
+  category: string;
+  mode: string;
+  tag: string;
+  query: string;
+  label: string;
+}
+
+interface SubscribeButtonProps {
+  category: string;
+  mode: string;
+  tag: string;
+}
+
+// Кнопка «Подписаться» сохраняет текущие фильтры ленты как поиск с оповещениями в боте
+export function SubscribeButton({ category, mode, tag }: SubscribeButtonProps) {
+  const [sending, setSending] = useState(false);
+  const [message, setMessage] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    setMessage(null);
+    setError(null);
+  }, [category, mode, tag]);
+
+  const subscribe = async () => {
+    setSending(true);
+    setMessage(null);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/subscriptions', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ category, mode, tag }),
+      });
+      const data = await response.json().catch(() => ({}));
+      if (response.status === 409) {
+        setMessage('Вы уже подписаны на эти фильтры.');
+        return;
+      }
+      if (!response.ok) {
+        throw new Error(data.error || 'Не удалось оформить подписку');
+      }
+      setMessage('Готово! Новые объявления будут приходить в чат с ботом.');
+    } catch (err) {
+      console.error('Failed to subscribe:', err);
+      setError(err instanceof Error ? err.message : 'Не удалось оформить подписку');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  return (
+    <div className="flex flex-col gap-1">
+      <Button
+        onClick={subscribe}
+        disabled={sending}
+        variant="outline"
+        size="sm"
+        className="rounded-full self-start border-border hover:border-[#FF0000]"
+      >
+        <Bell size={14} />
+        Подписаться на новые
+      </Button>
+      {message && <p className="text-sm text-green-700">{message}</p>}
+      {error && <p className="text-sm text-destructive">{error}</p>}
+    </div>
+  );
+}
+
+// Список сохранённых поисков пользователя с удалением
+export function SubscriptionsList() {
+  const [subscriptions, setSubscriptions] = useState<Subscription[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    fetchSubscriptions();
+  }, []);
+
+  const fetchSubscriptions = async () => {
+    setLoading(true);
+    setError(null);
+    try {
+      const response = await apiFetch('/api/subscriptions');
+      if (!response.ok) {
+        throw new Error('Ошибка загрузки подписок');
+      }
+      setSubscriptions(await response.json());
+    } catch (err) {
+      console.error('Failed to fetch subscriptions:', err);
+      setError('Не удалось загрузить подписки.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const removeSubscription = async (id: number) => {
+    try {
+      const response = await apiFetch(`/api/subscriptions/${id}`, { method: 'DELETE' });
+      if (!response.ok && response.status !== 404) {
+        throw new Error('Ошибка удаления подписки');
+      }
+      setSubscriptions((prev) => prev.filter((sub) => sub.id !== id));
+    } catch (err) {
+      console.error('Failed to delete subscription:', err);
+      setError('Не удалось удалить подписку.');
+    }
+  };
+
+  if (loading) {
+    return null;
+  }
+
+  return (
+    <div className="space-y-2">
+      <h2 className="text-lg">Подписки</h2>
+      {error && <p className="text-sm text-destructive">{error}</p>}
+      {subscriptions.length === 0 ? (
+        <p className="text-sm text-muted-foreground">
+          Подпишитесь на фильтры в ленте, чтобы получать новые объявления в боте.
+        </p>
+      ) : (
+        subscriptions.map((sub) => (
+          <div key={sub.id} className="flex items-center justify-between gap-2 rounded-xl border border-border px-3 py-2">
+            <span className="text-sm">{sub.label}</span>
+            <Button
+              onClick={() => removeSubscription(sub.id)}
+              variant="ghost"
+              size="sm"
+              aria-label="Удалить подписку"
+            >
+              <Trash2 size={16} />
+            </Button>
+          </div>
+        ))
+      )}
+    </div>
+  );
+}